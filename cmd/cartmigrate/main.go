@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/sunzhaoc/plant_be/internal/cart"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
+	"github.com/sunzhaoc/plant_be/pkg/db/redis"
+)
+
+// 将 Redis 中的旧版购物车（cart:u:<uid>，按规格名称存储）批量转换为按 SKU 存储的新版购物车：
+//
+//	go run ./cmd/cartmigrate
+//
+// 需在执行 migrations/008_cart_sku.sql 之后运行；未转换的购物车也会在首次读取或刷盘时自动转换
+func main() {
+	if err := mysql.Init(mysql.Load(), []string{"ali"}); err != nil {
+		log.Fatalf("初始化Mysql数据库失败：%v", err)
+	}
+	defer mysql.Close()
+
+	if err := redis.Init(redis.Load(), []string{"ali"}); err != nil {
+		log.Fatalf("初始化Redis数据库失败：%v", err)
+	}
+	cart.Load()
+
+	count, err := cart.MigrateAllLegacy(context.Background())
+	if err != nil {
+		log.Fatalf("转换旧版购物车失败（已转换%d个）：%v", count, err)
+	}
+	log.Printf("旧版购物车转换完成，共%d个", count)
+}
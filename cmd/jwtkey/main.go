@@ -0,0 +1,60 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 生成JWT签名密钥，用于密钥轮换：
+//
+//	go run ./cmd/jwtkey -dir config/keys/jwt -alg EdDSA
+//
+// 生成后将 config.yaml 中 jwt.active_kid 改为输出的 kid 并重新部署
+func main() {
+	dir := flag.String("dir", "config/keys/jwt", "密钥目录")
+	alg := flag.String("alg", "EdDSA", "签名算法：EdDSA 或 RS256")
+	kid := flag.String("kid", time.Now().Format("2006-01-02"), "密钥ID（文件名）")
+	flag.Parse()
+
+	var private any
+	switch *alg {
+	case "EdDSA":
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			log.Fatalf("生成Ed25519密钥失败: %v", err)
+		}
+		private = key
+	case "RS256":
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			log.Fatalf("生成RSA密钥失败: %v", err)
+		}
+		private = key
+	default:
+		log.Fatalf("不支持的算法: %s", *alg)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		log.Fatalf("编码私钥失败: %v", err)
+	}
+	if err := os.MkdirAll(*dir, 0700); err != nil {
+		log.Fatalf("创建密钥目录失败: %v", err)
+	}
+	path := filepath.Join(*dir, *kid+".pem")
+	if _, err := os.Stat(path); err == nil {
+		log.Fatalf("密钥文件已存在: %s", path)
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		log.Fatalf("写入私钥失败: %v", err)
+	}
+	log.Printf("已生成密钥 kid=%s alg=%s path=%s", *kid, *alg, path)
+}
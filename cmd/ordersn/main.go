@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sunzhaoc/plant_be/pkg/ordersn"
+)
+
+// 解析订单号，供客服/运维排查：
+//
+//	go run ./cmd/ordersn 202610171530120000420123453
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "用法: ordersn <订单号>...")
+		os.Exit(2)
+	}
+
+	failed := false
+	for _, sn := range flag.Args() {
+		info, err := ordersn.Parse(sn)
+		if err != nil {
+			fmt.Printf("%s\t%v\n", sn, err)
+			failed = true
+			continue
+		}
+		version := "v2"
+		if info.Legacy {
+			version = "legacy"
+		}
+		fmt.Printf("%s\t创建时间=%s\t序号=%d\t用户ID后6位=%06d\t格式=%s\n",
+			sn, info.CreatedAt.Format("2006-01-02 15:04:05"), info.Seq, info.UidSuffix, version)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -3,12 +3,24 @@ package main
 import (
 	"log"
 
+	"github.com/sunzhaoc/plant_be/internal/cart"
+	"github.com/sunzhaoc/plant_be/internal/flashsale"
+	"github.com/sunzhaoc/plant_be/internal/inventory"
+	"github.com/sunzhaoc/plant_be/internal/orders"
+	"github.com/sunzhaoc/plant_be/pkg/carrier"
 	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
 	"github.com/sunzhaoc/plant_be/pkg/db/redis"
+	"github.com/sunzhaoc/plant_be/pkg/payment"
+	"github.com/sunzhaoc/plant_be/pkg/utils"
 	"github.com/sunzhaoc/plant_be/routers"
 )
 
 func main() {
+	// 加载JWT签名密钥（生产环境缺少密钥时直接退出）
+	if err := utils.InitJWTKeys(utils.LoadJWTConfig()); err != nil {
+		log.Fatalf("初始化JWT密钥失败：%v", err)
+	}
+
 	// 初始化 Mysql
 	if err := mysql.Init(mysql.Load(), []string{"ali"}); err != nil {
 		log.Fatal("初始化Mysql数据库失败：%v", err)
@@ -20,5 +32,31 @@ func main() {
 		log.Fatalf("初始化Redis数据库失败：%v", err)
 	}
 
+	// 初始化支付渠道
+	if err := payment.Init(payment.Load()); err != nil {
+		log.Fatalf("初始化支付渠道失败：%v", err)
+	}
+
+	// 初始化物流公司
+	if err := carrier.Init(carrier.Load()); err != nil {
+		log.Fatalf("初始化物流公司失败：%v", err)
+	}
+
+	// 启动购物车定时持久化任务
+	cart.Load()
+	go cart.StartSyncWorker()
+
+	// 启动库存预占对账任务
+	inventory.Load()
+	go inventory.StartReconcileWorker()
+
+	// 启动秒杀异步下单任务
+	flashsale.Load()
+	go flashsale.StartWorker()
+
+	// 启动超时未支付订单的自动取消任务
+	orders.Load()
+	go orders.StartExpireWorker()
+
 	routers.InitRouter()
 }
@@ -0,0 +1,453 @@
+package aftersale
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/sunzhaoc/plant_be/internal/inventory"
+	"github.com/sunzhaoc/plant_be/internal/orders"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"github.com/sunzhaoc/plant_be/pkg/money"
+	"github.com/sunzhaoc/plant_be/pkg/payment"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrNotFound           = errors.New("售后申请不存在")
+	ErrItemNotFound       = errors.New("订单商品不存在")
+	ErrOrderNotEligible   = errors.New("当前订单状态不支持申请售后")
+	ErrTypeNotAllowed     = errors.New("订单未发货，仅支持仅退款")
+	ErrQuantityExceeded   = errors.New("售后数量超过可申请数量")
+	ErrIllegalState       = errors.New("售后申请当前状态不允许该操作")
+	ErrAmountExceeded     = errors.New("退款金额超过可退金额")
+	ErrPaymentUnsupported = errors.New("订单支付渠道不可用，无法原路退款")
+	ErrRefundFailed       = errors.New("退款发起失败")
+)
+
+// eligibleStatuses 可申请售后的订单状态；退款中表示已有其他售后在处理，仍可继续申请
+var eligibleStatuses = []models.OrderStatus{
+	models.OrderStatusPaid,
+	models.OrderStatusShipped,
+	models.OrderStatusDelivered,
+	models.OrderStatusCompleted,
+	models.OrderStatusRefunding,
+}
+
+// FileRequest 用户发起售后申请
+type FileRequest struct {
+	UserId      uint64
+	OrderItemId uint64
+	Type        models.AfterSaleType
+	Quantity    uint
+	Reason      string
+	Description string
+	Images      []string // 凭证图片 OSS Key
+}
+
+// ApproveRequest 审核通过
+type ApproveRequest struct {
+	RefundAmount *money.Money // 为空时按申请金额退款，只能调低
+	Restock      bool         // 退款后归还库存（退货退款且商品可二次销售时）
+	Remark       string
+}
+
+// Hook 在售后状态变更的事务内调用（可选），用于写入审计日志等与变更同生共死的记录
+type Hook func(tx *gorm.DB, before models.AfterSale, after models.AfterSale) error
+
+// runHook 重新读取变更后的申请并调用 hook，返回变更后的申请
+func runHook(tx *gorm.DB, before *models.AfterSale, hook Hook) (*models.AfterSale, error) {
+	var after models.AfterSale
+	if err := tx.Where("id = ?", before.Id).Take(&after).Error; err != nil {
+		return nil, fmt.Errorf("查询售后申请失败: %w", err)
+	}
+	if hook != nil {
+		if err := hook(tx, *before, after); err != nil {
+			return nil, err
+		}
+	}
+	return &after, nil
+}
+
+// claimedQuantity 订单项已申请（未驳回、未撤销）的售后数量
+func claimedQuantity(tx *gorm.DB, orderItemId uint64) (uint, error) {
+	var claimed uint
+	err := tx.Model(&models.AfterSale{}).
+		Select("COALESCE(SUM(quantity), 0)").
+		Where("order_item_id = ? AND status NOT IN ?", orderItemId, []models.AfterSaleStatus{models.AfterSaleRejected, models.AfterSaleCancelled}).
+		Scan(&claimed).Error
+	if err != nil {
+		return 0, fmt.Errorf("查询已申请售后数量失败: %w", err)
+	}
+	return claimed, nil
+}
+
+// lineRefund 订单项第 from+1 至 from+n 件的可退金额（实付 = 单价 × 数量 - 分摊优惠）
+//
+// 按累计件数比例计算差值，保证整行分多次退款时合计恰好等于该行实付
+func lineRefund(item models.OrderItem, from uint, n uint) money.Money {
+	paid := item.Price.Mul(int64(item.Quantity)) - item.DiscountAmount
+	qty := int64(item.Quantity)
+	return paid.MulRatio(int64(from+n), qty) - paid.MulRatio(int64(from), qty)
+}
+
+// coversWholeOrder 加上本次申请后，订单全部商品是否均已申请退款类售后
+func coversWholeOrder(tx *gorm.DB, orderId uint64, itemId uint64, quantity uint) (bool, error) {
+	var items []models.OrderItem
+	if err := tx.Select("id", "quantity").Where("order_id = ?", orderId).Find(&items).Error; err != nil {
+		return false, fmt.Errorf("查询订单项失败: %w", err)
+	}
+	var claims []struct {
+		OrderItemId uint64
+		Quantity    uint
+	}
+	err := tx.Model(&models.AfterSale{}).
+		Select("order_item_id, SUM(quantity) quantity").
+		Where("order_id = ? AND type IN ? AND status NOT IN ?", orderId,
+			[]models.AfterSaleType{models.AfterSaleRefundOnly, models.AfterSaleReturn},
+			[]models.AfterSaleStatus{models.AfterSaleRejected, models.AfterSaleCancelled}).
+		Group("order_item_id").
+		Scan(&claims).Error
+	if err != nil {
+		return false, fmt.Errorf("查询售后数量失败: %w", err)
+	}
+	claimed := make(map[uint64]uint, len(claims))
+	for _, c := range claims {
+		claimed[c.OrderItemId] = c.Quantity
+	}
+	claimed[itemId] += quantity
+	for _, item := range items {
+		if claimed[item.Id] < item.Quantity {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// File 发起售后申请，订单进入退款中（已在退款中时保持）
+//
+// 退款类申请的金额为该件数对应的实付金额；本次申请使整单全部退款时一并退还运费
+func File(db *gorm.DB, req FileRequest) (*models.AfterSale, error) {
+	var claim *models.AfterSale
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var item models.OrderItem
+		err := tx.Where("id = ?", req.OrderItemId).Take(&item).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrItemNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("查询订单项失败: %w", err)
+		}
+		// 锁定订单行，串行化同一订单的售后申请
+		var order models.Orders
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "order_sn", "user_id", "order_status", "shipping_fee").
+			Where("id = ? AND user_id = ?", item.OrderId, req.UserId).
+			Take(&order).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrItemNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("查询订单失败: %w", err)
+		}
+		eligible := false
+		for _, s := range eligibleStatuses {
+			if order.OrderStatus == s {
+				eligible = true
+			}
+		}
+		if !eligible {
+			return ErrOrderNotEligible
+		}
+		if order.OrderStatus == models.OrderStatusPaid && req.Type != models.AfterSaleRefundOnly {
+			return ErrTypeNotAllowed
+		}
+
+		claimed, err := claimedQuantity(tx, item.Id)
+		if err != nil {
+			return err
+		}
+		if req.Quantity == 0 || claimed+req.Quantity > item.Quantity {
+			return ErrQuantityExceeded
+		}
+
+		claim = &models.AfterSale{
+			OrderId:     order.Id,
+			OrderSn:     order.OrderSn,
+			OrderItemId: item.Id,
+			UserId:      req.UserId,
+			Type:        req.Type,
+			Quantity:    req.Quantity,
+			Reason:      req.Reason,
+			Description: req.Description,
+			Status:      models.AfterSalePending,
+		}
+		if len(req.Images) > 0 {
+			data, _ := json.Marshal(req.Images)
+			images := string(data)
+			claim.Images = &images
+		}
+		if req.Type.Refundable() {
+			claim.RefundAmount = lineRefund(item, claimed, req.Quantity)
+			whole, err := coversWholeOrder(tx, order.Id, item.Id, req.Quantity)
+			if err != nil {
+				return err
+			}
+			if whole {
+				claim.RefundAmount += order.ShippingFee
+			}
+		}
+		if err := tx.Create(claim).Error; err != nil {
+			return fmt.Errorf("写入售后申请失败: %w", err)
+		}
+		claim.RefundNo = fmt.Sprintf("RF%012d", claim.Id)
+		if err := tx.Model(claim).Update("refund_no", claim.RefundNo).Error; err != nil {
+			return fmt.Errorf("写入退款单号失败: %w", err)
+		}
+
+		if order.OrderStatus == models.OrderStatusRefunding {
+			return nil
+		}
+		_, err = orders.Transition(tx, orders.Change{
+			OrderId:  order.Id,
+			To:       models.OrderStatusRefunding,
+			Operator: orders.User(req.UserId),
+			Remark:   fmt.Sprintf("申请售后#%d", claim.Id),
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claim, nil
+}
+
+// lockClaim 在事务中锁定售后申请并校验状态
+func lockClaim(tx *gorm.DB, id uint64, status models.AfterSaleStatus) (*models.AfterSale, error) {
+	var claim models.AfterSale
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).Take(&claim).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询售后申请失败: %w", err)
+	}
+	if claim.Status != status {
+		return nil, fmt.Errorf("%w: %s", ErrIllegalState, claim.Status.Label())
+	}
+	return &claim, nil
+}
+
+// Approve 审核通过：退款类申请进入待退款（由 Refund 发起退款），换货申请直接完成
+func Approve(db *gorm.DB, id uint64, op orders.Operator, req ApproveRequest, hook Hook) (*models.AfterSale, error) {
+	var approved *models.AfterSale
+	err := db.Transaction(func(tx *gorm.DB) error {
+		claim, err := lockClaim(tx, id, models.AfterSalePending)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		updates := map[string]interface{}{
+			"status":       models.AfterSaleApproved,
+			"admin_remark": req.Remark,
+			"handler_id":   op.Id,
+			"handle_time":  now,
+		}
+		if claim.Type.Refundable() {
+			if req.RefundAmount != nil {
+				if *req.RefundAmount <= 0 || *req.RefundAmount > claim.RefundAmount {
+					return ErrAmountExceeded
+				}
+				updates["refund_amount"] = *req.RefundAmount
+			}
+			updates["restock"] = req.Restock
+		} else {
+			// 换货由客服线下补发，审核通过即完成
+			updates["status"] = models.AfterSaleCompleted
+			updates["complete_time"] = now
+		}
+		if err := tx.Model(claim).Updates(updates).Error; err != nil {
+			return fmt.Errorf("更新售后申请失败: %w", err)
+		}
+		if !claim.Type.Refundable() {
+			if err := resolveOrder(tx, claim.OrderId, op, fmt.Sprintf("售后#%d换货已同意", claim.Id)); err != nil {
+				return err
+			}
+		}
+		approved, err = runHook(tx, claim, hook)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return approved, nil
+}
+
+// Reject 驳回申请，无其他处理中的售后时订单恢复到申请前的状态
+func Reject(db *gorm.DB, id uint64, op orders.Operator, remark string, hook Hook) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		claim, err := lockClaim(tx, id, models.AfterSalePending)
+		if err != nil {
+			return err
+		}
+		err = tx.Model(claim).Updates(map[string]interface{}{
+			"status":       models.AfterSaleRejected,
+			"admin_remark": remark,
+			"handler_id":   op.Id,
+			"handle_time":  time.Now(),
+		}).Error
+		if err != nil {
+			return fmt.Errorf("更新售后申请失败: %w", err)
+		}
+		if err := resolveOrder(tx, claim.OrderId, op, fmt.Sprintf("售后#%d已驳回", claim.Id)); err != nil {
+			return err
+		}
+		_, err = runHook(tx, claim, hook)
+		return err
+	})
+}
+
+// Withdraw 用户撤销待审核的申请
+func Withdraw(db *gorm.DB, userId uint64, id uint64) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var claim models.AfterSale
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND user_id = ?", id, userId).Take(&claim).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("查询售后申请失败: %w", err)
+		}
+		if claim.Status != models.AfterSalePending {
+			return fmt.Errorf("%w: %s", ErrIllegalState, claim.Status.Label())
+		}
+		if err := tx.Model(&claim).Update("status", models.AfterSaleCancelled).Error; err != nil {
+			return fmt.Errorf("更新售后申请失败: %w", err)
+		}
+		return resolveOrder(tx, claim.OrderId, orders.User(userId), fmt.Sprintf("售后#%d已撤销", claim.Id))
+	})
+}
+
+// Refund 对已同意的退款类申请发起原路退款，失败时记录原因并保持待退款，可重试
+//
+// 渠道侧以退款单号保证幂等，重试不会重复退款；退款成功后在事务内完成申请、累计订单退款金额、
+// 按需归还库存，并在无其他处理中的售后时更新订单状态
+func Refund(ctx context.Context, db *gorm.DB, id uint64, op orders.Operator, hook Hook) error {
+	var claim models.AfterSale
+	err := db.Where("id = ?", id).Take(&claim).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("查询售后申请失败: %w", err)
+	}
+	if claim.Status != models.AfterSaleApproved || !claim.Type.Refundable() {
+		return fmt.Errorf("%w: %s", ErrIllegalState, claim.Status.Label())
+	}
+	var order models.Orders
+	if err := db.Select("id", "pay_channel").Where("id = ?", claim.OrderId).Take(&order).Error; err != nil {
+		return fmt.Errorf("查询订单失败: %w", err)
+	}
+	provider, err := payment.Get(order.PayChannel)
+	if err != nil {
+		return ErrPaymentUnsupported
+	}
+
+	_, err = provider.Refund(ctx, payment.RefundRequest{
+		OrderSn:  claim.OrderSn,
+		RefundNo: claim.RefundNo,
+		Amount:   claim.RefundAmount,
+		Reason:   claim.Reason,
+	})
+	if err != nil {
+		msg := err.Error()
+		if len(msg) > 255 {
+			msg = msg[:255]
+		}
+		if uerr := db.Model(&claim).Update("refund_error", msg).Error; uerr != nil {
+			slog.Error("记录退款失败原因失败", "afterSaleId", id, "error", uerr)
+		}
+		return fmt.Errorf("发起退款失败: %w", err)
+	}
+
+	var restockSku uint64
+	err = db.Transaction(func(tx *gorm.DB) error {
+		locked, err := lockClaim(tx, id, models.AfterSaleApproved)
+		if err != nil {
+			return err
+		}
+		err = tx.Model(locked).Updates(map[string]interface{}{
+			"status":        models.AfterSaleCompleted,
+			"refund_error":  "",
+			"complete_time": time.Now(),
+		}).Error
+		if err != nil {
+			return fmt.Errorf("更新售后申请失败: %w", err)
+		}
+		err = tx.Model(&models.Orders{}).Where("id = ?", locked.OrderId).
+			UpdateColumn("refund_amount", gorm.Expr("refund_amount + ?", locked.RefundAmount)).Error
+		if err != nil {
+			return fmt.Errorf("累计订单退款金额失败: %w", err)
+		}
+		if locked.Restock {
+			var item models.OrderItem
+			if err := tx.Select("sku_id").Where("id = ?", locked.OrderItemId).Take(&item).Error; err != nil {
+				return fmt.Errorf("查询订单项失败: %w", err)
+			}
+			if err := tx.Exec("UPDATE plant.plant_sku SET stock = stock + ? WHERE id = ?", locked.Quantity, item.SkuId).Error; err != nil {
+				return fmt.Errorf("归还SKU[%d]库存失败: %w", item.SkuId, err)
+			}
+			restockSku = item.SkuId
+		}
+		if err := resolveOrder(tx, locked.OrderId, op, fmt.Sprintf("售后#%d已退款%s元", locked.Id, locked.RefundAmount)); err != nil {
+			return err
+		}
+		_, err = runHook(tx, locked, hook)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	// 归还的库存同步到可用库存计数，失败时由库存对账任务修正
+	if restockSku != 0 {
+		if err := inventory.AdjustAvailable(context.Background(), restockSku, int64(claim.Quantity)); err != nil {
+			slog.Error("同步可用库存失败", "skuId", restockSku, "error", err)
+		}
+	}
+	return nil
+}
+
+// resolveOrder 订单无处理中的售后时结束退款中状态：累计退款达到实付金额为已退款，否则恢复到申请售后前的状态
+func resolveOrder(tx *gorm.DB, orderId uint64, op orders.Operator, remark string) error {
+	var open int64
+	err := tx.Model(&models.AfterSale{}).
+		Where("order_id = ? AND status IN ?", orderId, []models.AfterSaleStatus{models.AfterSalePending, models.AfterSaleApproved}).
+		Count(&open).Error
+	if err != nil {
+		return fmt.Errorf("查询处理中的售后失败: %w", err)
+	}
+	if open > 0 {
+		return nil
+	}
+
+	var order models.Orders
+	if err := tx.Select("id", "order_status", "pay_amount", "refund_amount").Where("id = ?", orderId).Take(&order).Error; err != nil {
+		return fmt.Errorf("查询订单失败: %w", err)
+	}
+	if order.OrderStatus != models.OrderStatusRefunding {
+		return nil
+	}
+	to := models.OrderStatusRefunded
+	if order.RefundAmount < order.PayAmount {
+		to, err = orders.RefundingFrom(tx, orderId)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = orders.Transition(tx, orders.Change{OrderId: orderId, To: to, Operator: op, Remark: remark})
+	return err
+}
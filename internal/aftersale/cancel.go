@@ -0,0 +1,120 @@
+package aftersale
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sunzhaoc/plant_be/internal/orders"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CancelPaid 后台取消已支付未发货的订单：按订单项生成已同意的仅退款申请（归还库存）并逐笔原路退款
+//
+// 申请创建与订单进入退款中在同一事务内完成，hook（可选）在该事务内调用；事务提交后逐笔发起退款，
+// 全部退款完成后订单变为已退款。退款失败的申请保持待退款并返回 ErrRefundFailed，可通过 Refund 重试
+func CancelPaid(ctx context.Context, db *gorm.DB, orderId uint64, op orders.Operator, reason string, hook func(tx *gorm.DB) error) error {
+	var claimIds []uint64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var order models.Orders
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "order_sn", "user_id", "order_status", "shipping_fee").
+			Where("id = ?", orderId).
+			Take(&order).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return orders.ErrOrderNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("查询订单失败: %w", err)
+		}
+		if order.OrderStatus != models.OrderStatusPaid {
+			return fmt.Errorf("%w: %s", ErrOrderNotEligible, order.OrderStatus.Label())
+		}
+		var shipped int64
+		if err := tx.Raw("SELECT COUNT(*) FROM plant.shipments WHERE order_id = ?", order.Id).Scan(&shipped).Error; err != nil {
+			return fmt.Errorf("查询发货包裹失败: %w", err)
+		}
+		if shipped > 0 {
+			return fmt.Errorf("%w: 订单已部分发货", ErrOrderNotEligible)
+		}
+
+		remark := "后台取消订单"
+		if reason != "" {
+			remark += "：" + reason
+		}
+		_, err = orders.Transition(tx, orders.Change{
+			OrderId:  order.Id,
+			To:       models.OrderStatusRefunding,
+			Operator: op,
+			Remark:   remark,
+		})
+		if err != nil {
+			return err
+		}
+
+		var items []models.OrderItem
+		if err := tx.Where("order_id = ?", order.Id).Order("id").Find(&items).Error; err != nil {
+			return fmt.Errorf("查询订单项失败: %w", err)
+		}
+		now := time.Now()
+		for _, item := range items {
+			claimed, err := claimedQuantity(tx, item.Id)
+			if err != nil {
+				return err
+			}
+			if claimed >= item.Quantity {
+				continue
+			}
+			quantity := item.Quantity - claimed
+			claim := models.AfterSale{
+				OrderId:      order.Id,
+				OrderSn:      order.OrderSn,
+				OrderItemId:  item.Id,
+				UserId:       order.UserId,
+				Type:         models.AfterSaleRefundOnly,
+				Quantity:     quantity,
+				Reason:       "商家取消订单",
+				Description:  reason,
+				RefundAmount: lineRefund(item, claimed, quantity),
+				Restock:      true,
+				Status:       models.AfterSaleApproved,
+				AdminRemark:  reason,
+				HandlerId:    op.Id,
+				HandleTime:   &now,
+			}
+			// 最后一笔覆盖整单时一并退还运费
+			whole, err := coversWholeOrder(tx, order.Id, item.Id, quantity)
+			if err != nil {
+				return err
+			}
+			if whole {
+				claim.RefundAmount += order.ShippingFee
+			}
+			if err := tx.Create(&claim).Error; err != nil {
+				return fmt.Errorf("写入售后申请失败: %w", err)
+			}
+			claim.RefundNo = fmt.Sprintf("RF%012d", claim.Id)
+			if err := tx.Model(&claim).Update("refund_no", claim.RefundNo).Error; err != nil {
+				return fmt.Errorf("写入退款单号失败: %w", err)
+			}
+			claimIds = append(claimIds, claim.Id)
+		}
+		if hook != nil {
+			return hook(tx)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, id := range claimIds {
+		if err := Refund(ctx, db, id, op, nil); err != nil {
+			return fmt.Errorf("%w: 售后#%d: %v", ErrRefundFailed, id, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,434 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sunzhaoc/plant_be/internal/orders"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"github.com/sunzhaoc/plant_be/pkg/region"
+	"gorm.io/gorm"
+)
+
+// MaxAddressCount 每个用户最多保存的收货地址数
+const MaxAddressCount = 20
+
+// mobileRegexp 中国大陆手机号
+var mobileRegexp = regexp.MustCompile(`^1[3-9]\d{9}$`)
+
+// AddressRequest 新增/修改收货地址请求
+type AddressRequest struct {
+	Receiver      string `json:"receiver" binding:"required,max=32"`       // 收货人姓名
+	Phone         string `json:"phone" binding:"required"`                 // 联系电话
+	Province      string `json:"province" binding:"max=32"`                // 省（未传 provinceCode 时按名称识别）
+	ProvinceCode  string `json:"provinceCode" binding:"max=6"`             // 省级行政区划代码
+	City          string `json:"city" binding:"required,max=32"`           // 市
+	Area          string `json:"area" binding:"max=32"`                    // 区/县（直辖市下属区等可为空）
+	DetailAddress string `json:"detailAddress" binding:"required,max=200"` // 详细地址
+	IsDefault     bool   `json:"isDefault"`                                // 是否设为默认地址
+}
+
+// AddressResponse 收货地址响应
+type AddressResponse struct {
+	AddressId     uint64 `json:"addressId"`
+	Receiver      string `json:"receiver"`
+	Phone         string `json:"phone"`
+	Province      string `json:"province"`
+	ProvinceCode  string `json:"provinceCode"`
+	City          string `json:"city"`
+	Area          string `json:"area"`
+	DetailAddress string `json:"detailAddress"`
+	IsDefault     bool   `json:"isDefault"`
+}
+
+func toAddressResponse(a models.UserAddress) AddressResponse {
+	return AddressResponse{
+		AddressId:     a.Id,
+		Receiver:      a.Receiver,
+		Phone:         a.Phone,
+		Province:      a.Province,
+		ProvinceCode:  a.ProvinceCode,
+		City:          a.City,
+		Area:          a.Area,
+		DetailAddress: a.DetailAddress,
+		IsDefault:     a.IsDefault,
+	}
+}
+
+// normalize 去除首尾空白并校验手机号与地区字段，省份统一为标准名称与代码，返回错误提示
+func (r *AddressRequest) normalize() string {
+	r.Receiver = strings.TrimSpace(r.Receiver)
+	r.Phone = strings.TrimSpace(r.Phone)
+	r.Province = strings.TrimSpace(r.Province)
+	r.ProvinceCode = strings.TrimSpace(r.ProvinceCode)
+	r.City = strings.TrimSpace(r.City)
+	r.Area = strings.TrimSpace(r.Area)
+	r.DetailAddress = strings.TrimSpace(r.DetailAddress)
+
+	if r.Receiver == "" {
+		return "收货人不能为空"
+	}
+	if !mobileRegexp.MatchString(r.Phone) {
+		return "手机号格式不正确"
+	}
+	if (r.Province == "" && r.ProvinceCode == "") || r.City == "" {
+		return "请选择完整的省市区"
+	}
+	province, ok := region.Resolve(r.ProvinceCode, r.Province)
+	if !ok {
+		return "省份不正确"
+	}
+	r.Province, r.ProvinceCode = province.Name, province.Code
+	if r.DetailAddress == "" {
+		return "详细地址不能为空"
+	}
+	return ""
+}
+
+// parseAddressId 解析路径参数中的地址ID
+func parseAddressId(c *gin.Context) (uint64, bool) {
+	addressId, err := strconv.ParseUint(c.Param("addressId"), 10, 64)
+	if err != nil || addressId == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "地址ID无效"})
+		return 0, false
+	}
+	return addressId, true
+}
+
+// setDefaultAddress 在事务内将指定地址设为默认，并取消该用户其他默认地址
+func setDefaultAddress(tx *gorm.DB, userId uint64, addressId uint64) error {
+	if err := tx.Model(&models.UserAddress{}).
+		Where("user_id = ? AND id <> ? AND is_default = 1", userId, addressId).
+		Update("is_default", false).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.UserAddress{}).
+		Where("user_id = ? AND id = ?", userId, addressId).
+		Update("is_default", true).Error
+}
+
+// resolveCheckoutAddress 解析下单使用的收货地址
+//
+// 优先使用地址簿中的 addressId（必须属于当前用户），否则校验请求中的内联地址；
+// 地址不合法时返回错误提示 msg，数据库异常时返回 err
+func resolveCheckoutAddress(db *gorm.DB, userId uint64, req PaymentRequest) (address models.UserAddress, msg string, err error) {
+	if req.AddressId != 0 {
+		err = db.Where("id = ? AND user_id = ?", req.AddressId, userId).Take(&address).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return address, "收货地址不存在", nil
+		}
+		if err != nil {
+			return address, "", err
+		}
+		// 旧地址未记录省份代码时按名称识别
+		province, ok := region.Resolve(address.ProvinceCode, address.Province)
+		if !ok {
+			return address, orders.ErrInvalidProvince.Error(), nil
+		}
+		address.Province, address.ProvinceCode = province.Name, province.Code
+		return address, "", nil
+	}
+
+	inline := AddressRequest{
+		Receiver:      req.Address.Receiver,
+		Phone:         req.Address.Phone,
+		Province:      req.Address.Province,
+		ProvinceCode:  req.Address.ProvinceCode,
+		City:          req.Address.City,
+		Area:          req.Address.Area,
+		DetailAddress: req.Address.DetailAddress,
+	}
+	if msg := inline.normalize(); msg != "" {
+		return address, msg, nil
+	}
+	return models.UserAddress{
+		UserId:        userId,
+		Receiver:      inline.Receiver,
+		Phone:         inline.Phone,
+		Province:      inline.Province,
+		ProvinceCode:  inline.ProvinceCode,
+		City:          inline.City,
+		Area:          inline.Area,
+		DetailAddress: inline.DetailAddress,
+	}, "", nil
+}
+
+// GetRegions 可选的省级行政区列表，新增/修改地址时提交其中的代码
+func GetRegions(c *gin.Context) {
+	provinces := region.All()
+	list := make([]gin.H, 0, len(provinces))
+	for _, p := range provinces {
+		list = append(list, gin.H{"code": p.Code, "name": p.Name})
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "", "data": list})
+}
+
+// GetAddresses 获取当前用户的收货地址列表（默认地址在前）
+func GetAddresses(c *gin.Context) {
+	userId, ok := getUserId(c)
+	if !ok {
+		return
+	}
+
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	var addresses []models.UserAddress
+	if err := db.Where("user_id = ?", userId).Order("is_default DESC, update_time DESC").Find(&addresses).Error; err != nil {
+		slog.Error("查询收货地址失败", "uid", userId, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "查询收货地址失败"})
+		return
+	}
+
+	list := make([]AddressResponse, 0, len(addresses))
+	for _, a := range addresses {
+		list = append(list, toAddressResponse(a))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    list,
+	})
+}
+
+// CreateAddress 新增收货地址，用户的第一个地址自动成为默认地址
+func CreateAddress(c *gin.Context) {
+	userId, ok := getUserId(c)
+	if !ok {
+		return
+	}
+
+	var req AddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "参数校验失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+	if msg := req.normalize(); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": msg})
+		return
+	}
+
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	var count int64
+	if err := db.Model(&models.UserAddress{}).Where("user_id = ?", userId).Count(&count).Error; err != nil {
+		slog.Error("查询收货地址数量失败", "uid", userId, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	if count >= MaxAddressCount {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "收货地址数量已达上限"})
+		return
+	}
+
+	address := models.UserAddress{
+		UserId:        userId,
+		Receiver:      req.Receiver,
+		Phone:         req.Phone,
+		Province:      req.Province,
+		ProvinceCode:  req.ProvinceCode,
+		City:          req.City,
+		Area:          req.Area,
+		DetailAddress: req.DetailAddress,
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&address).Error; err != nil {
+			return err
+		}
+		if req.IsDefault || count == 0 {
+			address.IsDefault = true
+			return setDefaultAddress(tx, userId, address.Id)
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Error("新增收货地址失败", "uid", userId, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "新增收货地址失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "新增收货地址成功",
+		"data":    toAddressResponse(address),
+	})
+}
+
+// UpdateAddress 修改收货地址
+func UpdateAddress(c *gin.Context) {
+	userId, ok := getUserId(c)
+	if !ok {
+		return
+	}
+	addressId, ok := parseAddressId(c)
+	if !ok {
+		return
+	}
+
+	var req AddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "参数校验失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+	if msg := req.normalize(); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": msg})
+		return
+	}
+
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	var address models.UserAddress
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND user_id = ?", addressId, userId).Take(&address).Error; err != nil {
+			return err
+		}
+		address.Receiver = req.Receiver
+		address.Phone = req.Phone
+		address.Province = req.Province
+		address.ProvinceCode = req.ProvinceCode
+		address.City = req.City
+		address.Area = req.Area
+		address.DetailAddress = req.DetailAddress
+		if err := tx.Save(&address).Error; err != nil {
+			return err
+		}
+		if req.IsDefault && !address.IsDefault {
+			address.IsDefault = true
+			return setDefaultAddress(tx, userId, address.Id)
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "收货地址不存在"})
+		return
+	}
+	if err != nil {
+		slog.Error("修改收货地址失败", "uid", userId, "addressId", addressId, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "修改收货地址失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "修改收货地址成功",
+		"data":    toAddressResponse(address),
+	})
+}
+
+// DeleteAddress 删除收货地址，删除默认地址时由最近更新的地址接替默认
+func DeleteAddress(c *gin.Context) {
+	userId, ok := getUserId(c)
+	if !ok {
+		return
+	}
+	addressId, ok := parseAddressId(c)
+	if !ok {
+		return
+	}
+
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var address models.UserAddress
+		if err := tx.Where("id = ? AND user_id = ?", addressId, userId).Take(&address).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&address).Error; err != nil {
+			return err
+		}
+		if !address.IsDefault {
+			return nil
+		}
+		var next models.UserAddress
+		err := tx.Where("user_id = ?", userId).Order("update_time DESC").Take(&next).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return setDefaultAddress(tx, userId, next.Id)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "收货地址不存在"})
+		return
+	}
+	if err != nil {
+		slog.Error("删除收货地址失败", "uid", userId, "addressId", addressId, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "删除收货地址失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "删除收货地址成功"})
+}
+
+// SetDefaultAddress 设置默认收货地址
+func SetDefaultAddress(c *gin.Context) {
+	userId, ok := getUserId(c)
+	if !ok {
+		return
+	}
+	addressId, ok := parseAddressId(c)
+	if !ok {
+		return
+	}
+
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var address models.UserAddress
+		if err := tx.Select("id").Where("id = ? AND user_id = ?", addressId, userId).Take(&address).Error; err != nil {
+			return err
+		}
+		return setDefaultAddress(tx, userId, addressId)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "收货地址不存在"})
+		return
+	}
+	if err != nil {
+		slog.Error("设置默认收货地址失败", "uid", userId, "addressId", addressId, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "设置默认地址失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "设置默认地址成功"})
+}
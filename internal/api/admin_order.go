@@ -0,0 +1,542 @@
+package api
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sunzhaoc/plant_be/internal/aftersale"
+	"github.com/sunzhaoc/plant_be/internal/audit"
+	"github.com/sunzhaoc/plant_be/internal/orders"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"github.com/sunzhaoc/plant_be/pkg/region"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	adminOrderExportBatch = 500   // 导出时每批查询的订单数
+	adminOrderExportMax   = 10000 // 单次导出的订单数上限
+)
+
+var (
+	errAddressLocked   = errors.New("订单已发货或已关闭，无法修改收货地址")
+	errProvinceChanged = errors.New("收货省份影响运费，不支持修改省份")
+)
+
+// AdminOrderNoteRequest 添加订单备注请求
+type AdminOrderNoteRequest struct {
+	Content string `json:"content" binding:"required,max=500"`
+}
+
+// AdminCancelOrderRequest 后台取消订单请求
+type AdminCancelOrderRequest struct {
+	Reason string `json:"reason" binding:"required,max=200"`
+}
+
+// adminOrderConditions 解析后台订单筛选参数，返回错误提示
+//
+// 查询参数（均可选）：
+//
+//	status      订单状态
+//	startDate   下单日期起（YYYY-MM-DD，含当天）
+//	endDate     下单日期止（YYYY-MM-DD，含当天）
+//	orderSn     订单号（精确匹配）
+//	phone       收货人手机号（精确匹配）
+//	receiver    收货人姓名（前缀匹配）
+//	userId      下单用户ID
+func adminOrderConditions(c *gin.Context) ([]string, []interface{}, string) {
+	conditions := []string{"1 = 1"}
+	var args []interface{}
+	if v := c.Query("status"); v != "" {
+		status, err := strconv.Atoi(v)
+		if err != nil || !models.OrderStatus(status).Valid() {
+			return nil, nil, "订单状态参数错误"
+		}
+		conditions = append(conditions, "o.order_status = ?")
+		args = append(args, status)
+	}
+	if v := c.Query("startDate"); v != "" {
+		start, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			return nil, nil, "日期参数错误"
+		}
+		conditions = append(conditions, "o.create_time >= ?")
+		args = append(args, start)
+	}
+	if v := c.Query("endDate"); v != "" {
+		end, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			return nil, nil, "日期参数错误"
+		}
+		conditions = append(conditions, "o.create_time < ?")
+		args = append(args, end.AddDate(0, 0, 1))
+	}
+	if v := strings.TrimSpace(c.Query("orderSn")); v != "" {
+		conditions = append(conditions, "o.order_sn = ?")
+		args = append(args, v)
+	}
+	if v := strings.TrimSpace(c.Query("phone")); v != "" {
+		conditions = append(conditions, "o.receiver_phone = ?")
+		args = append(args, v)
+	}
+	if v := strings.TrimSpace(c.Query("receiver")); v != "" {
+		conditions = append(conditions, "o.receiver_name LIKE ?")
+		args = append(args, likeEscaper.Replace(v)+"%")
+	}
+	if v := c.Query("userId"); v != "" {
+		userId, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, nil, "用户ID参数错误"
+		}
+		conditions = append(conditions, "o.user_id = ?")
+		args = append(args, userId)
+	}
+	return conditions, args, ""
+}
+
+// queryAdminOrders 按筛选条件查询 id 小于 cursor 的订单（cursor 为 0 时从最新开始），按 id 倒序
+func queryAdminOrders(db *gorm.DB, conditions []string, args []interface{}, cursor uint64, limit int) ([]models.Orders, error) {
+	if cursor > 0 {
+		conditions = append(append([]string{}, conditions...), "o.id < ?")
+		args = append(append([]interface{}{}, args...), cursor)
+	}
+	var list []models.Orders
+	query := "SELECT o.* FROM plant.orders o WHERE " + strings.Join(conditions, " AND ") + " ORDER BY o.id DESC LIMIT ?"
+	err := db.Raw(query, append(args, limit)...).Scan(&list).Error
+	return list, err
+}
+
+func adminOrderSummary(o models.Orders) gin.H {
+	return gin.H{
+		"order_id":               o.Id,
+		"order_sn":               o.OrderSn,
+		"user_id":                o.UserId,
+		"pay_amount":             o.PayAmount,
+		"refund_amount":          o.RefundAmount,
+		"order_status":           o.OrderStatus,
+		"order_status_label":     o.OrderStatus.Label(),
+		"receiver_name":          o.ReceiverName,
+		"receiver_phone":         o.ReceiverPhone,
+		"receiver_province":      o.ReceiverProvince,
+		"receiver_province_code": o.ReceiverProvinceCode,
+		"pay_channel":            o.PayChannel,
+		"create_time":            o.CreateTime.Format("2006-01-02 15:04:05"),
+	}
+}
+
+// AdminGetOrders 后台订单列表，支持跨用户筛选，按游标分页
+//
+// 筛选参数见 adminOrderConditions；cursor 为上一页返回的 next_cursor，pageSize 默认20，最大100
+func AdminGetOrders(c *gin.Context) {
+	conditions, args, msg := adminOrderConditions(c)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": msg})
+		return
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	var cursor uint64
+	if v := c.Query("cursor"); v != "" {
+		cursor, err = strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "分页游标参数错误"})
+			return
+		}
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	var total int64
+	countQuery := "SELECT COUNT(*) FROM plant.orders o WHERE " + strings.Join(conditions, " AND ")
+	if err := db.Raw(countQuery, args...).Scan(&total).Error; err != nil {
+		slog.Error("查询订单总数失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	orderList, err := queryAdminOrders(db, conditions, args, cursor, pageSize)
+	if err != nil {
+		slog.Error("查询订单列表失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	list := make([]gin.H, 0, len(orderList))
+	for _, o := range orderList {
+		list = append(list, adminOrderSummary(o))
+	}
+	var nextCursor string
+	if len(orderList) == pageSize {
+		nextCursor = strconv.FormatUint(orderList[len(orderList)-1].Id, 10)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"list":        list,
+			"total":       total,
+			"next_cursor": nextCursor,
+		},
+	})
+}
+
+// AdminGetOrder 后台订单详情：在用户可见的详情基础上增加交易号、售后申请与内部备注
+func AdminGetOrder(c *gin.Context) {
+	orderId, ok := parseUintParam(c, "orderId")
+	if !ok {
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	var order models.Orders
+	if err := db.Where("id = ?", orderId).Take(&order).Error; err != nil {
+		respondAdminError(c, err, "订单不存在", "查询订单失败", "orderId", orderId)
+		return
+	}
+	data, err := orderDetailView(db, &order)
+	if err != nil {
+		slog.Error("查询订单详情失败", "orderId", orderId, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	var claims []models.AfterSale
+	if err := db.Where("order_id = ?", orderId).Order("id").Find(&claims).Error; err != nil {
+		slog.Error("查询售后申请失败", "orderId", orderId, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	var notes []models.OrderNote
+	if err := db.Where("order_id = ?", orderId).Order("id").Find(&notes).Error; err != nil {
+		slog.Error("查询订单备注失败", "orderId", orderId, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	var exceptions []models.OrderException
+	if err := db.Where("order_sn = ?", order.OrderSn).Order("id").Find(&exceptions).Error; err != nil {
+		slog.Error("查询订单异常失败", "orderId", orderId, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	afterSales := make([]gin.H, 0, len(claims))
+	for _, claim := range claims {
+		view := afterSaleView(claim)
+		view["refund_no"] = claim.RefundNo
+		view["refund_error"] = claim.RefundError
+		afterSales = append(afterSales, view)
+	}
+	noteList := make([]gin.H, 0, len(notes))
+	for _, n := range notes {
+		noteList = append(noteList, gin.H{
+			"note_id":     n.Id,
+			"admin_id":    n.AdminId,
+			"content":     n.Content,
+			"create_time": n.CreateTime.Format("2006-01-02 15:04:05"),
+		})
+	}
+	data["user_id"] = order.UserId
+	data["trade_no"] = order.TradeNo
+	data["after_sales"] = afterSales
+	data["notes"] = noteList
+	exceptionList := make([]gin.H, 0, len(exceptions))
+	for _, e := range exceptions {
+		exceptionList = append(exceptionList, orderExceptionView(e))
+	}
+	data["exceptions"] = exceptionList
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "", "data": data})
+}
+
+// csvCell 以 = + - @ 或制表符、回车开头的内容会被表格软件当作公式执行，前置单引号按文本处理
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// AdminExportOrders 按筛选条件导出订单 CSV，单次最多导出 adminOrderExportMax 条，
+// 超出上限或中途出错时在末尾追加一行说明
+func AdminExportOrders(c *gin.Context) {
+	conditions, args, msg := adminOrderConditions(c)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": msg})
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	// 导出包含收货人信息，先记录审计日志再输出
+	if err := audit.Record(db, c, "order.export", "order", 0, nil, gin.H{"query": c.Request.URL.RawQuery}); err != nil {
+		slog.Error("记录导出审计日志失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	filename := fmt.Sprintf("orders_%s.csv", time.Now().Format("20060102150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+	// 写入 BOM，Excel 打开时按 UTF-8 识别中文
+	c.Writer.WriteString("\xEF\xBB\xBF")
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"订单号", "用户ID", "下单时间", "订单状态", "商品金额", "运费", "优惠金额", "实付金额", "已退款", "支付渠道", "支付时间", "收货人", "手机号", "收货地址"})
+
+	var cursor uint64
+	exported := 0
+	truncated := false
+	for {
+		if exported >= adminOrderExportMax {
+			// 再查一条判断是否还有未导出的订单
+			more, err := queryAdminOrders(db, conditions, args, cursor, 1)
+			if err != nil {
+				slog.Error("查询剩余订单失败", "error", err)
+			}
+			truncated = err != nil || len(more) > 0
+			break
+		}
+		size := min(adminOrderExportBatch, adminOrderExportMax-exported)
+		batch, err := queryAdminOrders(db, conditions, args, cursor, size)
+		if err != nil {
+			// 响应头已发出，只能在末尾标明导出不完整
+			slog.Error("导出订单失败", "exported", exported, "error", err)
+			w.Write([]string{fmt.Sprintf("导出中断，仅包含前 %d 条订单，请重试", exported)})
+			break
+		}
+		for _, o := range batch {
+			payTime := ""
+			if o.PayTime != nil {
+				payTime = o.PayTime.Format("2006-01-02 15:04:05")
+			}
+			row := []string{
+				o.OrderSn,
+				strconv.FormatUint(o.UserId, 10),
+				o.CreateTime.Format("2006-01-02 15:04:05"),
+				o.OrderStatus.Label(),
+				o.TotalAmount.String(),
+				o.ShippingFee.String(),
+				o.DiscountAmount.String(),
+				o.PayAmount.String(),
+				o.RefundAmount.String(),
+				o.PayChannel,
+				payTime,
+				o.ReceiverName,
+				o.ReceiverPhone,
+				o.ReceiverAddress,
+			}
+			for i := range row {
+				row[i] = csvCell(row[i])
+			}
+			w.Write(row)
+		}
+		exported += len(batch)
+		if len(batch) < size {
+			break
+		}
+		cursor = batch[len(batch)-1].Id
+	}
+	if truncated {
+		w.Write([]string{fmt.Sprintf("超出单次导出上限，仅包含前 %d 条订单，请缩小筛选范围后分批导出", adminOrderExportMax)})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		slog.Error("写入导出文件失败", "error", err)
+	}
+}
+
+// AdminUpdateOrderAddress 发货前修改订单收货地址（不可修改省份）
+func AdminUpdateOrderAddress(c *gin.Context) {
+	orderId, ok := parseUintParam(c, "orderId")
+	if !ok {
+		return
+	}
+	var req AddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "参数校验失败", "error": err.Error()})
+		return
+	}
+	if msg := req.normalize(); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": msg})
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var order models.Orders
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", orderId).Take(&order).Error; err != nil {
+			return err
+		}
+		if order.OrderStatus != models.OrderStatusPendingPayment && order.OrderStatus != models.OrderStatusPaid {
+			return errAddressLocked
+		}
+		var shipped int64
+		if err := tx.Model(&models.Shipment{}).Where("order_id = ?", orderId).Count(&shipped).Error; err != nil {
+			return err
+		}
+		if shipped > 0 {
+			return errAddressLocked
+		}
+		// 旧订单未记录省份代码时按名称识别
+		if before, ok := region.Resolve(order.ReceiverProvinceCode, order.ReceiverProvince); !ok || before.Code != req.ProvinceCode {
+			return errProvinceChanged
+		}
+
+		address := models.UserAddress{Province: req.Province, City: req.City, Area: req.Area, DetailAddress: req.DetailAddress}
+		before := gin.H{
+			"receiver_name":    order.ReceiverName,
+			"receiver_phone":   order.ReceiverPhone,
+			"receiver_address": order.ReceiverAddress,
+		}
+		after := map[string]interface{}{
+			"receiver_name":          req.Receiver,
+			"receiver_phone":         req.Phone,
+			"receiver_address":       address.FullAddress(),
+			"receiver_province":      req.Province,
+			"receiver_province_code": req.ProvinceCode,
+			"receiver_city":          req.City,
+			"receiver_area":          req.Area,
+			"receiver_detail":        req.DetailAddress,
+		}
+		if err := tx.Model(&order).Updates(after).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, c, "order.update_address", "order", orderId, before, after)
+	})
+	switch {
+	case errors.Is(err, errAddressLocked), errors.Is(err, errProvinceChanged):
+		c.JSON(http.StatusConflict, gin.H{"success": false, "message": err.Error()})
+	case err != nil:
+		respondAdminError(c, err, "订单不存在", "修改收货地址失败", "orderId", orderId)
+	default:
+		c.JSON(http.StatusOK, gin.H{"success": true, "message": "修改成功"})
+	}
+}
+
+// AdminAddOrderNote 添加订单内部备注
+func AdminAddOrderNote(c *gin.Context) {
+	adminId, ok := getUserId(c)
+	if !ok {
+		return
+	}
+	orderId, ok := parseUintParam(c, "orderId")
+	if !ok {
+		return
+	}
+	var req AdminOrderNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "参数校验失败", "error": err.Error()})
+		return
+	}
+	content := strings.TrimSpace(req.Content)
+	if content == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "备注内容不能为空"})
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	note := models.OrderNote{OrderId: orderId, AdminId: adminId, Content: content}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var order models.Orders
+		if err := tx.Select("id").Where("id = ?", orderId).Take(&order).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&note).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, c, "order.note", "order", orderId, nil, gin.H{"note_id": note.Id, "content": content})
+	})
+	if err != nil {
+		respondAdminError(c, err, "订单不存在", "添加订单备注失败", "orderId", orderId)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "添加成功", "data": gin.H{"note_id": note.Id}})
+}
+
+// AdminCancelOrder 后台强制取消订单
+//
+// 待支付订单关闭渠道交易后取消；已支付未发货的订单整单原路退款并归还库存，退款失败时可在售后列表重试
+func AdminCancelOrder(c *gin.Context) {
+	adminId, ok := getUserId(c)
+	if !ok {
+		return
+	}
+	orderId, ok := parseUintParam(c, "orderId")
+	if !ok {
+		return
+	}
+	var req AdminCancelOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "参数校验失败", "error": err.Error()})
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	var order models.Orders
+	if err := db.Select("id", "order_status").Where("id = ?", orderId).Take(&order).Error; err != nil {
+		respondAdminError(c, err, "订单不存在", "查询订单失败", "orderId", orderId)
+		return
+	}
+	op := orders.Admin(adminId)
+	record := func(tx *gorm.DB) error {
+		return audit.Record(tx, c, "order.cancel", "order", orderId,
+			gin.H{"order_status": order.OrderStatus}, gin.H{"reason": req.Reason})
+	}
+
+	switch order.OrderStatus {
+	case models.OrderStatusPendingPayment:
+		err = orders.AdminCancel(c.Request.Context(), db, orderId, op, "后台取消订单："+req.Reason, record)
+	case models.OrderStatusPaid:
+		err = aftersale.CancelPaid(c.Request.Context(), db, orderId, op, req.Reason, record)
+	default:
+		c.JSON(http.StatusConflict, gin.H{"success": false, "message": fmt.Sprintf("订单%s，无法取消", order.OrderStatus.Label())})
+		return
+	}
+	switch {
+	case errors.Is(err, aftersale.ErrRefundFailed):
+		slog.Error("取消订单退款失败", "orderId", orderId, "error", err)
+		c.JSON(http.StatusBadGateway, gin.H{"success": false, "message": "订单已进入退款中，但退款发起失败，可在售后列表重试退款", "error": err.Error()})
+	case errors.Is(err, aftersale.ErrOrderNotEligible):
+		c.JSON(http.StatusConflict, gin.H{"success": false, "message": err.Error()})
+	case err != nil:
+		respondOrderError(c, err, "取消订单失败", "orderId", orderId)
+	default:
+		c.JSON(http.StatusOK, gin.H{"success": true, "message": "订单已取消"})
+	}
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sunzhaoc/plant_be/internal/audit"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errExceptionResolved = errors.New("该异常已处理")
+
+func orderExceptionView(e models.OrderException) gin.H {
+	view := gin.H{
+		"exception_id": e.Id,
+		"order_sn":     e.OrderSn,
+		"kind":         e.Kind,
+		"detail":       e.Detail,
+		"resolved":     e.Resolved,
+		"resolved_by":  e.ResolvedBy,
+		"create_time":  e.CreateTime.Format("2006-01-02 15:04:05"),
+	}
+	if e.ResolveTime != nil {
+		view["resolve_time"] = e.ResolveTime.Format("2006-01-02 15:04:05")
+	}
+	return view
+}
+
+// AdminGetOrderExceptions 订单异常列表（最近100条），默认只返回未处理的，resolved=1 返回已处理的
+func AdminGetOrderExceptions(c *gin.Context) {
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	var exceptions []models.OrderException
+	err = db.Where("resolved = ?", c.Query("resolved") == "1").Order("id DESC").Limit(100).Find(&exceptions).Error
+	if err != nil {
+		slog.Error("查询订单异常失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	list := make([]gin.H, 0, len(exceptions))
+	for _, e := range exceptions {
+		list = append(list, orderExceptionView(e))
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "", "data": list})
+}
+
+// AdminResolveOrderException 人工处理完成后标记订单异常为已处理
+func AdminResolveOrderException(c *gin.Context) {
+	adminId, ok := getUserId(c)
+	if !ok {
+		return
+	}
+	exceptionId, ok := parseUintParam(c, "exceptionId")
+	if !ok {
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var e models.OrderException
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", exceptionId).Take(&e).Error; err != nil {
+			return err
+		}
+		if e.Resolved {
+			return errExceptionResolved
+		}
+		err := tx.Model(&e).Updates(map[string]interface{}{
+			"resolved":     true,
+			"resolved_by":  adminId,
+			"resolve_time": time.Now(),
+		}).Error
+		if err != nil {
+			return err
+		}
+		return audit.Record(tx, c, "order_exception.resolve", "order_exception", exceptionId, nil, gin.H{"order_sn": e.OrderSn, "kind": e.Kind})
+	})
+	switch {
+	case errors.Is(err, errExceptionResolved):
+		c.JSON(http.StatusConflict, gin.H{"success": false, "message": err.Error()})
+	case err != nil:
+		respondAdminError(c, err, "订单异常不存在", "处理订单异常失败", "exceptionId", exceptionId)
+	default:
+		c.JSON(http.StatusOK, gin.H{"success": true, "message": "处理成功"})
+	}
+}
@@ -0,0 +1,592 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sunzhaoc/plant_be/internal/audit"
+	"github.com/sunzhaoc/plant_be/internal/inventory"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"github.com/sunzhaoc/plant_be/pkg/money"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MaxSkuPrice SKU单价上限，防止误录入
+const MaxSkuPrice = 1000000 * money.Yuan
+
+// AdminPlantRequest 新增/修改植物请求
+type AdminPlantRequest struct {
+	Name               string  `json:"name" binding:"required,max=64"`
+	LatinName          string  `json:"latinName" binding:"max=128"`
+	MainImgUrl         string  `json:"mainImgUrl" binding:"required,max=255"`
+	CategoryId         uint64  `json:"categoryId"`
+	ShippingTemplateId *uint64 `json:"shippingTemplateId"` // 运费模板，为空使用默认模板
+}
+
+type AdminOnSaleRequest struct {
+	OnSale bool `json:"onSale"`
+}
+
+// AdminSkuRequest 新增/修改SKU请求
+type AdminSkuRequest struct {
+	Size        string      `json:"size" binding:"required,max=32"`
+	Price       money.Money `json:"price" binding:"required,gt=0"`
+	Stock       uint        `json:"stock"`       // 初始库存，仅新增时生效；已有SKU通过库存调整接口增减
+	WeightGrams uint        `json:"weightGrams"` // 计费重量（克，含包装）
+	Sort        *int        `json:"sort"`        // 为空时新增SKU排在最后，修改时保持不变
+}
+
+// AdminSkuStockRequest 按增量调整SKU库存，避免整体覆盖期间已售出的库存
+type AdminSkuStockRequest struct {
+	Delta  int64  `json:"delta" binding:"required,ne=0"` // 正数入库，负数出库
+	Reason string `json:"reason" binding:"max=200"`
+}
+
+// AdminImageRequest 添加植物图片请求
+type AdminImageRequest struct {
+	ImgUrl string `json:"imgUrl" binding:"required,max=255"`
+	Sort   *int   `json:"sort"` // 为空时排在最后
+}
+
+// AdminSortRequest 按给定ID顺序重排，ID必须覆盖该植物下的全部记录
+type AdminSortRequest struct {
+	Ids []uint64 `json:"ids" binding:"required,min=1"`
+}
+
+// parseUintParam 解析路径中的ID参数
+func parseUintParam(c *gin.Context, name string) (uint64, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "ID参数无效"})
+		return 0, false
+	}
+	return id, true
+}
+
+// refreshMinPrice 按SKU最低价刷新植物起始价格
+func refreshMinPrice(tx *gorm.DB, plantId uint64) error {
+	return tx.Exec(`UPDATE plant.plants
+		SET min_price = COALESCE((SELECT MIN(price) FROM plant.plant_sku WHERE plant_id = ?), 0)
+		WHERE id = ?`, plantId, plantId).Error
+}
+
+// nextSort 返回表中该植物下一个排序值
+func nextSort(tx *gorm.DB, model any, plantId uint64) (int, error) {
+	var maxSort int
+	err := tx.Model(model).Where("plant_id = ?", plantId).Select("COALESCE(MAX(sort), 0)").Scan(&maxSort).Error
+	return maxSort + 1, err
+}
+
+// reorder 按 ids 的顺序重写 sort 字段，ids 必须与该植物下的记录一一对应
+func reorder(tx *gorm.DB, model any, plantId uint64, ids []uint64) (bool, error) {
+	var existing []uint64
+	if err := tx.Model(model).Where("plant_id = ?", plantId).Pluck("id", &existing).Error; err != nil {
+		return false, err
+	}
+	if len(existing) != len(ids) {
+		return false, nil
+	}
+	set := make(map[uint64]struct{}, len(existing))
+	for _, id := range existing {
+		set[id] = struct{}{}
+	}
+	for _, id := range ids {
+		if _, ok := set[id]; !ok {
+			return false, nil
+		}
+		delete(set, id) // 防止重复ID
+	}
+	for i, id := range ids {
+		if err := tx.Model(model).Where("id = ?", id).Update("sort", i+1).Error; err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// respondAdminError 统一处理后台写操作的错误响应
+func respondAdminError(c *gin.Context, err error, notFoundMsg string, logMsg string, args ...any) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": notFoundMsg})
+		return
+	}
+	slog.Error(logMsg, append(args, "error", err)...)
+	c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+}
+
+// AdminGetPlant 后台获取植物完整信息（含未上架、SKU与图片）
+func AdminGetPlant(c *gin.Context) {
+	plantId, ok := parseUintParam(c, "plantId")
+	if !ok {
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	var plant models.Plant
+	if err := db.Where("id = ?", plantId).Take(&plant).Error; err != nil {
+		respondAdminError(c, err, "植物不存在", "查询植物失败", "plantId", plantId)
+		return
+	}
+	var skus []models.PlantSku
+	if err := db.Where("plant_id = ?", plantId).Order("sort, id").Find(&skus).Error; err != nil {
+		respondAdminError(c, err, "", "查询植物SKU失败", "plantId", plantId)
+		return
+	}
+	var images []models.PlantImage
+	if err := db.Where("plant_id = ?", plantId).Order("sort, id").Find(&images).Error; err != nil {
+		respondAdminError(c, err, "", "查询植物图片失败", "plantId", plantId)
+		return
+	}
+
+	skuList := make([]gin.H, 0, len(skus))
+	for _, s := range skus {
+		skuList = append(skuList, gin.H{"sku_id": s.Id, "size": s.Size, "price": s.Price, "stock": s.Stock, "weight_grams": s.WeightGrams, "sort": s.Sort})
+	}
+	imageList := make([]gin.H, 0, len(images))
+	for _, i := range images {
+		imageList = append(imageList, gin.H{"image_id": i.Id, "img_url": i.ImgUrl, "sort": i.Sort})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"plant_id":             plant.Id,
+			"name":                 plant.Name,
+			"latin_name":           plant.LatinName,
+			"main_img_url":         plant.MainImgUrl,
+			"min_price":            plant.MinPrice,
+			"category_id":          plant.CategoryId,
+			"is_on_sale":           plant.IsOnSale,
+			"shipping_template_id": plant.ShippingTemplateId,
+			"skus":                 skuList,
+			"images":               imageList,
+		},
+	})
+}
+
+// AdminCreatePlant 新增植物（默认未上架，补全SKU后再上架）
+func AdminCreatePlant(c *gin.Context) {
+	var req AdminPlantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "参数校验失败", "error": err.Error()})
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	plant := models.Plant{
+		Name:               strings.TrimSpace(req.Name),
+		LatinName:          strings.TrimSpace(req.LatinName),
+		MainImgUrl:         req.MainImgUrl,
+		CategoryId:         req.CategoryId,
+		ShippingTemplateId: req.ShippingTemplateId,
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&plant).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, c, "plant.create", "plant", plant.Id, nil, plant)
+	})
+	if err != nil {
+		respondAdminError(c, err, "", "新增植物失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "新增植物成功", "data": gin.H{"plant_id": plant.Id}})
+}
+
+// AdminUpdatePlant 修改植物基本信息
+func AdminUpdatePlant(c *gin.Context) {
+	plantId, ok := parseUintParam(c, "plantId")
+	if !ok {
+		return
+	}
+	var req AdminPlantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "参数校验失败", "error": err.Error()})
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var plant models.Plant
+		if err := tx.Where("id = ?", plantId).Take(&plant).Error; err != nil {
+			return err
+		}
+		before := plant
+		plant.Name = strings.TrimSpace(req.Name)
+		plant.LatinName = strings.TrimSpace(req.LatinName)
+		plant.MainImgUrl = req.MainImgUrl
+		plant.CategoryId = req.CategoryId
+		plant.ShippingTemplateId = req.ShippingTemplateId
+		if err := tx.Select("name", "latin_name", "main_img_url", "category_id", "shipping_template_id").Save(&plant).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, c, "plant.update", "plant", plantId, before, plant)
+	})
+	if err != nil {
+		respondAdminError(c, err, "植物不存在", "修改植物失败", "plantId", plantId)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "修改植物成功"})
+}
+
+// AdminSetPlantOnSale 上架/下架植物，上架前至少需要一个SKU
+func AdminSetPlantOnSale(c *gin.Context) {
+	plantId, ok := parseUintParam(c, "plantId")
+	if !ok {
+		return
+	}
+	var req AdminOnSaleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "参数校验失败", "error": err.Error()})
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	var noSku bool
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var plant models.Plant
+		if err := tx.Where("id = ?", plantId).Take(&plant).Error; err != nil {
+			return err
+		}
+		if req.OnSale {
+			var skuCount int64
+			if err := tx.Model(&models.PlantSku{}).Where("plant_id = ?", plantId).Count(&skuCount).Error; err != nil {
+				return err
+			}
+			if skuCount == 0 {
+				noSku = true
+				return nil
+			}
+		}
+		if err := tx.Model(&plant).Update("is_on_sale", req.OnSale).Error; err != nil {
+			return err
+		}
+		action := "plant.delist"
+		if req.OnSale {
+			action = "plant.list"
+		}
+		return audit.Record(tx, c, action, "plant", plantId, gin.H{"is_on_sale": !req.OnSale}, gin.H{"is_on_sale": req.OnSale})
+	})
+	if err != nil {
+		respondAdminError(c, err, "植物不存在", "修改植物上架状态失败", "plantId", plantId)
+		return
+	}
+	if noSku {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "请先添加规格再上架"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "操作成功"})
+}
+
+// AdminCreateSku 为植物新增SKU
+func AdminCreateSku(c *gin.Context) {
+	plantId, ok := parseUintParam(c, "plantId")
+	if !ok {
+		return
+	}
+	var req AdminSkuRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "参数校验失败", "error": err.Error()})
+		return
+	}
+	if req.Price > MaxSkuPrice {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "价格超出允许范围"})
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	sku := models.PlantSku{
+		PlantId:     plantId,
+		Size:        strings.TrimSpace(req.Size),
+		Price:       req.Price,
+		Stock:       req.Stock,
+		WeightGrams: req.WeightGrams,
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var plant models.Plant
+		if err := tx.Select("id").Where("id = ?", plantId).Take(&plant).Error; err != nil {
+			return err
+		}
+		if req.Sort != nil {
+			sku.Sort = *req.Sort
+		} else {
+			next, err := nextSort(tx, &models.PlantSku{}, plantId)
+			if err != nil {
+				return err
+			}
+			sku.Sort = next
+		}
+		if err := tx.Create(&sku).Error; err != nil {
+			return err
+		}
+		if err := refreshMinPrice(tx, plantId); err != nil {
+			return err
+		}
+		return audit.Record(tx, c, "sku.create", "sku", sku.Id, nil, sku)
+	})
+	if err != nil {
+		respondAdminError(c, err, "植物不存在", "新增SKU失败", "plantId", plantId)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "新增规格成功", "data": gin.H{"sku_id": sku.Id}})
+}
+
+// AdminUpdateSku 修改SKU的规格名称、价格、重量与排序，库存通过 AdminAdjustSkuStock 按增量调整
+func AdminUpdateSku(c *gin.Context) {
+	skuId, ok := parseUintParam(c, "skuId")
+	if !ok {
+		return
+	}
+	var req AdminSkuRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "参数校验失败", "error": err.Error()})
+		return
+	}
+	if req.Price > MaxSkuPrice {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "价格超出允许范围"})
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var sku models.PlantSku
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", skuId).Take(&sku).Error; err != nil {
+			return err
+		}
+		before := sku
+		sku.Size = strings.TrimSpace(req.Size)
+		sku.Price = req.Price
+		sku.WeightGrams = req.WeightGrams
+		if req.Sort != nil {
+			sku.Sort = *req.Sort
+		}
+		if err := tx.Select("size", "price", "weight_grams", "sort").Save(&sku).Error; err != nil {
+			return err
+		}
+		if err := refreshMinPrice(tx, sku.PlantId); err != nil {
+			return err
+		}
+		return audit.Record(tx, c, "sku.update", "sku", skuId, before, sku)
+	})
+	if err != nil {
+		respondAdminError(c, err, "规格不存在", "修改SKU失败", "skuId", skuId)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "修改规格成功"})
+}
+
+// AdminAdjustSkuStock 按增量调整SKU库存，在行锁内基于当前库存计算，不会覆盖期间已售出的数量；
+// 扣减后的库存不能少于待支付订单已预占的数量
+func AdminAdjustSkuStock(c *gin.Context) {
+	skuId, ok := parseUintParam(c, "skuId")
+	if !ok {
+		return
+	}
+	var req AdminSkuStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "参数校验失败", "error": err.Error()})
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	var stock uint
+	insufficient := false
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var sku models.PlantSku
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", skuId).Take(&sku).Error; err != nil {
+			return err
+		}
+		reserved, err := inventory.Reserved(tx, skuId)
+		if err != nil {
+			return err
+		}
+		if int64(sku.Stock)-reserved+req.Delta < 0 {
+			insufficient = true
+			return nil
+		}
+		stock = uint(int64(sku.Stock) + req.Delta)
+		if err := tx.Model(&sku).Update("stock", stock).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, c, "sku.adjust_stock", "sku", skuId,
+			gin.H{"stock": sku.Stock},
+			gin.H{"stock": stock, "delta": req.Delta, "reason": strings.TrimSpace(req.Reason)})
+	})
+	if err != nil {
+		respondAdminError(c, err, "规格不存在", "调整SKU库存失败", "skuId", skuId)
+		return
+	}
+	if insufficient {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "扣减数量超过可用库存（待支付订单已预占的库存不可扣减）"})
+		return
+	}
+	// 增量同步到可用库存计数，失败时由库存对账任务修正
+	if err := inventory.AdjustAvailable(c.Request.Context(), skuId, req.Delta); err != nil {
+		slog.Error("同步可用库存失败", "skuId", skuId, "delta", req.Delta, "error", err)
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "调整库存成功", "data": gin.H{"stock": stock}})
+}
+
+// AdminSortSkus 调整植物下SKU的展示顺序
+func AdminSortSkus(c *gin.Context) {
+	adminReorder(c, &models.PlantSku{}, "sku.sort")
+}
+
+// AdminCreateImage 为植物添加图片
+func AdminCreateImage(c *gin.Context) {
+	plantId, ok := parseUintParam(c, "plantId")
+	if !ok {
+		return
+	}
+	var req AdminImageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "参数校验失败", "error": err.Error()})
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	image := models.PlantImage{PlantId: plantId, ImgUrl: req.ImgUrl}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var plant models.Plant
+		if err := tx.Select("id").Where("id = ?", plantId).Take(&plant).Error; err != nil {
+			return err
+		}
+		if req.Sort != nil {
+			image.Sort = *req.Sort
+		} else {
+			next, err := nextSort(tx, &models.PlantImage{}, plantId)
+			if err != nil {
+				return err
+			}
+			image.Sort = next
+		}
+		if err := tx.Create(&image).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, c, "image.create", "image", image.Id, nil, image)
+	})
+	if err != nil {
+		respondAdminError(c, err, "植物不存在", "添加植物图片失败", "plantId", plantId)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "添加图片成功", "data": gin.H{"image_id": image.Id}})
+}
+
+// AdminDeleteImage 删除植物图片
+func AdminDeleteImage(c *gin.Context) {
+	imageId, ok := parseUintParam(c, "imageId")
+	if !ok {
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var image models.PlantImage
+		if err := tx.Where("id = ?", imageId).Take(&image).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&image).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, c, "image.delete", "image", imageId, image, nil)
+	})
+	if err != nil {
+		respondAdminError(c, err, "图片不存在", "删除植物图片失败", "imageId", imageId)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "删除图片成功"})
+}
+
+// AdminSortImages 调整植物图片的展示顺序
+func AdminSortImages(c *gin.Context) {
+	adminReorder(c, &models.PlantImage{}, "image.sort")
+}
+
+// adminReorder SKU与图片重排的公共实现
+func adminReorder(c *gin.Context, model any, action string) {
+	plantId, ok := parseUintParam(c, "plantId")
+	if !ok {
+		return
+	}
+	var req AdminSortRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "参数校验失败", "error": err.Error()})
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	var matched bool
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		matched, err = reorder(tx, model, plantId, req.Ids)
+		if err != nil || !matched {
+			return err
+		}
+		return audit.Record(tx, c, action, "plant", plantId, nil, req.Ids)
+	})
+	if err != nil {
+		respondAdminError(c, err, "", "调整排序失败", "plantId", plantId, "action", action)
+		return
+	}
+	if !matched {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "排序ID与现有记录不一致"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "调整排序成功"})
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sunzhaoc/plant_be/internal/audit"
+	"github.com/sunzhaoc/plant_be/internal/auth"
+	"github.com/sunzhaoc/plant_be/internal/rbac"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"gorm.io/gorm"
+)
+
+type AdminGrantRoleRequest struct {
+	Role string `json:"role" binding:"required,max=32"`
+}
+
+// AdminGetRoles 查询全部角色及其权限
+func AdminGetRoles(c *gin.Context) {
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	type rolePermission struct {
+		RoleCode       string
+		RoleName       string
+		PermissionCode *string
+	}
+	var rows []rolePermission
+	err = db.Raw(`SELECT r.code role_code, r.name role_name, p.code permission_code
+		FROM plant.roles r
+		LEFT JOIN plant.role_permissions rp ON rp.role_id = r.id
+		LEFT JOIN plant.permissions p ON p.id = rp.permission_id
+		ORDER BY r.id, p.code`).Scan(&rows).Error
+	if err != nil {
+		slog.Error("查询角色列表失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	roles := make([]gin.H, 0)
+	index := make(map[string]int)
+	for _, row := range rows {
+		i, ok := index[row.RoleCode]
+		if !ok {
+			i = len(roles)
+			index[row.RoleCode] = i
+			roles = append(roles, gin.H{"code": row.RoleCode, "name": row.RoleName, "permissions": []string{}})
+		}
+		if row.PermissionCode != nil {
+			roles[i]["permissions"] = append(roles[i]["permissions"].([]string), *row.PermissionCode)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "", "data": roles})
+}
+
+// AdminGetUserRoles 查询用户当前的角色与权限
+func AdminGetUserRoles(c *gin.Context) {
+	userId, ok := parseUintParam(c, "userId")
+	if !ok {
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	var user models.User
+	if err := db.Select("id").Where("id = ?", userId).Take(&user).Error; err != nil {
+		respondAdminError(c, err, "用户不存在", "查询用户失败", "userId", userId)
+		return
+	}
+	access, err := rbac.LoadAccess(db, userId)
+	if err != nil {
+		slog.Error("查询用户角色失败", "userId", userId, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    gin.H{"roles": access.Roles, "permissions": access.Permissions},
+	})
+}
+
+// AdminGrantRole 授予用户角色
+func AdminGrantRole(c *gin.Context) {
+	userId, ok := parseUintParam(c, "userId")
+	if !ok {
+		return
+	}
+	var req AdminGrantRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "参数校验失败", "error": err.Error()})
+		return
+	}
+	changeUserRole(c, userId, req.Role, true)
+}
+
+// AdminRevokeRole 撤销用户角色
+func AdminRevokeRole(c *gin.Context) {
+	userId, ok := parseUintParam(c, "userId")
+	if !ok {
+		return
+	}
+	role := c.Param("role")
+	// 防止管理员误操作后无人可授权
+	if operatorId, _ := c.Get("userId"); role == rbac.RoleAdmin && operatorId == uint(userId) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "不能撤销自己的管理员角色"})
+		return
+	}
+	changeUserRole(c, userId, role, false)
+}
+
+// changeUserRole 授予/撤销角色并记录审计日志，变更后吊销该用户的 Access Token，
+// 客户端刷新 Token 时即获得最新权限，无需重新登录
+func changeUserRole(c *gin.Context, userId uint64, role string, grant bool) {
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	var changed bool
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Select("id").Where("id = ?", userId).Take(&user).Error; err != nil {
+			return err
+		}
+		action := "role.revoke"
+		if grant {
+			action = "role.grant"
+			changed, err = rbac.Grant(tx, userId, role)
+		} else {
+			changed, err = rbac.Revoke(tx, userId, role)
+		}
+		if err != nil || !changed {
+			return err
+		}
+		return audit.Record(tx, c, action, "user", userId, nil, gin.H{"role": role})
+	})
+	if errors.Is(err, rbac.ErrRoleNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	if err != nil {
+		respondAdminError(c, err, "用户不存在", "修改用户角色失败", "userId", userId, "role", role)
+		return
+	}
+
+	if changed {
+		if err := auth.RevokeAccessTokens(c.Request.Context(), uint(userId)); err != nil {
+			// 角色已变更，旧 Token 最迟在 TokenExpire 后失效
+			slog.Error("角色变更后吊销Access Token失败", "userId", userId, "error", err)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "操作成功"})
+}
@@ -0,0 +1,384 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sunzhaoc/plant_be/internal/audit"
+	"github.com/sunzhaoc/plant_be/internal/shipping"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"github.com/sunzhaoc/plant_be/pkg/money"
+	"github.com/sunzhaoc/plant_be/pkg/region"
+	"gorm.io/gorm"
+)
+
+// AdminShippingRuleRequest 运费规则，Regions 为空表示其他地区
+type AdminShippingRuleRequest struct {
+	Regions        []string    `json:"regions"`
+	FirstUnit      uint        `json:"firstUnit" binding:"required,min=1"` // 首重（克）/首件（件）
+	FirstFee       money.Money `json:"firstFee" binding:"min=0"`
+	AdditionalUnit uint        `json:"additionalUnit"` // 续重（克）/续件（件），为空时为1
+	AdditionalFee  money.Money `json:"additionalFee" binding:"min=0"`
+}
+
+// AdminShippingTemplateRequest 新增/修改运费模板请求，修改时整体替换规则
+type AdminShippingTemplateRequest struct {
+	Name            string                     `json:"name" binding:"required,max=64"`
+	ChargeMode      models.ChargeMode          `json:"chargeMode" binding:"required,oneof=1 2"`
+	FreeThreshold   money.Money                `json:"freeThreshold" binding:"min=0"` // 包邮门槛，0 表示不包邮
+	ExcludedRegions []string                   `json:"excludedRegions"`               // 不配送的省份
+	IsDefault       bool                       `json:"isDefault"`                     // 设为默认模板时取消其他模板的默认标记
+	Rules           []AdminShippingRuleRequest `json:"rules" binding:"required,min=1,dive"`
+}
+
+// AdminShippingRestrictionRequest 新增植物季节性配送限制请求
+type AdminShippingRestrictionRequest struct {
+	Regions  []string `json:"regions" binding:"required,min=1"`
+	StartDay string   `json:"startDay" binding:"required"` // MM-DD
+	EndDay   string   `json:"endDay" binding:"required"`   // MM-DD（含），早于开始日期表示跨年
+	Reason   string   `json:"reason" binding:"max=128"`
+}
+
+// joinRegions 将省份（代码、全称或简称）统一为省份代码后以逗号拼接，第二个返回值为无法识别的省份
+func joinRegions(regions []string) (string, string) {
+	codes := make([]string, 0, len(regions))
+	for _, r := range regions {
+		if r = strings.TrimSpace(r); r == "" {
+			continue
+		}
+		p, ok := region.Lookup(r)
+		if !ok {
+			return "", r
+		}
+		codes = append(codes, p.Code)
+	}
+	return strings.Join(codes, ","), ""
+}
+
+func splitRegions(regions string) []string {
+	if regions == "" {
+		return []string{}
+	}
+	return strings.Split(regions, ",")
+}
+
+// buildShippingRules 校验规则并转换为模型，同时返回不配送省份的代码列表与错误提示
+func buildShippingRules(req AdminShippingTemplateRequest) ([]models.ShippingTemplateRule, string, string) {
+	if req.FreeThreshold > MaxSkuPrice {
+		return nil, "", "包邮门槛超出上限"
+	}
+	excluded, unknown := joinRegions(req.ExcludedRegions)
+	if unknown != "" {
+		return nil, "", "未知的省份: " + unknown
+	}
+	rules := make([]models.ShippingTemplateRule, 0, len(req.Rules))
+	fallbacks := 0
+	for _, r := range req.Rules {
+		if r.FirstFee > MaxSkuPrice || r.AdditionalFee > MaxSkuPrice {
+			return nil, "", "运费超出上限"
+		}
+		regions, unknown := joinRegions(r.Regions)
+		if unknown != "" {
+			return nil, "", "未知的省份: " + unknown
+		}
+		if regions == "" {
+			fallbacks++
+		}
+		if r.AdditionalUnit == 0 {
+			r.AdditionalUnit = 1
+		}
+		rules = append(rules, models.ShippingTemplateRule{
+			Regions:        regions,
+			FirstUnit:      r.FirstUnit,
+			FirstFee:       r.FirstFee,
+			AdditionalUnit: r.AdditionalUnit,
+			AdditionalFee:  r.AdditionalFee,
+		})
+	}
+	if fallbacks > 1 {
+		return nil, "", "只能有一条适用于其他地区的规则"
+	}
+	return rules, excluded, ""
+}
+
+// saveShippingTemplate 写入模板与规则（替换原有规则），设为默认时取消其他模板的默认标记
+func saveShippingTemplate(tx *gorm.DB, template *models.ShippingTemplate, rules []models.ShippingTemplateRule) error {
+	if template.IsDefault {
+		if err := tx.Model(&models.ShippingTemplate{}).Where("is_default = ? AND id <> ?", true, template.Id).Update("is_default", false).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Where("template_id = ?", template.Id).Delete(&models.ShippingTemplateRule{}).Error; err != nil {
+		return err
+	}
+	for i := range rules {
+		rules[i].TemplateId = template.Id
+	}
+	return tx.Create(&rules).Error
+}
+
+func shippingTemplateView(t models.ShippingTemplate, rules []models.ShippingTemplateRule) gin.H {
+	ruleList := make([]gin.H, 0, len(rules))
+	for _, r := range rules {
+		ruleList = append(ruleList, gin.H{
+			"regions":         splitRegions(r.Regions),
+			"first_unit":      r.FirstUnit,
+			"first_fee":       r.FirstFee,
+			"additional_unit": r.AdditionalUnit,
+			"additional_fee":  r.AdditionalFee,
+		})
+	}
+	return gin.H{
+		"template_id":      t.Id,
+		"name":             t.Name,
+		"charge_mode":      t.ChargeMode,
+		"free_threshold":   t.FreeThreshold,
+		"excluded_regions": splitRegions(t.ExcludedRegions),
+		"is_default":       t.IsDefault,
+		"rules":            ruleList,
+	}
+}
+
+// AdminGetShippingTemplates 后台运费模板列表（含规则）
+func AdminGetShippingTemplates(c *gin.Context) {
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	var templates []models.ShippingTemplate
+	if err := db.Order("id").Find(&templates).Error; err != nil {
+		slog.Error("查询运费模板失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	var rules []models.ShippingTemplateRule
+	if err := db.Order("id").Find(&rules).Error; err != nil {
+		slog.Error("查询运费规则失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	rulesByTemplate := make(map[uint64][]models.ShippingTemplateRule, len(templates))
+	for _, r := range rules {
+		rulesByTemplate[r.TemplateId] = append(rulesByTemplate[r.TemplateId], r)
+	}
+	list := make([]gin.H, 0, len(templates))
+	for _, t := range templates {
+		list = append(list, shippingTemplateView(t, rulesByTemplate[t.Id]))
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "", "data": list})
+}
+
+// AdminCreateShippingTemplate 新增运费模板
+func AdminCreateShippingTemplate(c *gin.Context) {
+	var req AdminShippingTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "参数校验失败", "error": err.Error()})
+		return
+	}
+	rules, excluded, msg := buildShippingRules(req)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": msg})
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	template := models.ShippingTemplate{
+		Name:            strings.TrimSpace(req.Name),
+		ChargeMode:      req.ChargeMode,
+		FreeThreshold:   req.FreeThreshold,
+		ExcludedRegions: excluded,
+		IsDefault:       req.IsDefault,
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&template).Error; err != nil {
+			return err
+		}
+		if err := saveShippingTemplate(tx, &template, rules); err != nil {
+			return err
+		}
+		return audit.Record(tx, c, "shipping_template.create", "shipping_template", template.Id, nil, shippingTemplateView(template, rules))
+	})
+	if err != nil {
+		respondAdminError(c, err, "", "新增运费模板失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "新增运费模板成功", "data": gin.H{"template_id": template.Id}})
+}
+
+// AdminUpdateShippingTemplate 修改运费模板，规则整体替换
+func AdminUpdateShippingTemplate(c *gin.Context) {
+	templateId, ok := parseUintParam(c, "templateId")
+	if !ok {
+		return
+	}
+	var req AdminShippingTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "参数校验失败", "error": err.Error()})
+		return
+	}
+	rules, excluded, msg := buildShippingRules(req)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": msg})
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var template models.ShippingTemplate
+		if err := tx.Where("id = ?", templateId).Take(&template).Error; err != nil {
+			return err
+		}
+		var beforeRules []models.ShippingTemplateRule
+		if err := tx.Where("template_id = ?", templateId).Order("id").Find(&beforeRules).Error; err != nil {
+			return err
+		}
+		before := shippingTemplateView(template, beforeRules)
+		template.Name = strings.TrimSpace(req.Name)
+		template.ChargeMode = req.ChargeMode
+		template.FreeThreshold = req.FreeThreshold
+		template.ExcludedRegions = excluded
+		template.IsDefault = req.IsDefault
+		if err := tx.Select("name", "charge_mode", "free_threshold", "excluded_regions", "is_default").Save(&template).Error; err != nil {
+			return err
+		}
+		if err := saveShippingTemplate(tx, &template, rules); err != nil {
+			return err
+		}
+		return audit.Record(tx, c, "shipping_template.update", "shipping_template", templateId, before, shippingTemplateView(template, rules))
+	})
+	if err != nil {
+		respondAdminError(c, err, "运费模板不存在", "修改运费模板失败", "templateId", templateId)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "修改运费模板成功"})
+}
+
+// AdminGetShippingRestrictions 植物的季节性配送限制
+func AdminGetShippingRestrictions(c *gin.Context) {
+	plantId, ok := parseUintParam(c, "plantId")
+	if !ok {
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	var restrictions []models.PlantShippingRestriction
+	if err := db.Where("plant_id = ?", plantId).Order("id").Find(&restrictions).Error; err != nil {
+		respondAdminError(c, err, "", "查询配送限制失败", "plantId", plantId)
+		return
+	}
+	list := make([]gin.H, 0, len(restrictions))
+	for _, r := range restrictions {
+		list = append(list, gin.H{
+			"restriction_id": r.Id,
+			"regions":        splitRegions(r.Regions),
+			"start_day":      r.StartDay,
+			"end_day":        r.EndDay,
+			"reason":         r.Reason,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "", "data": list})
+}
+
+// AdminCreateShippingRestriction 为植物新增季节性配送限制
+func AdminCreateShippingRestriction(c *gin.Context) {
+	plantId, ok := parseUintParam(c, "plantId")
+	if !ok {
+		return
+	}
+	var req AdminShippingRestrictionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "参数校验失败", "error": err.Error()})
+		return
+	}
+	if !shipping.ValidDay(req.StartDay) || !shipping.ValidDay(req.EndDay) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "日期格式应为 MM-DD"})
+		return
+	}
+	regions, unknown := joinRegions(req.Regions)
+	if unknown != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "未知的省份: " + unknown})
+		return
+	}
+	if regions == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "请选择限制的省份"})
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	restriction := models.PlantShippingRestriction{
+		PlantId:  plantId,
+		Regions:  regions,
+		StartDay: req.StartDay,
+		EndDay:   req.EndDay,
+		Reason:   strings.TrimSpace(req.Reason),
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var plant models.Plant
+		if err := tx.Select("id").Where("id = ?", plantId).Take(&plant).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&restriction).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, c, "shipping_restriction.create", "plant", plantId, nil, restriction)
+	})
+	if err != nil {
+		respondAdminError(c, err, "植物不存在", "新增配送限制失败", "plantId", plantId)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "新增配送限制成功", "data": gin.H{"restriction_id": restriction.Id}})
+}
+
+// AdminDeleteShippingRestriction 删除季节性配送限制
+func AdminDeleteShippingRestriction(c *gin.Context) {
+	restrictionId, ok := parseUintParam(c, "restrictionId")
+	if !ok {
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var restriction models.PlantShippingRestriction
+		if err := tx.Where("id = ?", restrictionId).Take(&restriction).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&restriction).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, c, "shipping_restriction.delete", "plant", restriction.PlantId, restriction, nil)
+	})
+	if err != nil {
+		respondAdminError(c, err, "配送限制不存在", "删除配送限制失败", "restrictionId", restrictionId)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "删除配送限制成功"})
+}
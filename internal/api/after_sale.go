@@ -0,0 +1,430 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sunzhaoc/plant_be/internal/aftersale"
+	"github.com/sunzhaoc/plant_be/internal/audit"
+	"github.com/sunzhaoc/plant_be/internal/orders"
+	"github.com/sunzhaoc/plant_be/pkg/aliyun"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"github.com/sunzhaoc/plant_be/pkg/money"
+	"github.com/sunzhaoc/plant_be/pkg/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	maxAfterSaleImageSize = 5 << 20 // 售后凭证单张图片上限 5MB
+	maxAfterSaleImages    = 6       // 单个售后申请最多凭证图片数
+)
+
+// afterSaleImageExts 允许上传的凭证图片类型
+var afterSaleImageExts = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// AfterSaleRequest 发起售后申请请求
+type AfterSaleRequest struct {
+	OrderItemId uint64               `json:"orderItemId" binding:"required"`
+	Type        models.AfterSaleType `json:"type" binding:"required,oneof=1 2 3"`
+	Quantity    uint                 `json:"quantity" binding:"required,min=1"`
+	Reason      string               `json:"reason" binding:"required,max=64"`
+	Description string               `json:"description" binding:"max=500"`
+	Images      []string             `json:"images"` // UploadAfterSaleImage 返回的 OSS Key
+}
+
+// AdminApproveAfterSaleRequest 审核通过售后申请请求
+type AdminApproveAfterSaleRequest struct {
+	RefundAmount *money.Money `json:"refundAmount"` // 为空时按申请金额退款
+	Restock      bool         `json:"restock"`
+	Remark       string       `json:"remark" binding:"max=255"`
+}
+
+// AdminRejectAfterSaleRequest 驳回售后申请请求
+type AdminRejectAfterSaleRequest struct {
+	Remark string `json:"remark" binding:"required,max=255"`
+}
+
+// afterSaleImagePrefix 用户凭证图片的 OSS Key 前缀，申请只能引用本人上传的图片
+func afterSaleImagePrefix(userId uint64) string {
+	return fmt.Sprintf("after-sales/%d/", userId)
+}
+
+func afterSaleView(a models.AfterSale) gin.H {
+	var keys []string
+	if a.Images != nil {
+		if err := json.Unmarshal([]byte(*a.Images), &keys); err != nil {
+			slog.Warn("解析售后凭证图片失败", "afterSaleId", a.Id, "error", err)
+		}
+	}
+	expire := time.Now().Unix() + 3600
+	images := make([]string, 0, len(keys))
+	for _, key := range keys {
+		signed, err := cdnConfig.GenerageCdnAuthUrlTypeA(key, expire)
+		if err != nil {
+			continue
+		}
+		images = append(images, signed)
+	}
+	view := gin.H{
+		"after_sale_id": a.Id,
+		"order_sn":      a.OrderSn,
+		"order_item_id": a.OrderItemId,
+		"type":          a.Type,
+		"quantity":      a.Quantity,
+		"reason":        a.Reason,
+		"description":   a.Description,
+		"images":        images,
+		"refund_amount": a.RefundAmount,
+		"status":        a.Status,
+		"status_label":  a.Status.Label(),
+		"admin_remark":  a.AdminRemark,
+		"create_time":   a.CreateTime.Format("2006-01-02 15:04:05"),
+	}
+	if a.HandleTime != nil {
+		view["handle_time"] = a.HandleTime.Format("2006-01-02 15:04:05")
+	}
+	if a.CompleteTime != nil {
+		view["complete_time"] = a.CompleteTime.Format("2006-01-02 15:04:05")
+	}
+	return view
+}
+
+// respondAfterSaleError 售后业务错误映射为对应的响应
+func respondAfterSaleError(c *gin.Context, err error, logMsg string, args ...any) {
+	switch {
+	case errors.Is(err, aftersale.ErrNotFound), errors.Is(err, aftersale.ErrItemNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": err.Error()})
+	case errors.Is(err, aftersale.ErrTypeNotAllowed), errors.Is(err, aftersale.ErrQuantityExceeded), errors.Is(err, aftersale.ErrAmountExceeded):
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+	case errors.Is(err, aftersale.ErrOrderNotEligible), errors.Is(err, aftersale.ErrIllegalState), errors.Is(err, aftersale.ErrPaymentUnsupported):
+		c.JSON(http.StatusConflict, gin.H{"success": false, "message": err.Error()})
+	default:
+		slog.Error(logMsg, append(args, "error", err)...)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+	}
+}
+
+// auditAfterSale 返回在售后变更事务内写入审计日志的 Hook
+func auditAfterSale(c *gin.Context, action string) aftersale.Hook {
+	return func(tx *gorm.DB, before models.AfterSale, after models.AfterSale) error {
+		return audit.Record(tx, c, action, "after_sale", after.Id, before, after)
+	}
+}
+
+// UploadAfterSaleImage 上传售后凭证图片（multipart 字段 file），返回供申请引用的 OSS Key
+func UploadAfterSaleImage(c *gin.Context) {
+	userId, ok := getUserId(c)
+	if !ok {
+		return
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "请选择要上传的图片"})
+		return
+	}
+	if fileHeader.Size > maxAfterSaleImageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "图片大小不能超过5MB"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		slog.Error("读取上传图片失败", "uid", userId, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	defer file.Close()
+
+	// 按文件内容判断类型，不信任客户端声明的 Content-Type 与扩展名
+	head := make([]byte, 512)
+	n, _ := file.Read(head)
+	contentType := http.DetectContentType(head[:n])
+	ext, allowed := afterSaleImageExts[contentType]
+	if !allowed {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "仅支持 JPG、PNG、WEBP 格式的图片"})
+		return
+	}
+	if _, err := file.Seek(0, 0); err != nil {
+		slog.Error("读取上传图片失败", "uid", userId, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	cfg, err := aliyun.ReadAliConfig()
+	if err != nil {
+		slog.Error("OSS未配置，无法上传售后图片", "error", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "message": "图片上传暂不可用"})
+		return
+	}
+	objectKey := afterSaleImagePrefix(userId) + utils.RandomToken(16) + ext
+	if err := aliyun.UploadObject(cfg, objectKey, file, contentType); err != nil {
+		slog.Error("上传售后图片失败", "uid", userId, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	url, _ := cdnConfig.GenerageCdnAuthUrlTypeA(objectKey, time.Now().Unix()+3600)
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "上传成功", "data": gin.H{"key": objectKey, "url": url}})
+}
+
+// CreateAfterSale 用户对订单商品发起售后申请
+func CreateAfterSale(c *gin.Context) {
+	userId, ok := getUserId(c)
+	if !ok {
+		return
+	}
+	var req AfterSaleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "参数校验失败", "error": err.Error()})
+		return
+	}
+	if len(req.Images) > maxAfterSaleImages {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": fmt.Sprintf("凭证图片最多%d张", maxAfterSaleImages)})
+		return
+	}
+	prefix := afterSaleImagePrefix(userId)
+	for _, key := range req.Images {
+		if !strings.HasPrefix(key, prefix) || path.Clean(key) != key {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "凭证图片无效，请重新上传"})
+			return
+		}
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	claim, err := aftersale.File(db, aftersale.FileRequest{
+		UserId:      userId,
+		OrderItemId: req.OrderItemId,
+		Type:        req.Type,
+		Quantity:    req.Quantity,
+		Reason:      req.Reason,
+		Description: req.Description,
+		Images:      req.Images,
+	})
+	if err != nil {
+		respondAfterSaleError(c, err, "发起售后申请失败", "uid", userId, "orderItemId", req.OrderItemId)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "售后申请已提交", "data": afterSaleView(*claim)})
+}
+
+// GetMyAfterSales 我的售后申请列表（最近100条）
+func GetMyAfterSales(c *gin.Context) {
+	userId, ok := getUserId(c)
+	if !ok {
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	var claims []models.AfterSale
+	if err := db.Where("user_id = ?", userId).Order("id DESC").Limit(100).Find(&claims).Error; err != nil {
+		slog.Error("查询售后申请失败", "uid", userId, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	list := make([]gin.H, 0, len(claims))
+	for _, claim := range claims {
+		list = append(list, afterSaleView(claim))
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "", "data": list})
+}
+
+// GetAfterSale 售后申请详情
+func GetAfterSale(c *gin.Context) {
+	userId, ok := getUserId(c)
+	if !ok {
+		return
+	}
+	afterSaleId, ok := parseUintParam(c, "afterSaleId")
+	if !ok {
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	var claim models.AfterSale
+	if err := db.Where("id = ? AND user_id = ?", afterSaleId, userId).Take(&claim).Error; err != nil {
+		respondAdminError(c, err, "售后申请不存在", "查询售后申请失败", "afterSaleId", afterSaleId)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "", "data": afterSaleView(claim)})
+}
+
+// WithdrawAfterSale 用户撤销待审核的售后申请
+func WithdrawAfterSale(c *gin.Context) {
+	userId, ok := getUserId(c)
+	if !ok {
+		return
+	}
+	afterSaleId, ok := parseUintParam(c, "afterSaleId")
+	if !ok {
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	if err := aftersale.Withdraw(db, userId, afterSaleId); err != nil {
+		respondAfterSaleError(c, err, "撤销售后申请失败", "uid", userId, "afterSaleId", afterSaleId)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "已撤销"})
+}
+
+// AdminGetAfterSales 后台售后申请列表，status 可按状态筛选（最近100条）
+func AdminGetAfterSales(c *gin.Context) {
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	query := db.Model(&models.AfterSale{})
+	if raw := c.Query("status"); raw != "" {
+		status, err := strconv.ParseInt(raw, 10, 8)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "状态参数无效"})
+			return
+		}
+		query = query.Where("status = ?", status)
+	}
+	var claims []models.AfterSale
+	if err := query.Order("id DESC").Limit(100).Find(&claims).Error; err != nil {
+		slog.Error("查询售后申请失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	list := make([]gin.H, 0, len(claims))
+	for _, claim := range claims {
+		view := afterSaleView(claim)
+		view["user_id"] = claim.UserId
+		view["restock"] = claim.Restock
+		view["refund_no"] = claim.RefundNo
+		view["refund_error"] = claim.RefundError
+		list = append(list, view)
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "", "data": list})
+}
+
+// AdminApproveAfterSale 审核通过售后申请，退款类申请随即发起原路退款
+func AdminApproveAfterSale(c *gin.Context) {
+	adminId, ok := getUserId(c)
+	if !ok {
+		return
+	}
+	afterSaleId, ok := parseUintParam(c, "afterSaleId")
+	if !ok {
+		return
+	}
+	var req AdminApproveAfterSaleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "参数校验失败", "error": err.Error()})
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	op := orders.Admin(adminId)
+	claim, err := aftersale.Approve(db, afterSaleId, op, aftersale.ApproveRequest{
+		RefundAmount: req.RefundAmount,
+		Restock:      req.Restock,
+		Remark:       req.Remark,
+	}, auditAfterSale(c, "after_sale.approve"))
+	if err != nil {
+		respondAfterSaleError(c, err, "审核售后申请失败", "afterSaleId", afterSaleId)
+		return
+	}
+	if claim.Type.Refundable() {
+		if err := aftersale.Refund(c.Request.Context(), db, afterSaleId, op, auditAfterSale(c, "after_sale.refund")); err != nil {
+			slog.Error("售后退款失败", "afterSaleId", afterSaleId, "error", err)
+			c.JSON(http.StatusBadGateway, gin.H{"success": false, "message": "已同意申请，但退款发起失败，可稍后重试退款", "error": err.Error()})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "处理成功"})
+}
+
+// AdminRejectAfterSale 驳回售后申请
+func AdminRejectAfterSale(c *gin.Context) {
+	adminId, ok := getUserId(c)
+	if !ok {
+		return
+	}
+	afterSaleId, ok := parseUintParam(c, "afterSaleId")
+	if !ok {
+		return
+	}
+	var req AdminRejectAfterSaleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "参数校验失败", "error": err.Error()})
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	err = aftersale.Reject(db, afterSaleId, orders.Admin(adminId), req.Remark, auditAfterSale(c, "after_sale.reject"))
+	if err != nil {
+		respondAfterSaleError(c, err, "驳回售后申请失败", "afterSaleId", afterSaleId)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "已驳回"})
+}
+
+// AdminRetryRefund 对已同意但退款失败的售后申请重新发起退款
+func AdminRetryRefund(c *gin.Context) {
+	adminId, ok := getUserId(c)
+	if !ok {
+		return
+	}
+	afterSaleId, ok := parseUintParam(c, "afterSaleId")
+	if !ok {
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	err = aftersale.Refund(c.Request.Context(), db, afterSaleId, orders.Admin(adminId), auditAfterSale(c, "after_sale.refund"))
+	switch {
+	case errors.Is(err, aftersale.ErrNotFound), errors.Is(err, aftersale.ErrIllegalState), errors.Is(err, aftersale.ErrPaymentUnsupported):
+		respondAfterSaleError(c, err, "售后退款失败", "afterSaleId", afterSaleId)
+	case err != nil:
+		slog.Error("售后退款失败", "afterSaleId", afterSaleId, "error", err)
+		c.JSON(http.StatusBadGateway, gin.H{"success": false, "message": "退款发起失败，请稍后重试", "error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"success": true, "message": "退款成功"})
+	}
+}
@@ -42,3 +42,18 @@ func GetPlantImageHandler(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, ImageResponse{URL: signedURL})
 }
+
+// getUserId 从上下文读取JWT中间件写入的用户ID，失败时直接返回401
+func getUserId(c *gin.Context) (uint64, bool) {
+	uidRaw, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "用户未登录或ID无效"})
+		return 0, false
+	}
+	userId, ok := uidRaw.(uint)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "用户信息解析失败"})
+		return 0, false
+	}
+	return uint64(userId), true
+}
@@ -0,0 +1,226 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sunzhaoc/plant_be/internal/cart"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
+	"github.com/sunzhaoc/plant_be/pkg/money"
+	"gorm.io/gorm"
+)
+
+// CartMergeItemReq 未登录时本地购物车中的一项
+type CartMergeItemReq struct {
+	Id       uint64 `json:"id" binding:"required"`
+	SkuId    uint64 `json:"skuId" binding:"required"`
+	Quantity int    `json:"quantity" binding:"required,min=1"`
+	Selected *bool  `json:"selected"`
+}
+
+// CartMergeReq 登录后合并本地购物车请求
+type CartMergeReq struct {
+	Items    []CartMergeItemReq `json:"items" binding:"dive"`
+	Strategy string             `json:"strategy"` // 为空时使用配置 cart.merge_strategy
+}
+
+// CartItemView 购物车项及商品当前信息
+type CartItemView struct {
+	Id         uint64      `json:"id"`
+	SkuId      uint64      `json:"skuId"`
+	Size       string      `json:"size"`
+	Quantity   int         `json:"quantity"`
+	Name       string      `json:"name"`
+	MainImgUrl string      `json:"mainImgUrl"`
+	Price      money.Money `json:"price"`      // 当前单价
+	PriceAtAdd money.Money `json:"priceAtAdd"` // 加入购物车时的单价
+	AddedAt    int64       `json:"addedAt"`    // 加入时间（Unix 秒）
+	Selected   bool        `json:"selected"`
+	Stock      uint64      `json:"stock"`
+	Available  bool        `json:"available"` // 商品已下架或规格已删除时为 false
+}
+
+// lookupSkuPrices 查询 SKU 当前价格，不存在或不属于对应植物的 SKU 不在结果中
+func lookupSkuPrices(db *gorm.DB, keys []cart.ItemKey) (map[cart.ItemKey]money.Money, error) {
+	prices := make(map[cart.ItemKey]money.Money, len(keys))
+	if len(keys) == 0 {
+		return prices, nil
+	}
+	pairs := make([][]interface{}, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, []interface{}{key.PlantId, key.SkuId})
+	}
+	var rows []struct {
+		PlantId uint64
+		Id      uint64
+		Price   money.Money
+	}
+	if err := db.Raw("SELECT plant_id, id, price FROM plant.plant_sku WHERE (plant_id, id) IN ?", pairs).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		prices[cart.ItemKey{PlantId: row.PlantId, SkuId: row.Id}] = row.Price
+	}
+	return prices, nil
+}
+
+// buildCartView 为购物车项补充植物名称、主图、当前价格与库存
+func buildCartView(db *gorm.DB, items []cart.Item) ([]CartItemView, error) {
+	views := make([]CartItemView, 0, len(items))
+	if len(items) == 0 {
+		return views, nil
+	}
+
+	pairs := make([][]interface{}, 0, len(items))
+	for _, item := range items {
+		pairs = append(pairs, []interface{}{item.PlantId, item.SkuId})
+	}
+	type skuRow struct {
+		PlantId    uint64
+		SkuId      uint64
+		Size       string
+		Price      money.Money
+		Stock      uint64
+		Name       string
+		MainImgUrl string
+		IsOnSale   bool
+	}
+	var rows []skuRow
+	err := db.Raw(`SELECT s.plant_id, s.id sku_id, s.size, s.price, s.stock, p.name, p.main_img_url, p.is_on_sale
+		FROM plant.plant_sku s
+		JOIN plant.plants p ON p.id = s.plant_id
+		WHERE (s.plant_id, s.id) IN ?`, pairs).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	skuMap := make(map[uint64]skuRow, len(rows))
+	for _, row := range rows {
+		skuMap[row.SkuId] = row
+	}
+
+	for _, item := range items {
+		view := CartItemView{
+			Id:         item.PlantId,
+			SkuId:      item.SkuId,
+			Quantity:   item.Quantity,
+			PriceAtAdd: item.PriceAtAdd,
+			AddedAt:    item.AddedAt.Unix(),
+			Selected:   item.Selected,
+		}
+		if row, ok := skuMap[item.SkuId]; ok {
+			view.Size = row.Size
+			view.Name = row.Name
+			view.MainImgUrl = row.MainImgUrl
+			view.Price = row.Price
+			view.Stock = row.Stock
+			view.Available = row.IsOnSale
+		}
+		views = append(views, view)
+	}
+	// 按加入时间倒序，最近加入的在前
+	sort.Slice(views, func(i, j int) bool {
+		if views[i].AddedAt != views[j].AddedAt {
+			return views[i].AddedAt > views[j].AddedAt
+		}
+		return views[i].SkuId < views[j].SkuId
+	})
+	return views, nil
+}
+
+// GetCart 查询当前用户的购物车
+func GetCart(c *gin.Context) {
+	userId, ok := getUserId(c)
+	if !ok {
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	items, err := cart.Get(c.Request.Context(), userId)
+	if err != nil {
+		slog.Error("读取购物车失败", "uid", userId, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	views, err := buildCartView(db, items)
+	if err != nil {
+		slog.Error("查询购物车商品信息失败", "uid", userId, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "查询购物车成功", "data": views})
+}
+
+// MergeCart 登录后将本地购物车合并到服务端，返回合并后的购物车
+func MergeCart(c *gin.Context) {
+	userId, ok := getUserId(c)
+	if !ok {
+		return
+	}
+	var req CartMergeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "参数校验失败", "error": err.Error()})
+		return
+	}
+	strategy := req.Strategy
+	if strategy == "" {
+		strategy = cart.CartCfg.MergeStrategy
+	}
+	if !cart.ValidStrategy(strategy) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "不支持的合并规则"})
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	keys := make([]cart.ItemKey, 0, len(req.Items))
+	for _, item := range req.Items {
+		keys = append(keys, cart.ItemKey{PlantId: item.Id, SkuId: item.SkuId})
+	}
+	prices, err := lookupSkuPrices(db, keys)
+	if err != nil {
+		slog.Error("查询SKU价格失败", "uid", userId, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	// 本地购物车中已删除的规格直接忽略
+	local := make([]cart.Upsert, 0, len(req.Items))
+	for _, item := range req.Items {
+		price, ok := prices[cart.ItemKey{PlantId: item.Id, SkuId: item.SkuId}]
+		if !ok {
+			continue
+		}
+		local = append(local, cart.Upsert{
+			PlantId:  item.Id,
+			SkuId:    item.SkuId,
+			Quantity: item.Quantity,
+			Price:    price,
+			Selected: item.Selected,
+		})
+	}
+	merged, err := cart.Merge(c.Request.Context(), userId, local, strategy)
+	if err != nil {
+		slog.Error("合并购物车失败", "uid", userId, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "合并购物车失败"})
+		return
+	}
+	views, err := buildCartView(db, merged)
+	if err != nil {
+		slog.Error("查询购物车商品信息失败", "uid", userId, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	slog.Info("购物车合并成功", "uid", userId, "localCount", len(local), "strategy", strategy)
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "合并购物车成功", "data": views})
+}
@@ -1,36 +1,36 @@
 package api
 
 import (
-	"fmt"
 	"log/slog"
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/sunzhaoc/plant_be/pkg/db/redis"
+	"github.com/sunzhaoc/plant_be/internal/cart"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
 )
 
 // 1. 购物车新增/修改项请求结构体（明确语义，专用于新增/更新场景）
 type CartAddOrUpdateItemReq struct {
-	Id       int    `json:"id" binding:"required"`
-	Size     string `json:"size"`
+	Id       uint64 `json:"id" binding:"required"`    // 商品ID
+	SkuId    uint64 `json:"skuId" binding:"required"` // SKU ID
 	Quantity int    `json:"quantity" binding:"required,min=1"`
+	Selected *bool  `json:"selected"` // 是否勾选结算，为空时新增默认勾选、修改保持不变
 }
 
 // 2. 购物车删除项请求结构体（独立封装，专用于删除场景）
 type CartDeleteItemReq struct {
-	Id   int    `json:"id" binding:"required"` // 商品ID
-	Size string `json:"size"`                  // 商品规格（删除需匹配ID+规格）
+	Id    uint64 `json:"id" binding:"required"`    // 商品ID
+	SkuId uint64 `json:"skuId" binding:"required"` // SKU ID（删除需匹配商品ID+SKU ID）
 }
 
 // 3. 购物车增量同步顶层请求结构体（聚合上述两个结构体，作为接口入参）
 type CartIncrementalSyncReq struct {
-	AddedOrUpdatedItems []CartAddOrUpdateItemReq `json:"addedOrUpdatedItems"` // 新增/修改的项
-	DeletedItems        []CartDeleteItemReq      `json:"deletedItems"`
+	AddedOrUpdatedItems []CartAddOrUpdateItemReq `json:"addedOrUpdatedItems" binding:"dive"` // 新增/修改的项
+	DeletedItems        []CartDeleteItemReq      `json:"deletedItems" binding:"dive"`
 }
 
 const (
-	CartExpireTime = 7 * 24 * time.Hour
+	CartExpireTime = cart.ExpireTime
 )
 
 func SyncCartToRedis(c *gin.Context) {
@@ -56,23 +56,6 @@ func SyncCartToRedis(c *gin.Context) {
 		return
 	}
 
-	fmt.Println("req:", req)
-
-	// 3. 获取 Redis 客户端并检查错误
-	rdb, err := redis.GetDb("ali")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "数据库连接失败",
-			"error":   err.Error(),
-		})
-		return
-	}
-
-	// 4. 执行 Redis 操作
-	ctx := c.Request.Context()
-	redisKey := fmt.Sprintf("cart:u:%v", uid)
-
 	// 若无任何增量数据，直接返回成功
 	if len(req.AddedOrUpdatedItems) == 0 && len(req.DeletedItems) == 0 {
 		c.JSON(http.StatusOK, gin.H{
@@ -82,30 +65,45 @@ func SyncCartToRedis(c *gin.Context) {
 		return
 	}
 
-	pipe := rdb.Pipeline()
-
-	// 处理新增/修改项
-	if len(req.AddedOrUpdatedItems) > 0 {
-		cartData := make(map[string]interface{}, len(req.AddedOrUpdatedItems))
-		for _, item := range req.AddedOrUpdatedItems {
-			field := fmt.Sprintf("%d:%s", item.Id, item.Size)
-			cartData[field] = item.Quantity
-		}
-		pipe.HSet(ctx, redisKey, cartData)
+	// 3. 校验SKU归属并获取当前价格（新增项记录加入价）
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
 	}
-
-	// 处理删除项（HDel 删除指定字段）
-	if len(req.DeletedItems) > 0 {
-		delFields := make([]string, len(req.DeletedItems))
-		for i, item := range req.DeletedItems {
-			delFields[i] = fmt.Sprintf("%d:%s", item.Id, item.Size)
+	keys := make([]cart.ItemKey, 0, len(req.AddedOrUpdatedItems))
+	for _, item := range req.AddedOrUpdatedItems {
+		keys = append(keys, cart.ItemKey{PlantId: item.Id, SkuId: item.SkuId})
+	}
+	prices, err := lookupSkuPrices(db, keys)
+	if err != nil {
+		slog.Error("查询SKU价格失败", "uid", uid, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	upserts := make([]cart.Upsert, 0, len(req.AddedOrUpdatedItems))
+	for _, item := range req.AddedOrUpdatedItems {
+		price, ok := prices[cart.ItemKey{PlantId: item.Id, SkuId: item.SkuId}]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "商品规格不存在"})
+			return
 		}
-		pipe.HDel(ctx, redisKey, delFields...)
+		upserts = append(upserts, cart.Upsert{
+			PlantId:  item.Id,
+			SkuId:    item.SkuId,
+			Quantity: item.Quantity,
+			Price:    price,
+			Selected: item.Selected,
+		})
+	}
+	deletes := make([]cart.ItemKey, 0, len(req.DeletedItems))
+	for _, item := range req.DeletedItems {
+		deletes = append(deletes, cart.ItemKey{PlantId: item.Id, SkuId: item.SkuId})
 	}
 
-	pipe.Expire(ctx, redisKey, CartExpireTime)
-
-	if _, err := pipe.Exec(ctx); err != nil {
+	// 4. 执行 Redis 操作
+	if _, err := cart.Apply(c.Request.Context(), uint64(uid.(uint)), upserts, deletes); err != nil {
 		slog.Error("Redis增量同步操作失败", "uid", uid, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
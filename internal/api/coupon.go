@@ -0,0 +1,279 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sunzhaoc/plant_be/internal/audit"
+	"github.com/sunzhaoc/plant_be/internal/coupon"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"github.com/sunzhaoc/plant_be/pkg/money"
+	"gorm.io/gorm"
+)
+
+// AdminCouponRequest 新增优惠券请求
+type AdminCouponRequest struct {
+	Name          string            `json:"name" binding:"required,max=64"`
+	Type          models.CouponType `json:"type" binding:"required,oneof=1 2 3 4"`
+	Amount        money.Money       `json:"amount"`                      // 立减/满减金额
+	PercentOff    uint              `json:"percentOff" binding:"max=99"` // 折扣券减免百分比
+	MaxDiscount   money.Money       `json:"maxDiscount"`                 // 折扣券最高优惠，0 表示不限
+	Threshold     money.Money       `json:"threshold"`                   // 使用门槛，0 表示无门槛
+	TotalQuantity uint              `json:"totalQuantity"`               // 发放总量，0 表示不限
+	PerUserLimit  uint              `json:"perUserLimit"`                // 为空时每人限领1张
+	StartTime     time.Time         `json:"startTime" binding:"required"`
+	EndTime       time.Time         `json:"endTime" binding:"required,gtfield=StartTime"`
+}
+
+// AdminCouponActiveRequest 启停优惠券发放请求
+type AdminCouponActiveRequest struct {
+	IsActive bool `json:"isActive"`
+}
+
+// validateCoupon 按券类型校验金额参数，返回错误提示
+func validateCoupon(req AdminCouponRequest) string {
+	if req.Amount < 0 || req.MaxDiscount < 0 || req.Threshold < 0 {
+		return "金额不能为负数"
+	}
+	if req.Amount > MaxSkuPrice || req.MaxDiscount > MaxSkuPrice || req.Threshold > MaxSkuPrice {
+		return "金额超出上限"
+	}
+	switch req.Type {
+	case models.CouponFixed:
+		if req.Amount <= 0 {
+			return "立减券须设置立减金额"
+		}
+	case models.CouponThreshold:
+		if req.Amount <= 0 || req.Threshold <= req.Amount {
+			return "满减券须设置减免金额，且门槛须大于减免金额"
+		}
+	case models.CouponPercent:
+		if req.PercentOff == 0 {
+			return "折扣券须设置减免百分比"
+		}
+	}
+	return ""
+}
+
+func couponView(c models.Coupon) gin.H {
+	return gin.H{
+		"coupon_id":      c.Id,
+		"name":           c.Name,
+		"type":           c.Type,
+		"amount":         c.Amount,
+		"percent_off":    c.PercentOff,
+		"max_discount":   c.MaxDiscount,
+		"threshold":      c.Threshold,
+		"total_quantity": c.TotalQuantity,
+		"claimed_count":  c.ClaimedCount,
+		"per_user_limit": c.PerUserLimit,
+		"start_time":     c.StartTime.Format("2006-01-02 15:04:05"),
+		"end_time":       c.EndTime.Format("2006-01-02 15:04:05"),
+		"is_active":      c.IsActive,
+	}
+}
+
+func ownedCouponView(o coupon.Owned) gin.H {
+	view := couponView(o.Coupon)
+	view["user_coupon_id"] = o.Id
+	view["status"] = o.Status
+	view["usable"] = o.Usable(time.Now())
+	if o.OrderSn != nil {
+		view["order_sn"] = *o.OrderSn
+	}
+	return view
+}
+
+// AdminCreateCoupon 新增优惠券
+func AdminCreateCoupon(c *gin.Context) {
+	var req AdminCouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "参数校验失败", "error": err.Error()})
+		return
+	}
+	if msg := validateCoupon(req); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": msg})
+		return
+	}
+	if req.PerUserLimit == 0 {
+		req.PerUserLimit = 1
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	cp := models.Coupon{
+		Name:          req.Name,
+		Type:          req.Type,
+		Amount:        req.Amount,
+		PercentOff:    req.PercentOff,
+		MaxDiscount:   req.MaxDiscount,
+		Threshold:     req.Threshold,
+		TotalQuantity: req.TotalQuantity,
+		PerUserLimit:  req.PerUserLimit,
+		StartTime:     req.StartTime,
+		EndTime:       req.EndTime,
+		IsActive:      true,
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&cp).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, c, "coupon.create", "coupon", cp.Id, nil, cp)
+	})
+	if err != nil {
+		respondAdminError(c, err, "优惠券不存在", "新增优惠券失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "新增优惠券成功", "data": gin.H{"coupon_id": cp.Id}})
+}
+
+// AdminGetCoupons 后台优惠券列表（最近创建的100个）
+func AdminGetCoupons(c *gin.Context) {
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	var coupons []models.Coupon
+	if err := db.Order("id DESC").Limit(100).Find(&coupons).Error; err != nil {
+		slog.Error("查询优惠券失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	list := make([]gin.H, 0, len(coupons))
+	for _, cp := range coupons {
+		list = append(list, couponView(cp))
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "", "data": list})
+}
+
+// AdminSetCouponActive 启停优惠券发放，已领取的券不受影响
+func AdminSetCouponActive(c *gin.Context) {
+	couponId, ok := parseUintParam(c, "couponId")
+	if !ok {
+		return
+	}
+	var req AdminCouponActiveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "参数校验失败", "error": err.Error()})
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var cp models.Coupon
+		if err := tx.Where("id = ?", couponId).Take(&cp).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&cp).Update("is_active", req.IsActive).Error; err != nil {
+			return err
+		}
+		action := "coupon.deactivate"
+		if req.IsActive {
+			action = "coupon.activate"
+		}
+		return audit.Record(tx, c, action, "coupon", couponId, gin.H{"is_active": !req.IsActive}, gin.H{"is_active": req.IsActive})
+	})
+	if err != nil {
+		respondAdminError(c, err, "优惠券不存在", "修改优惠券状态失败", "couponId", couponId)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "修改成功"})
+}
+
+// GetCoupons 可领取的优惠券列表
+func GetCoupons(c *gin.Context) {
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	var coupons []models.Coupon
+	err = db.Where("is_active = ? AND end_time > ?", true, time.Now()).
+		Where("total_quantity = 0 OR claimed_count < total_quantity").
+		Order("id DESC").Limit(100).Find(&coupons).Error
+	if err != nil {
+		slog.Error("查询优惠券失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	list := make([]gin.H, 0, len(coupons))
+	for _, cp := range coupons {
+		list = append(list, couponView(cp))
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "", "data": list})
+}
+
+// ClaimCoupon 领取优惠券
+func ClaimCoupon(c *gin.Context) {
+	userId, ok := getUserId(c)
+	if !ok {
+		return
+	}
+	couponId, ok := parseUintParam(c, "couponId")
+	if !ok {
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	owned, err := coupon.Claim(db, userId, couponId)
+	switch {
+	case errors.Is(err, coupon.ErrCouponNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": err.Error()})
+	case errors.Is(err, coupon.ErrNotClaimable), errors.Is(err, coupon.ErrSoldOut), errors.Is(err, coupon.ErrClaimLimit):
+		c.JSON(http.StatusConflict, gin.H{"success": false, "message": err.Error()})
+	case err != nil:
+		slog.Error("领取优惠券失败", "couponId", couponId, "uid", userId, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"success": true, "message": "领取成功", "data": gin.H{"user_coupon_id": owned.Id}})
+	}
+}
+
+// GetMyCoupons 我的优惠券，status=usable 仅返回当前可用的券
+func GetMyCoupons(c *gin.Context) {
+	userId, ok := getUserId(c)
+	if !ok {
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	owned, err := coupon.ListOwned(db, userId)
+	if err != nil {
+		slog.Error("查询用户优惠券失败", "uid", userId, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	onlyUsable := c.Query("status") == "usable"
+	now := time.Now()
+	list := make([]gin.H, 0, len(owned))
+	for _, o := range owned {
+		if onlyUsable && !o.Usable(now) {
+			continue
+		}
+		list = append(list, ownedCouponView(o))
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "", "data": list})
+}
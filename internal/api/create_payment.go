@@ -1,30 +1,38 @@
 package api
 
 import (
-	"fmt"
+	"errors"
 	"log/slog"
-	"math/rand"
 	"net/http"
-	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sunzhaoc/plant_be/internal/coupon"
+	"github.com/sunzhaoc/plant_be/internal/inventory"
+	"github.com/sunzhaoc/plant_be/internal/middleware"
+	"github.com/sunzhaoc/plant_be/internal/orders"
+	"github.com/sunzhaoc/plant_be/internal/shipping"
 	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
-	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"github.com/sunzhaoc/plant_be/pkg/ordersn"
+	"github.com/sunzhaoc/plant_be/pkg/payment"
 )
 
 type Address struct {
 	Receiver      string `json:"receiver"`      // 收货人姓名
 	Phone         string `json:"phone"`         // 联系电话
 	Province      string `json:"province"`      // 省
+	ProvinceCode  string `json:"provinceCode"`  // 省级行政区划代码，优先于 province
 	City          string `json:"city"`          // 市
 	Area          string `json:"area"`          // 区/县
 	DetailAddress string `json:"detailAddress"` // 详细地址
 }
 
 type PaymentRequest struct {
-	CartItems []CartItem `json:"cartItems"`
-	Address   Address    `json:"address"` // 收货地址
+	CartItems    []CartItem `json:"cartItems"`
+	AddressId    uint64     `json:"addressId"`    // 地址簿中的收货地址ID
+	Address      Address    `json:"address"`      // 收货地址（兼容旧版前端，未传 addressId 时使用）
+	PayChannel   string     `json:"payChannel"`   // 支付渠道（为空使用默认渠道）
+	UserCouponId uint64     `json:"userCouponId"` // 使用的用户优惠券ID（可选）
+	QuoteToken   string     `json:"quoteToken"`   // 结算预览返回的报价令牌（可选），传入时实付金额须与预览一致
 }
 
 // CartItem 对应前端 cartItems 数组中的单个元素
@@ -34,14 +42,6 @@ type CartItem struct {
 	Quantity uint   `json:"quantity"`
 }
 
-func generateOrderSn(uid uint64) string {
-	now := time.Now()
-	timestamp := now.Format("20060102150405") // 年月日时分秒
-	randNum := rand.Intn(900000) + 100000     // 6位随机数（100000-999999）
-	uidSuffix := uid % 1000000                // 用户ID后6位（防止ID过长）
-	return fmt.Sprintf("%s%06d%06d", timestamp, randNum, uidSuffix)
-}
-
 func CreatePayment(c *gin.Context) {
 	uidRaw, exists := c.Get("userId")
 	if !exists {
@@ -66,6 +66,12 @@ func CreatePayment(c *gin.Context) {
 		return
 	}
 
+	provider, err := payment.Get(req.PayChannel)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "不支持的支付渠道"})
+		return
+	}
+
 	// 3. 获取mysql连接池
 	db, err := mysql.GetDB("ali")
 	if err != nil {
@@ -74,197 +80,96 @@ func CreatePayment(c *gin.Context) {
 		return
 	}
 
-	// 4. 开启数据库事务，保证库存操作原子性
-	tx := db.Begin()
-	if tx.Error != nil {
-		slog.Error("开启事务失败", "error", tx.Error)
-		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误", "error": tx.Error.Error()})
+	// 解析收货地址（地址簿或请求内联地址），下单时按结构化字段快照
+	address, msg, err := resolveCheckoutAddress(db, userId64, req)
+	if err != nil {
+		slog.Error("查询收货地址失败", "uid", userId64, "addressId", req.AddressId, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
 		return
 	}
-
-	// 5. 定义事务回滚的延迟处理（确保任何失败都能回滚）
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-			slog.Error("付款流程panic", "error", r)
-		}
-	}()
-
-	// 6. 获取sku的信息
-	skuParams := make([]interface{}, 0, len(req.CartItems))
-	for _, item := range req.CartItems {
-		skuParams = append(skuParams, item.SkuId)
-	}
-	skuPlaceholders := strings.TrimSuffix(strings.Repeat("?,", len(skuParams)), ",")
-	skuSql := fmt.Sprintf(`SELECT plant_id, id sku_id, size sku_size, stock, price FROM plant.plant_sku WHERE id IN (%s) FOR UPDATE`, skuPlaceholders)
-	type SkuInfo struct {
-		PlantId uint64  `gorm:"column:plant_id"`
-		SkuId   uint64  `gorm:"column:sku_id"`
-		SkuSize string  `gorm:"column:sku_size"`
-		Stock   uint    `gorm:"column:stock"`
-		Price   float64 `gorm:"column:price"`
-	}
-	var skuList []SkuInfo
-	if err := tx.Raw(skuSql, skuParams...).Scan(&skuList).Error; err != nil {
-		tx.Rollback()
-		slog.Error("批量查询SKU失败", "skuIDs", skuParams, "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "系统内部错误",
-			"error":   err.Error(),
-		})
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": msg})
 		return
 	}
-	skuMap := make(map[uint64]SkuInfo, len(skuList))
-	for _, sku := range skuList {
-		skuMap[sku.SkuId] = sku
-	}
 
-	// 7. 库存减扣˚
-	updateParams := make([]interface{}, 0, len(req.CartItems)*2) // 批量更新的参数
-	skuIdsForWhere := make([]interface{}, 0, len(req.CartItems)) // WHERE IN的SKU ID参数
-	var caseWhenBuilder strings.Builder                          // 构建CASE WHEN语句
-	for _, item := range req.CartItems {
-		// 检查购物车传进来的 sku id 是否存在
-		sku, ok := skuMap[item.SkuId]
-		if !ok || item.Quantity <= 0 || sku.Stock < item.Quantity {
-			tx.Rollback()
-			msg := "库存不足或参数错误"
-			if !ok {
-				msg = fmt.Sprintf("规格ID %d 不存在", item.SkuId)
-			} else if item.Quantity <= 0 {
-				msg = "购买数量必须大于0"
-			}
-			slog.Error("校验失败", "skuId", item.SkuId, "reason", msg)
-			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": msg})
-			return
-		}
-		caseWhenBuilder.WriteString("WHEN id = ? THEN stock - ? ")
-		updateParams = append(updateParams, item.SkuId, item.Quantity)
-		skuIdsForWhere = append(skuIdsForWhere, item.SkuId)
-	}
-	wherePlaceholders := strings.TrimSuffix(strings.Repeat("?,", len(skuIdsForWhere)), ",")
-	batchUpdateSql := fmt.Sprintf(`
-		UPDATE plant.plant_sku 
-		SET stock = CASE %s ELSE stock END 
-		WHERE id IN (%s)
-	`, caseWhenBuilder.String(), wherePlaceholders)
-	finalParams := append(updateParams, skuIdsForWhere...)
-	if err := tx.Exec(batchUpdateSql, finalParams...).Error; err != nil {
-		tx.Rollback()
-		slog.Error("批量扣减库存失败", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "库存扣减失败",
-			"error":   err.Error(),
-		})
+	// 4. 预占库存并写入订单（订单号提前生成，预占以订单号幂等）
+	orderSn, err := ordersn.New(c.Request.Context(), userId64)
+	if err != nil {
+		slog.Error("生成订单号失败", "uid", userId64, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
 		return
 	}
-
-	// ---------------------- 8. 订单主表写入逻辑开始 ----------------------
-	var totalAmount float64
+	lines := make([]orders.PlaceLine, 0, len(req.CartItems))
 	for _, item := range req.CartItems {
-		totalAmount += skuMap[item.SkuId].Price * float64(item.Quantity)
-	}
-	orderSn := generateOrderSn(userId64)
-
-	order := &models.Orders{
-		OrderSn:         orderSn,
-		UserId:          userId64,
-		TotalAmount:     totalAmount,
-		PayAmount:       totalAmount,
-		OrderStatus:     0,
-		ReceiverName:    req.Address.Receiver,
-		ReceiverPhone:   req.Address.Phone,
-		ReceiverAddress: req.Address.Province + req.Address.City + req.Address.Area + req.Address.DetailAddress,
-	}
-	if err := tx.Create(order).Error; err != nil {
-		tx.Rollback()
-		slog.Error("插入订单主表失败", "orderSN", orderSn, "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "创建订单失败",
-			"error":   err.Error(),
-		})
+		lines = append(lines, orders.PlaceLine{SkuId: item.SkuId, Quantity: item.Quantity})
+	}
+	order, err := orders.Place(c.Request.Context(), db, orders.PlaceRequest{
+		OrderSn:      orderSn,
+		UserId:       userId64,
+		PayChannel:   provider.Name(),
+		Address:      address,
+		Lines:        lines,
+		UserCouponId: req.UserCouponId,
+		QuoteToken:   req.QuoteToken,
+	})
+	if err != nil {
+		respondPlaceError(c, err)
 		return
 	}
+	// 订单已落库，之后的响应（含发起支付失败）都需保存，重试时不能再次下单
+	middleware.MarkIdempotencyCommitted(c)
+	expireAt := orders.ExpireAt(order)
 
-	// ---------------------- 9：订单详情快照开始 ----------------------
-	plantIds := make([]interface{}, 0)
-	plantIdMap := make(map[uint64]struct{})
-	for _, sku := range skuList {
-		if _, exists := plantIdMap[sku.PlantId]; !exists {
-			plantIdMap[sku.PlantId] = struct{}{}
-			plantIds = append(plantIds, sku.PlantId)
-		}
-	}
-	plantPlaceholders := strings.TrimSuffix(strings.Repeat("?,", len(plantIds)), ",")
-	plantSql := fmt.Sprintf("SELECT id plant_id, name, latin_name, main_img_url FROM plant.plants WHERE id IN (%s)", plantPlaceholders)
-	type PlantInfo struct {
-		PlantId    uint64 `gorm:"column:plant_id"`
-		Name       string `gorm:"column:name"`
-		LatinName  string `gorm:"column:latin_name"`
-		MainImgUrl string `gorm:"column:main_img_url"`
-	}
-	var plantList []PlantInfo
-	if err := tx.Raw(plantSql, plantIds...).Scan(&plantList).Error; err != nil {
-		tx.Rollback()
-		slog.Error("批量查询植物信息失败", "plantIds", plantIds, "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
+	// 5. 事务提交后向支付渠道下单，失败时订单保持待支付，可通过 /api/order/:orderSn/pay 重新发起支付
+	prepay, err := provider.CreatePrepay(c.Request.Context(), orders.NewPrepayRequest(order, c.ClientIP()))
+	if err != nil {
+		slog.Error("创建预支付单失败", "orderSn", orderSn, "provider", provider.Name(), "error", err)
+		c.JSON(http.StatusBadGateway, gin.H{
 			"success": false,
-			"message": "获取植物信息失败",
-			"error":   err.Error(),
+			"message": "下单成功，发起支付失败，请在订单详情中重新支付",
+			"data":    gin.H{"orderSn": orderSn},
 		})
 		return
 	}
-	plantMap := make(map[uint64]PlantInfo, len(plantList))
-	for _, plant := range plantList {
-		plantMap[plant.PlantId] = plant
-	}
-
-	var orderItems []models.OrderItem
-	for _, item := range req.CartItems {
-		sku := skuMap[item.SkuId]
-		plant := plantMap[sku.PlantId]
 
-		orderItem := models.OrderItem{
-			OrderId:        order.Id,         // 关联订单ID
-			PlantId:        sku.PlantId,      // 植物ID
-			SkuId:          sku.SkuId,        // 规格ID
-			PlantName:      plant.Name,       // 植物名称（快照）
-			PlantLatinName: plant.LatinName,  // 拉丁学名（快照）
-			SkuSize:        sku.SkuSize,      // 规格名称（快照）
-			MainImgUrl:     plant.MainImgUrl, // 主图（快照）
-			Price:          sku.Price,        // 下单时单价（快照）
-			Quantity:       item.Quantity,    // 购买数量
-		}
-		orderItems = append(orderItems, orderItem)
-	}
-
-	// 7. 批量插入订单商品详情表
-	if err := tx.CreateInBatches(&orderItems, 100).Error; err != nil { // 批量插入（每次最多100条）
-		tx.Rollback()
-		slog.Error("批量插入订单项失败", "orderID", order.OrderSn, "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "创建订单项失败",
-			"error":   err.Error(),
-		})
-		return
-	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "下单成功",
+		"data": gin.H{
+			"orderSn":    orderSn,
+			"payAmount":  order.PayAmount,
+			"expireTime": expireAt.Format("2006-01-02 15:04:05"),
+			"payment":    prepay,
+		},
+	})
+}
 
-	// 11. 所有操作成功，提交事务
-	if err := tx.Commit().Error; err != nil {
-		slog.Error("提交事务失败", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
+// respondPlaceError 下单失败的统一响应
+func respondPlaceError(c *gin.Context, err error) {
+	var invalid *orders.ErrInvalidItem
+	var insufficient *inventory.ErrInsufficientStock
+	var changed *orders.ErrPriceChanged
+	var undeliverable *shipping.ErrUndeliverable
+	switch {
+	case errors.Is(err, orders.ErrEmptyOrder), errors.Is(err, orders.ErrInvalidProvince), errors.Is(err, coupon.ErrUnavailable), errors.Is(err, coupon.ErrThresholdNotMet):
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+	case errors.As(err, &invalid):
+		slog.Error("校验失败", "skuId", invalid.SkuId, "reason", invalid.Reason)
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": invalid.Reason})
+	case errors.As(err, &insufficient):
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "库存不足", "data": gin.H{"skuId": insufficient.SkuId}})
+	case errors.As(err, &undeliverable):
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": undeliverable.Reason, "data": gin.H{"plantId": undeliverable.PlantId}})
+	case errors.Is(err, orders.ErrQuoteInvalid), errors.Is(err, orders.ErrQuoteExpired):
+		c.JSON(http.StatusConflict, gin.H{"success": false, "message": err.Error()})
+	case errors.As(err, &changed):
+		c.JSON(http.StatusConflict, gin.H{
 			"success": false,
-			"message": "系统内部错误",
-			"error":   err.Error(),
+			"message": "订单金额已变化，请重新确认订单",
+			"data":    gin.H{"quotedAmount": changed.Quoted, "payAmount": changed.Current},
 		})
-		return
+	default:
+		slog.Error("创建订单失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "创建订单失败"})
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-	})
 }
@@ -0,0 +1,239 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sunzhaoc/plant_be/internal/audit"
+	"github.com/sunzhaoc/plant_be/internal/flashsale"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"github.com/sunzhaoc/plant_be/pkg/money"
+	"github.com/sunzhaoc/plant_be/pkg/ordersn"
+	"github.com/sunzhaoc/plant_be/pkg/payment"
+	"gorm.io/gorm"
+)
+
+// AdminFlashSaleRequest 新增秒杀活动请求
+type AdminFlashSaleRequest struct {
+	SkuId        uint64      `json:"skuId" binding:"required"`
+	SalePrice    money.Money `json:"salePrice" binding:"required,gt=0"`
+	Quantity     uint        `json:"quantity" binding:"required,min=1"`
+	PerUserLimit uint        `json:"perUserLimit"` // 为空时每人限购1件
+	StartTime    time.Time   `json:"startTime" binding:"required"`
+	EndTime      time.Time   `json:"endTime" binding:"required,gtfield=StartTime"`
+}
+
+// FlashSalePurchaseRequest 秒杀抢购请求，仅支持地址簿中的收货地址
+type FlashSalePurchaseRequest struct {
+	Quantity   uint   `json:"quantity" binding:"required,min=1"`
+	AddressId  uint64 `json:"addressId" binding:"required"`
+	PayChannel string `json:"payChannel"` // 支付渠道（为空使用默认渠道）
+}
+
+// flashSaleView 活动信息，remain 为 Redis 中的剩余名额（未初始化时为活动限量）
+func flashSaleView(ctx context.Context, sale models.FlashSale) gin.H {
+	remain := int64(sale.Quantity)
+	if r, ok, err := flashsale.Remaining(ctx, sale.Id); err != nil {
+		slog.Error("查询秒杀剩余名额失败", "saleId", sale.Id, "error", err)
+	} else if ok {
+		remain = r
+	}
+	return gin.H{
+		"sale_id":        sale.Id,
+		"sku_id":         sale.SkuId,
+		"sale_price":     sale.SalePrice,
+		"quantity":       sale.Quantity,
+		"remain":         remain,
+		"per_user_limit": sale.PerUserLimit,
+		"start_time":     sale.StartTime.Format("2006-01-02 15:04:05"),
+		"end_time":       sale.EndTime.Format("2006-01-02 15:04:05"),
+	}
+}
+
+// AdminCreateFlashSale 新增秒杀活动，创建后即预热 Redis 准入状态
+//
+// 活动限量从 SKU 库存中抢占，下单时仍按 SKU 可用库存预占，库存不足的名额会下单失败
+func AdminCreateFlashSale(c *gin.Context) {
+	var req AdminFlashSaleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "参数校验失败", "error": err.Error()})
+		return
+	}
+	if req.SalePrice > MaxSkuPrice {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "秒杀价超出上限"})
+		return
+	}
+	if req.EndTime.Before(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "结束时间不能早于当前时间"})
+		return
+	}
+	if req.PerUserLimit == 0 {
+		req.PerUserLimit = 1
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	sale := models.FlashSale{
+		SkuId:        req.SkuId,
+		SalePrice:    req.SalePrice,
+		Quantity:     req.Quantity,
+		PerUserLimit: req.PerUserLimit,
+		StartTime:    req.StartTime,
+		EndTime:      req.EndTime,
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var sku models.PlantSku
+		if err := tx.Select("id").Where("id = ?", req.SkuId).Take(&sku).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&sale).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, c, "flash_sale.create", "flash_sale", sale.Id, nil, sale)
+	})
+	if err != nil {
+		respondAdminError(c, err, "规格不存在", "新增秒杀活动失败", "skuId", req.SkuId)
+		return
+	}
+	// 预热失败时由首个抢购请求回源加载
+	if err := flashsale.Preload(c.Request.Context(), sale.Id); err != nil {
+		slog.Error("预热秒杀活动状态失败", "saleId", sale.Id, "error", err)
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "新增秒杀活动成功", "data": gin.H{"sale_id": sale.Id}})
+}
+
+// AdminGetFlashSales 后台秒杀活动列表（最近创建的100个）
+func AdminGetFlashSales(c *gin.Context) {
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	var sales []models.FlashSale
+	if err := db.Order("id DESC").Limit(100).Find(&sales).Error; err != nil {
+		slog.Error("查询秒杀活动失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	list := make([]gin.H, 0, len(sales))
+	for _, sale := range sales {
+		list = append(list, flashSaleView(c.Request.Context(), sale))
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "", "data": list})
+}
+
+// GetFlashSale 获取秒杀活动信息与剩余名额
+func GetFlashSale(c *gin.Context) {
+	saleId, ok := parseUintParam(c, "saleId")
+	if !ok {
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	var sale models.FlashSale
+	if err := db.Where("id = ?", saleId).Take(&sale).Error; err != nil {
+		respondAdminError(c, err, "活动不存在", "查询秒杀活动失败", "saleId", saleId)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "", "data": flashSaleView(c.Request.Context(), sale)})
+}
+
+// PurchaseFlashSale 秒杀抢购：按 Redis 计数准入后返回排队凭证，订单异步创建
+//
+// 准入路径不访问 MySQL（活动状态未预热时除外），前端凭 ticketId 轮询 GetFlashSaleTicket 获取结果
+func PurchaseFlashSale(c *gin.Context) {
+	userId, ok := getUserId(c)
+	if !ok {
+		return
+	}
+	saleId, ok := parseUintParam(c, "saleId")
+	if !ok {
+		return
+	}
+	var req FlashSalePurchaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "参数校验失败", "error": err.Error()})
+		return
+	}
+	provider, err := payment.Get(req.PayChannel)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "不支持的支付渠道"})
+		return
+	}
+
+	orderSn, err := ordersn.New(c.Request.Context(), userId)
+	if err != nil {
+		slog.Error("生成订单号失败", "uid", userId, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	ticketId, err := flashsale.Admit(c.Request.Context(), flashsale.Ticket{
+		SaleId:     saleId,
+		UserId:     userId,
+		Quantity:   req.Quantity,
+		AddressId:  req.AddressId,
+		PayChannel: provider.Name(),
+		ClientIP:   c.ClientIP(),
+		OrderSn:    orderSn,
+	})
+	switch {
+	case errors.Is(err, flashsale.ErrSaleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": err.Error()})
+	case errors.Is(err, flashsale.ErrNotStarted), errors.Is(err, flashsale.ErrEnded),
+		errors.Is(err, flashsale.ErrSoldOut), errors.Is(err, flashsale.ErrLimitExceeded):
+		c.JSON(http.StatusConflict, gin.H{"success": false, "message": err.Error()})
+	case err != nil:
+		slog.Error("秒杀准入失败", "saleId", saleId, "uid", userId, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+	default:
+		c.JSON(http.StatusAccepted, gin.H{"success": true, "message": "排队中", "data": gin.H{"ticketId": ticketId}})
+	}
+}
+
+// GetFlashSaleTicket 查询排队凭证的下单结果
+func GetFlashSaleTicket(c *gin.Context) {
+	userId, ok := getUserId(c)
+	if !ok {
+		return
+	}
+	t, err := flashsale.GetTicket(c.Request.Context(), c.Param("ticketId"))
+	if err != nil {
+		slog.Error("查询秒杀凭证失败", "ticketId", c.Param("ticketId"), "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	if t == nil || t.UserId != userId {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "凭证不存在或已过期"})
+		return
+	}
+
+	data := gin.H{"ticketId": t.Id, "status": t.Status, "message": t.Message}
+	switch t.Status {
+	case flashsale.TicketProcessing:
+		// 对前端而言下单中与排队中无区别
+		data["status"] = flashsale.TicketQueued
+	case flashsale.TicketSuccess:
+		data["orderSn"] = t.OrderSn
+		data["payAmount"] = t.PayAmount
+		data["expireTime"] = t.ExpireTime
+		if t.Prepay != "" {
+			data["payment"] = json.RawMessage(t.Prepay)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "", "data": data})
+}
@@ -4,11 +4,28 @@ import (
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"github.com/sunzhaoc/plant_be/pkg/money"
 )
 
+// GetOrders 查询用户的历史订单
+//
+// 查询参数（均可选）：
+//
+//	status      订单状态
+//	startDate   下单日期起（YYYY-MM-DD，含当天）
+//	endDate     下单日期止（YYYY-MM-DD，含当天）
+//	keyword     匹配订单号或商品名称
+//	cursor      上一页返回的 next_cursor，传入时按游标翻页，忽略 page
+//	page        页码，默认1
+//	pageSize    每页条数，默认10，最大50
+//
+// 返回当前页订单、筛选后的总数、下一页游标，以及不受筛选影响的各状态订单数（用于标签页角标）
 func GetOrders(c *gin.Context) {
 	slog.Info("获取历史订单数据")
 
@@ -37,8 +54,58 @@ func GetOrders(c *gin.Context) {
 		pageSize = 10
 	}
 
-	// 计算偏移量：OFFSET = (页码-1) * 每页条数
+	// 计算偏移量：OFFSET = (页码-1) * 每页条数；按游标翻页时不使用偏移量，避免深分页扫描
 	offset := (page - 1) * pageSize
+	var cursor uint64
+	if v := c.Query("cursor"); v != "" {
+		cursor, err = strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "分页游标参数错误"})
+			return
+		}
+		offset = 0
+	}
+
+	// 2.1 构建筛选条件（o.id 随下单时间递增，按 id 排序与按下单时间排序一致）
+	conditions := []string{"o.user_id = ?"}
+	args := []interface{}{uid}
+	if v := c.Query("status"); v != "" {
+		status, err := strconv.Atoi(v)
+		if err != nil || !models.OrderStatus(status).Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "订单状态参数错误"})
+			return
+		}
+		conditions = append(conditions, "o.order_status = ?")
+		args = append(args, status)
+	}
+	if v := c.Query("startDate"); v != "" {
+		start, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "日期参数错误"})
+			return
+		}
+		conditions = append(conditions, "o.create_time >= ?")
+		args = append(args, start)
+	}
+	if v := c.Query("endDate"); v != "" {
+		end, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "日期参数错误"})
+			return
+		}
+		conditions = append(conditions, "o.create_time < ?")
+		args = append(args, end.AddDate(0, 0, 1))
+	}
+	if keyword := strings.TrimSpace(c.Query("keyword")); keyword != "" {
+		if len([]rune(keyword)) > 50 {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "关键词过长"})
+			return
+		}
+		pattern := "%" + likeEscaper.Replace(keyword) + "%"
+		conditions = append(conditions, "(o.order_sn = ? OR EXISTS (SELECT 1 FROM plant.order_items oi WHERE oi.order_id = o.id AND oi.plant_name LIKE ?))")
+		args = append(args, keyword, pattern)
+	}
+	where := strings.Join(conditions, " AND ")
 
 	// 3. 获取mysql连接池
 	db, err := mysql.GetDB("ali")
@@ -50,22 +117,23 @@ func GetOrders(c *gin.Context) {
 
 	// 4. 定义结构体（保持原有结构不变）
 	type OrderBase struct {
-		OrderId     uint64  `json:"order_id"`
-		OrderSn     string  `json:"order_sn"`
-		TotalAmount float64 `json:"total_amount"`
-		PayAmount   float64 `json:"pay_amount"`
-		OrderStatus uint    `json:"order_status"`
-		CreateTime  string  `json:"create_time"`
+		OrderId          uint64             `json:"order_id"`
+		OrderSn          string             `json:"order_sn"`
+		TotalAmount      money.Money        `json:"total_amount"`
+		PayAmount        money.Money        `json:"pay_amount"`
+		OrderStatus      models.OrderStatus `json:"order_status"`
+		OrderStatusLabel string             `json:"order_status_label" gorm:"-"`
+		CreateTime       string             `json:"create_time"`
 	}
 
 	type OrderItem struct {
-		OrderId        uint64  `json:"-"` // 新增：用于分组，不返回给前端
-		PlantName      string  `json:"plant_name"`
-		PlantLatinName string  `json:"plant_latin_name"`
-		SkuSize        string  `json:"sku_size"`
-		MainImgUrl     string  `json:"main_img_url"`
-		Price          float64 `json:"price"`
-		Quantity       int     `json:"quantity"`
+		OrderId        uint64      `json:"-"` // 新增：用于分组，不返回给前端
+		PlantName      string      `json:"plant_name"`
+		PlantLatinName string      `json:"plant_latin_name"`
+		SkuSize        string      `json:"sku_size"`
+		MainImgUrl     string      `json:"main_img_url"`
+		Price          money.Money `json:"price"`
+		Quantity       int         `json:"quantity"`
 	}
 
 	type Order struct {
@@ -73,10 +141,10 @@ func GetOrders(c *gin.Context) {
 		OrderItems []OrderItem `json:"order_items"`
 	}
 
-	// 5. 先查询总订单数（用于分页计算）
+	// 5. 先查询筛选后的订单总数（用于分页计算）
 	var total int64
-	countQuery := `SELECT COUNT(*) FROM plant.orders WHERE user_id = ?;`
-	countResult := db.Raw(countQuery, uid).Scan(&total)
+	countQuery := "SELECT COUNT(*) FROM plant.orders o WHERE " + where
+	countResult := db.Raw(countQuery, args...).Scan(&total)
 	if countResult.Error != nil {
 		slog.Error("查询订单总数失败", slog.Any("error", countResult.Error))
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -86,22 +154,47 @@ func GetOrders(c *gin.Context) {
 		return
 	}
 
+	// 5.1 各状态订单数，不受筛选条件影响
+	type StatusCount struct {
+		OrderStatus models.OrderStatus
+		Count       int64
+	}
+	var statusCountList []StatusCount
+	statusCountQuery := `SELECT order_status, COUNT(*) count FROM plant.orders WHERE user_id = ? GROUP BY order_status;`
+	if err := db.Raw(statusCountQuery, uid).Scan(&statusCountList).Error; err != nil {
+		slog.Error("查询各状态订单数失败", slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取订单总数失败",
+		})
+		return
+	}
+	statusCounts := make(map[string]int64, len(statusCountList))
+	for _, sc := range statusCountList {
+		statusCounts[strconv.Itoa(int(sc.OrderStatus))] = sc.Count
+	}
+
 	// 6. 查询当前页的订单基础数据
+	pageWhere, pageArgs := where, args
+	if cursor > 0 {
+		pageWhere += " AND o.id < ?"
+		pageArgs = append(append([]interface{}{}, args...), cursor)
+	}
 	var orderBaseList []OrderBase
 	orderQuery := `
 	SELECT
-	    id order_id,
-		order_sn,
-		total_amount,
-		pay_amount,
-		order_status,
-		DATE_FORMAT(create_time, '%Y-%m-%d %H:%i:%s') create_time
-	FROM plant.orders
-	WHERE user_id = ?
-	ORDER BY create_time DESC
+	    o.id order_id,
+		o.order_sn,
+		o.total_amount,
+		o.pay_amount,
+		o.order_status,
+		DATE_FORMAT(o.create_time, '%Y-%m-%d %H:%i:%s') create_time
+	FROM plant.orders o
+	WHERE ` + pageWhere + `
+	ORDER BY o.id DESC
 	LIMIT ? OFFSET ?
 	;`
-	queryResult := db.Raw(orderQuery, uid, pageSize, offset).Scan(&orderBaseList)
+	queryResult := db.Raw(orderQuery, append(pageArgs, pageSize, offset)...).Scan(&orderBaseList)
 	if queryResult.Error != nil {
 		slog.Error("获取用户的订单失败", slog.Any("error", queryResult.Error))
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -111,6 +204,12 @@ func GetOrders(c *gin.Context) {
 		return
 	}
 
+	// 6.1 当前页满页时返回下一页游标
+	var nextCursor string
+	if len(orderBaseList) == pageSize {
+		nextCursor = strconv.FormatUint(orderBaseList[len(orderBaseList)-1].OrderId, 10)
+	}
+
 	// 7. 批量查询订单项（核心优化点）
 	// 7.1 提取当前页所有订单ID
 	var orderIds []uint64
@@ -123,8 +222,10 @@ func GetOrders(c *gin.Context) {
 			"success": true,
 			"message": "",
 			"data": gin.H{
-				"list":  []Order{},
-				"total": total,
+				"list":          []Order{},
+				"total":         total,
+				"next_cursor":   "",
+				"status_counts": statusCounts,
 			},
 		})
 		return
@@ -165,6 +266,7 @@ func GetOrders(c *gin.Context) {
 	// 8. 组装最终订单数据（从map取订单项，无循环SQL）
 	var orderList []Order
 	for _, base := range orderBaseList {
+		base.OrderStatusLabel = base.OrderStatus.Label()
 		order := Order{
 			OrderBase:  base,
 			OrderItems: itemMap[base.OrderId],
@@ -177,8 +279,10 @@ func GetOrders(c *gin.Context) {
 		"success": true,
 		"message": "",
 		"data": gin.H{
-			"list":  orderList, // 当前页订单列表
-			"total": total,     // 订单总条数
+			"list":          orderList,    // 当前页订单列表
+			"total":         total,        // 筛选后的订单总条数
+			"next_cursor":   nextCursor,   // 下一页游标，为空表示没有更多
+			"status_counts": statusCounts, // 各状态订单数，key 为订单状态
 		},
 	})
 }
@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
+	"github.com/sunzhaoc/plant_be/pkg/money"
 )
 
 func GetPlantDetail(c *gin.Context) {
@@ -21,10 +22,10 @@ func GetPlantDetail(c *gin.Context) {
 
 	// 获取植物规格数据
 	type PlantSku = struct {
-		SkuId uint64  `json:"sku_id"`
-		Size  string  `json:"size"`
-		Price float64 `json:"price"`
-		Stock uint    `json:"stock"`
+		SkuId uint64      `json:"sku_id"`
+		Size  string      `json:"size"`
+		Price money.Money `json:"price"`
+		Stock uint        `json:"stock"`
 	}
 	var plantSkuList []PlantSku
 	query := "SELECT `id` sku_id, `size`, price, stock FROM plant.plant_sku WHERE plant_id = ? ORDER BY sort;"
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
+)
+
+// GetPlantFilters 获取植物目录可用的分类与标签，供列表页渲染筛选项
+func GetPlantFilters(c *gin.Context) {
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	type Category = struct {
+		CategoryId uint64 `json:"category_id"`
+		Name       string `json:"name"`
+	}
+	categoryList := make([]Category, 0)
+	query := "SELECT id category_id, name FROM plant.plant_categories ORDER BY sort, id;"
+	if err := db.Raw(query).Scan(&categoryList).Error; err != nil {
+		slog.Error("查询植物分类失败", slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "查询植物分类失败"})
+		return
+	}
+
+	type Tag = struct {
+		TagId uint64 `json:"tag_id"`
+		Name  string `json:"name"`
+	}
+	tagList := make([]Tag, 0)
+	query = "SELECT id tag_id, name FROM plant.plant_tags ORDER BY id;"
+	if err := db.Raw(query).Scan(&tagList).Error; err != nil {
+		slog.Error("查询植物标签失败", slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "查询植物标签失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"categories": categoryList,
+			"tags":       tagList,
+		},
+	})
+}
@@ -3,11 +3,38 @@ package api
 import (
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
+	"github.com/sunzhaoc/plant_be/pkg/money"
 )
 
+// plantSortOrders 支持的排序方式，key 为查询参数 sort 的取值
+var plantSortOrders = map[string]string{
+	"default":    "p.id ASC",
+	"price_asc":  "p.min_price ASC, p.id ASC",
+	"price_desc": "p.min_price DESC, p.id ASC",
+	"newest":     "p.id DESC",
+	"popularity": "p.sales_count DESC, p.id ASC",
+}
+
+// likeEscaper 转义 LIKE 通配符
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// GetPlants 查询上架植物列表
+//
+// 查询参数（均可选）：
+//
+//	keyword     按中文名、拉丁学名模糊搜索
+//	minPrice    起始价格下限
+//	maxPrice    起始价格上限
+//	categoryId  分类ID
+//	tagId       标签ID
+//	sort        default | price_asc | price_desc | newest | popularity
+//	page        页码，默认1
+//	pageSize    每页条数，默认20，最大50
 func GetPlants(c *gin.Context) {
 	db, err := mysql.GetDB("ali")
 	if err != nil {
@@ -16,17 +43,93 @@ func GetPlants(c *gin.Context) {
 		return
 	}
 
+	// 1. 解析分页参数
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if err != nil || pageSize < 1 || pageSize > 50 {
+		pageSize = 20
+	}
+	orderBy, ok := plantSortOrders[c.DefaultQuery("sort", "default")]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "不支持的排序方式"})
+		return
+	}
+
+	// 2. 构建筛选条件
+	conditions := []string{"p.is_on_sale = 1"}
+	var args []interface{}
+	if keyword := strings.TrimSpace(c.Query("keyword")); keyword != "" {
+		pattern := "%" + likeEscaper.Replace(keyword) + "%"
+		conditions = append(conditions, "(p.name LIKE ? OR p.latin_name LIKE ?)")
+		args = append(args, pattern, pattern)
+	}
+	if v := c.Query("minPrice"); v != "" {
+		minPrice, err := money.Parse(v)
+		if err != nil || minPrice < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "价格区间参数错误"})
+			return
+		}
+		conditions = append(conditions, "p.min_price >= ?")
+		args = append(args, minPrice)
+	}
+	if v := c.Query("maxPrice"); v != "" {
+		maxPrice, err := money.Parse(v)
+		if err != nil || maxPrice < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "价格区间参数错误"})
+			return
+		}
+		conditions = append(conditions, "p.min_price <= ?")
+		args = append(args, maxPrice)
+	}
+	if v := c.Query("categoryId"); v != "" {
+		categoryId, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "分类参数错误"})
+			return
+		}
+		conditions = append(conditions, "p.category_id = ?")
+		args = append(args, categoryId)
+	}
+	if v := c.Query("tagId"); v != "" {
+		tagId, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "标签参数错误"})
+			return
+		}
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM plant.plant_tag_relations r WHERE r.plant_id = p.id AND r.tag_id = ?)")
+		args = append(args, tagId)
+	}
+	where := strings.Join(conditions, " AND ")
+
+	// 3. 查询总数
+	var total int64
+	countQuery := "SELECT COUNT(*) FROM plant.plants p WHERE " + where
+	if err := db.Raw(countQuery, args...).Scan(&total).Error; err != nil {
+		slog.Error("查询植物总数失败", slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "查询植物列表失败",
+		})
+		return
+	}
+
 	type Plant = struct {
-		PlantId    uint64  `json:"plant_id"`     // 改为uint64匹配数据库bigint unsigned类型
-		Name       string  `json:"name"`         // 中文名
-		LatinName  string  `json:"latin_name"`   // 拉丁学名
-		MainImgUrl string  `json:"main_img_url"` // 主图地址
-		MinPrice   float64 `json:"min_price"`    // 起始价格
+		PlantId    uint64      `json:"plant_id"`     // 改为uint64匹配数据库bigint unsigned类型
+		Name       string      `json:"name"`         // 中文名
+		LatinName  string      `json:"latin_name"`   // 拉丁学名
+		MainImgUrl string      `json:"main_img_url"` // 主图地址
+		MinPrice   money.Money `json:"min_price"`    // 起始价格
 	}
-	var plantList []Plant
+	plantList := make([]Plant, 0, pageSize)
 
-	query := "SELECT id plant_id, name, latin_name, main_img_url, min_price FROM plant.plants WHERE is_on_sale = 1;"
-	result := db.Raw(query).Scan(&plantList)
+	// 4. 查询当前页
+	query := "SELECT p.id plant_id, p.name, p.latin_name, p.main_img_url, p.min_price FROM plant.plants p " +
+		"WHERE " + where + " ORDER BY " + orderBy + " LIMIT ? OFFSET ?"
+	queryArgs := append(args, pageSize, (page-1)*pageSize)
+	result := db.Raw(query, queryArgs...).Scan(&plantList)
 
 	if result.Error != nil {
 		slog.Error("查询植物列表失败", slog.Any("error", result.Error))
@@ -38,9 +141,12 @@ func GetPlants(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "查询上架植物列表成功",
-		"data":    plantList,
-		"count":   len(plantList),
+		"success":  true,
+		"message":  "查询上架植物列表成功",
+		"data":     plantList,
+		"count":    len(plantList),
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
 	})
 }
@@ -0,0 +1,14 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sunzhaoc/plant_be/pkg/utils"
+)
+
+// GetJWKS 公开JWT验签公钥（JWKS格式），供其他服务校验本服务签发的Token
+func GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, utils.JWKS())
+}
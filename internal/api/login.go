@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sunzhaoc/plant_be/internal/auth"
 	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
 	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
 	"github.com/sunzhaoc/plant_be/pkg/utils"
@@ -77,23 +78,16 @@ func PostLogin(c *gin.Context) {
 		return
 	}
 
-	// 生成 JWT token
-	token, err := utils.GenerateToken(user.ID, user.Username)
+	// 生成 Access Token 与 Refresh Token
+	pair, err := auth.IssueTokens(c.Request.Context(), user.ID, user.Username)
 	if err != nil {
-		slog.Error(fmt.Sprintf("生成[%d][%v]的JWT Token失败", user.ID, user.Username), err)
+		slog.Error(fmt.Sprintf("生成[%d][%v]的JWT Token失败", user.ID, user.Username), "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
 		return
 	}
 
 	// 设置HttpOnly Cookie
-	c.SetCookie(
-		"plant_token",                    // Cookie名称
-		token,                            // Cookie值（Token）
-		int(utils.TokenExpire.Seconds()), // Token 过期时间（秒）
-		"/",                              // 生效路径（全站）
-		"",                               // 生效域名（空表示当前域名）
-		true,                             // 是否开启HTTPS（生产环境建议true，开发环境false）
-		true,                             // 是否开启HttpOnly（防止XSS攻击，无法通过JS获取）
-	)
+	setAuthCookies(c, pair)
 
 	slog.Info(fmt.Sprintf("[%d][%v]登录成功", user.ID, user.Username))
 	c.JSON(http.StatusOK, gin.H{
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sunzhaoc/plant_be/internal/fulfillment"
+	"github.com/sunzhaoc/plant_be/internal/orders"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"gorm.io/gorm"
+)
+
+// respondOrderError 订单操作错误映射为对应的响应
+func respondOrderError(c *gin.Context, err error, logMsg string, args ...any) {
+	switch {
+	case errors.Is(err, orders.ErrOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": orders.ErrOrderNotFound.Error()})
+	case errors.Is(err, orders.ErrIllegalTransition):
+		c.JSON(http.StatusConflict, gin.H{"success": false, "message": err.Error()})
+	case errors.Is(err, orders.ErrOrderExpired):
+		c.JSON(http.StatusConflict, gin.H{"success": false, "message": orders.ErrOrderExpired.Error()})
+	case errors.Is(err, orders.ErrPrepayFailed):
+		slog.Error(logMsg, append(args, "error", err)...)
+		c.JSON(http.StatusBadGateway, gin.H{"success": false, "message": orders.ErrPrepayFailed.Error()})
+	case errors.Is(err, orders.ErrPaymentPending):
+		slog.Warn(logMsg, append(args, "error", err)...)
+		c.JSON(http.StatusConflict, gin.H{"success": false, "message": orders.ErrPaymentPending.Error()})
+	default:
+		slog.Error(logMsg, append(args, "error", err)...)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+	}
+}
+
+// GetOrderDetail 订单详情：订单金额、收货地址、商品明细、状态变更记录与物流轨迹，仅可查看本人订单
+func GetOrderDetail(c *gin.Context) {
+	userId, ok := getUserId(c)
+	if !ok {
+		return
+	}
+	orderSn := c.Param("orderSn")
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	order, err := orders.FindForUser(db, userId, orderSn)
+	if err != nil {
+		respondOrderError(c, err, "查询订单失败", "orderSn", orderSn)
+		return
+	}
+
+	data, err := orderDetailView(db, order)
+	if err != nil {
+		slog.Error("查询订单详情失败", "orderSn", orderSn, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "", "data": data})
+}
+
+// orderDetailView 组装订单详情：金额、收货地址、商品明细、状态变更记录与物流轨迹
+func orderDetailView(db *gorm.DB, order *models.Orders) (gin.H, error) {
+	var items []models.OrderItem
+	if err := db.Where("order_id = ?", order.Id).Order("id").Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("查询订单项失败: %w", err)
+	}
+	var history []models.OrderStatusHistory
+	if err := db.Where("order_id = ?", order.Id).Order("id").Find(&history).Error; err != nil {
+		return nil, fmt.Errorf("查询订单状态记录失败: %w", err)
+	}
+
+	itemList := make([]gin.H, 0, len(items))
+	for _, item := range items {
+		itemList = append(itemList, gin.H{
+			"order_item_id":    item.Id,
+			"plant_id":         item.PlantId,
+			"sku_id":           item.SkuId,
+			"plant_name":       item.PlantName,
+			"plant_latin_name": item.PlantLatinName,
+			"sku_size":         item.SkuSize,
+			"main_img_url":     item.MainImgUrl,
+			"price":            item.Price,
+			"quantity":         item.Quantity,
+			"discount_amount":  item.DiscountAmount,
+		})
+	}
+	timeline := make([]gin.H, 0, len(history))
+	for _, h := range history {
+		entry := gin.H{
+			"to_status":       h.ToStatus,
+			"to_status_label": h.ToStatus.Label(),
+			"operator_type":   h.OperatorType,
+			"remark":          h.Remark,
+			"create_time":     h.CreateTime.Format("2006-01-02 15:04:05"),
+		}
+		if h.FromStatus != nil {
+			entry["from_status"] = *h.FromStatus
+			entry["from_status_label"] = h.FromStatus.Label()
+		}
+		timeline = append(timeline, entry)
+	}
+	details, err := fulfillment.Shipments(db, order.Id)
+	if err != nil {
+		return nil, err
+	}
+	shipments := make([]gin.H, 0, len(details))
+	for _, d := range details {
+		shipments = append(shipments, shipmentView(d))
+	}
+
+	data := gin.H{
+		"order_id":           order.Id,
+		"order_sn":           order.OrderSn,
+		"total_amount":       order.TotalAmount,
+		"shipping_fee":       order.ShippingFee,
+		"discount_amount":    order.DiscountAmount,
+		"pay_amount":         order.PayAmount,
+		"refund_amount":      order.RefundAmount,
+		"order_status":       order.OrderStatus,
+		"order_status_label": order.OrderStatus.Label(),
+		"pay_channel":        order.PayChannel,
+		"address": gin.H{
+			"receiver_name":  order.ReceiverName,
+			"receiver_phone": order.ReceiverPhone,
+			"province":       order.ReceiverProvince,
+			"city":           order.ReceiverCity,
+			"area":           order.ReceiverArea,
+			"detail":         order.ReceiverDetail,
+			"full_address":   order.ReceiverAddress,
+		},
+		"order_items": itemList,
+		"timeline":    timeline,
+		"shipments":   shipments,
+		"create_time": order.CreateTime.Format("2006-01-02 15:04:05"),
+	}
+	if order.PayTime != nil {
+		data["pay_time"] = order.PayTime.Format("2006-01-02 15:04:05")
+	}
+	return data, nil
+}
+
+// CancelOrder 用户取消本人的待支付订单，释放预占库存与优惠券
+func CancelOrder(c *gin.Context) {
+	userId, ok := getUserId(c)
+	if !ok {
+		return
+	}
+	orderSn := c.Param("orderSn")
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	if err := orders.UserCancel(c.Request.Context(), db, userId, orderSn); err != nil {
+		respondOrderError(c, err, "取消订单失败", "uid", userId, "orderSn", orderSn)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "订单已取消"})
+}
+
+// PayOrder 对本人的待支付订单重新发起支付，返回新的预支付信息
+func PayOrder(c *gin.Context) {
+	userId, ok := getUserId(c)
+	if !ok {
+		return
+	}
+	orderSn := c.Param("orderSn")
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	order, prepay, err := orders.Repay(c.Request.Context(), db, userId, orderSn, c.ClientIP())
+	if err != nil {
+		respondOrderError(c, err, "重新发起支付失败", "uid", userId, "orderSn", orderSn)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"orderSn":    order.OrderSn,
+			"payAmount":  order.PayAmount,
+			"expireTime": orders.ExpireAt(order).Format("2006-01-02 15:04:05"),
+			"payment":    prepay,
+		},
+	})
+}
+
+// ConfirmOrder 用户确认收货
+func ConfirmOrder(c *gin.Context) {
+	userId, ok := getUserId(c)
+	if !ok {
+		return
+	}
+	orderSn := c.Param("orderSn")
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	if err := orders.Confirm(db, userId, orderSn); err != nil {
+		respondOrderError(c, err, "确认收货失败", "uid", userId, "orderSn", orderSn)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "已确认收货"})
+}
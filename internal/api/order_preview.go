@@ -0,0 +1,157 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sunzhaoc/plant_be/internal/coupon"
+	"github.com/sunzhaoc/plant_be/internal/inventory"
+	"github.com/sunzhaoc/plant_be/internal/orders"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+)
+
+// PreviewOrder 结算预览：按当前单价、可用库存、收货地区运费与优惠券计算订单金额明细，并列出可用券及各自的优惠金额，
+// 不预占库存、不锁定优惠券
+//
+// 未传收货地址时不计算运费；传入地址且全部商品有货时返回报价令牌 quoteToken，CreatePayment 携带该令牌时会校验实付金额与预览一致
+func PreviewOrder(c *gin.Context) {
+	userId, ok := getUserId(c)
+	if !ok {
+		return
+	}
+	var req PaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "参数校验失败", "error": err.Error()})
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	// 运费按收货地区计算，与下单时解析地址的规则一致
+	var shipTo string
+	if req.AddressId != 0 || req.Address != (Address{}) {
+		address, msg, err := resolveCheckoutAddress(db, userId, req)
+		if err != nil {
+			slog.Error("查询收货地址失败", "uid", userId, "addressId", req.AddressId, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+			return
+		}
+		if msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": msg})
+			return
+		}
+		shipTo = address.ProvinceCode
+	}
+
+	lines := make([]orders.PlaceLine, 0, len(req.CartItems))
+	for _, item := range req.CartItems {
+		lines = append(lines, orders.PlaceLine{SkuId: item.SkuId, Quantity: item.Quantity})
+	}
+	pricing, err := orders.Quote(c.Request.Context(), db, userId, shipTo, lines, req.UserCouponId)
+	if err != nil {
+		respondPlaceError(c, err)
+		return
+	}
+
+	// 同一 SKU 多行时按合计数量判断是否有货
+	quantities := make(map[uint64]uint, len(lines))
+	plantIdSet := make(map[uint64]struct{}, len(lines))
+	for _, item := range pricing.Items {
+		quantities[item.SkuId] += item.Quantity
+		plantIdSet[item.PlantId] = struct{}{}
+	}
+	skuIds := make([]uint64, 0, len(quantities))
+	for skuId := range quantities {
+		skuIds = append(skuIds, skuId)
+	}
+	available, err := inventory.Available(c.Request.Context(), skuIds)
+	if err != nil {
+		slog.Error("查询可用库存失败", "uid", userId, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	plantIds := make([]uint64, 0, len(plantIdSet))
+	for plantId := range plantIdSet {
+		plantIds = append(plantIds, plantId)
+	}
+	var plantList []models.Plant
+	if err := db.Select("id", "name", "main_img_url").Where("id IN ?", plantIds).Find(&plantList).Error; err != nil {
+		slog.Error("查询植物信息失败", "uid", userId, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	plantMap := make(map[uint64]models.Plant, len(plantList))
+	for _, plant := range plantList {
+		plantMap[plant.Id] = plant
+	}
+
+	allInStock := true
+	items := make([]gin.H, 0, len(pricing.Items))
+	for i, item := range pricing.Items {
+		inStock := available[item.SkuId] >= int64(quantities[item.SkuId])
+		if !inStock {
+			allInStock = false
+		}
+		plant := plantMap[item.PlantId]
+		items = append(items, gin.H{
+			"plantId":   item.PlantId,
+			"plantName": plant.Name,
+			"imgUrl":    plant.MainImgUrl,
+			"skuId":     item.SkuId,
+			"size":      item.Size,
+			"price":     item.UnitPrice,
+			"quantity":  item.Quantity,
+			"amount":    item.Amount,
+			"discount":  pricing.ItemDiscounts[i],
+			"available": available[item.SkuId],
+			"inStock":   inStock,
+		})
+	}
+
+	owned, err := coupon.ListOwned(db, userId)
+	if err != nil {
+		slog.Error("查询用户优惠券失败", "uid", userId, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	now := time.Now()
+	usable := make([]gin.H, 0)
+	for _, o := range owned {
+		if !o.Usable(now) {
+			continue
+		}
+		discount, err := coupon.Discount(o.Coupon, pricing.Subtotal, pricing.Shipping)
+		if err != nil {
+			continue
+		}
+		view := ownedCouponView(o)
+		view["discount"] = discount
+		usable = append(usable, view)
+	}
+
+	data := gin.H{
+		"items":        items,
+		"subtotal":     pricing.Subtotal,
+		"shipping":     pricing.Shipping,
+		"discount":     pricing.Discount,
+		"payAmount":    pricing.PayAmount,
+		"userCouponId": req.UserCouponId,
+		"allInStock":   allInStock,
+		"coupons":      usable,
+	}
+	// 报价须包含运费，未传地址时不签发
+	if allInStock && shipTo != "" {
+		token, expireAt := orders.SignQuote(userId, lines, req.UserCouponId, pricing.PayAmount)
+		data["quoteToken"] = token
+		data["quoteExpireTime"] = expireAt.Format("2006-01-02 15:04:05")
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "", "data": data})
+}
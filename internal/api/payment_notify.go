@@ -0,0 +1,135 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sunzhaoc/plant_be/internal/inventory"
+	"github.com/sunzhaoc/plant_be/internal/orders"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"github.com/sunzhaoc/plant_be/pkg/payment"
+	"gorm.io/gorm"
+)
+
+// PaymentNotify 处理支付渠道的异步通知
+//
+// 校验签名与金额后将订单从待支付改为已支付；重复通知直接应答成功，保证幂等
+func PaymentNotify(c *gin.Context) {
+	provider, err := payment.Get(c.Param("provider"))
+	if err != nil {
+		c.String(http.StatusNotFound, "unknown provider")
+		return
+	}
+	ack := func(success bool) {
+		status, body := provider.NotifyAck(success)
+		c.String(status, body)
+	}
+
+	notify, err := provider.VerifyNotify(c.Request)
+	if err != nil {
+		slog.Error("支付通知验签失败", "provider", provider.Name(), "error", err)
+		ack(false)
+		return
+	}
+	if !notify.Paid {
+		// 非支付成功类通知（如交易关闭）无需处理
+		slog.Info("收到非支付成功通知", "provider", provider.Name(), "orderSn", notify.OrderSn)
+		ack(true)
+		return
+	}
+
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		ack(false)
+		return
+	}
+
+	var order models.Orders
+	if err := db.Where("order_sn = ?", notify.OrderSn).Take(&order).Error; err != nil {
+		slog.Error("支付通知对应订单不存在", "orderSn", notify.OrderSn, "error", err)
+		ack(false)
+		return
+	}
+	if order.PayChannel != provider.Name() {
+		slog.Error("支付通知渠道与订单不一致", "orderSn", notify.OrderSn, "orderChannel", order.PayChannel, "provider", provider.Name())
+		ack(false)
+		return
+	}
+	if order.PayAmount != notify.Amount {
+		slog.Error("支付通知金额与订单不一致", "orderSn", notify.OrderSn, "payAmount", order.PayAmount, "notifyAmount", notify.Amount)
+		ack(false)
+		return
+	}
+
+	// 状态机在行锁内校验，并发或重复通知只会有一次从待支付流转为已支付；同一事务内累加销量并确认库存预占
+	var from models.OrderStatus
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		from, err = orders.Transition(tx, orders.Change{
+			OrderId:  order.Id,
+			To:       models.OrderStatusPaid,
+			Operator: orders.System,
+			Remark:   fmt.Sprintf("%s支付成功，交易号%s", provider.Name(), notify.TradeNo),
+			Fields: map[string]interface{}{
+				"trade_no": notify.TradeNo,
+				"pay_time": notify.PaidAt,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if err := orders.RecordSales(tx, order.Id); err != nil {
+			return err
+		}
+		_, err = inventory.Confirm(tx, order.OrderSn)
+		return err
+	})
+	if errors.Is(err, orders.ErrIllegalTransition) {
+		if from != models.OrderStatusPaid {
+			// 订单已取消等非待支付状态却收到支付成功，记录订单异常由人工退款；记录失败时不应答成功，等待渠道重推
+			slog.Error("非待支付订单收到支付成功通知", "orderSn", notify.OrderSn, "orderStatus", from.Label(), "tradeNo", notify.TradeNo)
+			if err := recordPaidAfterClosed(db, notify, provider.Name(), from); err != nil {
+				slog.Error("记录订单异常失败", "orderSn", notify.OrderSn, "error", err)
+				ack(false)
+				return
+			}
+		}
+		ack(true)
+		return
+	}
+	if err != nil {
+		slog.Error("更新订单支付状态失败", "orderSn", notify.OrderSn, "error", err)
+		ack(false)
+		return
+	}
+
+	if err := inventory.Settle(c.Request.Context(), order.OrderSn); err != nil {
+		slog.Error("结算预占库存失败", "orderSn", order.OrderSn, "error", err)
+	}
+
+	slog.Info("订单支付成功", "orderSn", notify.OrderSn, "provider", provider.Name(), "tradeNo", notify.TradeNo)
+	ack(true)
+}
+
+// recordPaidAfterClosed 记录非待支付订单收到的支付，同一交易号重复通知只记录一次
+func recordPaidAfterClosed(db *gorm.DB, notify *payment.NotifyResult, channel string, status models.OrderStatus) error {
+	detail := fmt.Sprintf("订单%s时收到%s支付成功通知，交易号%s，金额%s元，需原路退款",
+		status.Label(), channel, notify.TradeNo, notify.Amount)
+	var count int64
+	err := db.Model(&models.OrderException{}).
+		Where("order_sn = ? AND kind = ? AND detail = ?", notify.OrderSn, models.ExceptionPaidAfterClosed, detail).
+		Count(&count).Error
+	if err != nil || count > 0 {
+		return err
+	}
+	return db.Create(&models.OrderException{
+		OrderSn: notify.OrderSn,
+		Kind:    models.ExceptionPaidAfterClosed,
+		Detail:  detail,
+	}).Error
+}
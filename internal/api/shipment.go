@@ -0,0 +1,245 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sunzhaoc/plant_be/internal/audit"
+	"github.com/sunzhaoc/plant_be/internal/fulfillment"
+	"github.com/sunzhaoc/plant_be/internal/orders"
+	"github.com/sunzhaoc/plant_be/pkg/carrier"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"gorm.io/gorm"
+)
+
+// AdminShipLineRequest 包裹中一个订单项的件数
+type AdminShipLineRequest struct {
+	OrderItemId uint64 `json:"orderItemId" binding:"required"`
+	Quantity    uint   `json:"quantity" binding:"required,min=1"`
+}
+
+// AdminShipmentRequest 创建发货包裹请求
+type AdminShipmentRequest struct {
+	Carrier    string                 `json:"carrier" binding:"required,max=32"`
+	TrackingNo string                 `json:"trackingNo" binding:"required,max=64"`
+	Items      []AdminShipLineRequest `json:"items" binding:"dive"` // 为空时发出全部待发货商品
+	Remark     string                 `json:"remark" binding:"max=255"`
+}
+
+// AdminMarkShippedRequest 标记订单已发货请求
+type AdminMarkShippedRequest struct {
+	Remark string `json:"remark" binding:"max=255"`
+}
+
+func shipmentView(d fulfillment.Detail) gin.H {
+	items := make([]gin.H, 0, len(d.Items))
+	for _, item := range d.Items {
+		items = append(items, gin.H{"order_item_id": item.OrderItemId, "quantity": item.Quantity})
+	}
+	events := make([]gin.H, 0, len(d.Events))
+	for _, e := range d.Events {
+		events = append(events, gin.H{
+			"status":       e.Status,
+			"status_label": carrier.EventStatus(e.Status).Label(),
+			"location":     e.Location,
+			"description":  e.Description,
+			"event_time":   e.EventTime.Format("2006-01-02 15:04:05"),
+		})
+	}
+	view := gin.H{
+		"shipment_id":  d.Id,
+		"carrier":      d.Carrier,
+		"tracking_no":  d.TrackingNo,
+		"status":       d.Status,
+		"status_label": carrier.EventStatus(d.Status).Label(),
+		"shipped_at":   d.ShippedAt.Format("2006-01-02 15:04:05"),
+		"items":        items,
+		"events":       events,
+	}
+	if d.DeliveredAt != nil {
+		view["delivered_at"] = d.DeliveredAt.Format("2006-01-02 15:04:05")
+	}
+	return view
+}
+
+// respondShipmentError 发货业务错误映射为对应的响应
+func respondShipmentError(c *gin.Context, err error, logMsg string, args ...any) {
+	switch {
+	case errors.Is(err, orders.ErrOrderNotFound), errors.Is(err, fulfillment.ErrShipmentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": err.Error()})
+	case errors.Is(err, fulfillment.ErrCarrierUnsupported), errors.Is(err, fulfillment.ErrItemNotFound), errors.Is(err, fulfillment.ErrQuantityExceeded):
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+	case errors.Is(err, fulfillment.ErrOrderNotShippable), errors.Is(err, fulfillment.ErrDuplicateTracking),
+		errors.Is(err, fulfillment.ErrNothingToShip), errors.Is(err, fulfillment.ErrNoShipment), errors.Is(err, orders.ErrIllegalTransition):
+		c.JSON(http.StatusConflict, gin.H{"success": false, "message": err.Error()})
+	default:
+		slog.Error(logMsg, append(args, "error", err)...)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+	}
+}
+
+// AdminCreateShipment 为订单创建发货包裹，支持拆单发货；商品全部发出后订单自动变为已发货
+func AdminCreateShipment(c *gin.Context) {
+	adminId, ok := getUserId(c)
+	if !ok {
+		return
+	}
+	orderId, ok := parseUintParam(c, "orderId")
+	if !ok {
+		return
+	}
+	var req AdminShipmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "参数校验失败", "error": err.Error()})
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	lines := make([]fulfillment.ShipLine, 0, len(req.Items))
+	for _, item := range req.Items {
+		lines = append(lines, fulfillment.ShipLine{OrderItemId: item.OrderItemId, Quantity: item.Quantity})
+	}
+	shipment, err := fulfillment.Ship(db, fulfillment.ShipRequest{
+		OrderId:    orderId,
+		Carrier:    req.Carrier,
+		TrackingNo: req.TrackingNo,
+		Lines:      lines,
+		Remark:     req.Remark,
+	}, orders.Admin(adminId), func(tx *gorm.DB, shipment models.Shipment) error {
+		return audit.Record(tx, c, "shipment.create", "order", orderId, nil, gin.H{
+			"shipment_id": shipment.Id,
+			"carrier":     shipment.Carrier,
+			"tracking_no": shipment.TrackingNo,
+			"items":       req.Items,
+		})
+	})
+	if err != nil {
+		respondShipmentError(c, err, "创建发货包裹失败", "orderId", orderId)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "发货成功", "data": gin.H{"shipment_id": shipment.Id}})
+}
+
+// AdminMarkOrderShipped 部分发货的订单不再发出剩余商品时，手动标记为已发货
+func AdminMarkOrderShipped(c *gin.Context) {
+	adminId, ok := getUserId(c)
+	if !ok {
+		return
+	}
+	orderId, ok := parseUintParam(c, "orderId")
+	if !ok {
+		return
+	}
+	var req AdminMarkShippedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "参数校验失败", "error": err.Error()})
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := fulfillment.MarkShipped(tx, orderId, orders.Admin(adminId), req.Remark); err != nil {
+			return err
+		}
+		return audit.Record(tx, c, "order.mark_shipped", "order", orderId,
+			gin.H{"order_status": models.OrderStatusPaid}, gin.H{"order_status": models.OrderStatusShipped, "remark": req.Remark})
+	})
+	if err != nil {
+		respondShipmentError(c, err, "标记订单已发货失败", "orderId", orderId)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "已标记为已发货"})
+}
+
+// AdminGetOrderShipments 订单的发货包裹与物流轨迹
+func AdminGetOrderShipments(c *gin.Context) {
+	orderId, ok := parseUintParam(c, "orderId")
+	if !ok {
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	details, err := fulfillment.Shipments(db, orderId)
+	if err != nil {
+		slog.Error("查询发货包裹失败", "orderId", orderId, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	list := make([]gin.H, 0, len(details))
+	for _, d := range details {
+		view := shipmentView(d)
+		view["operator_id"] = d.OperatorId
+		view["remark"] = d.Remark
+		list = append(list, view)
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "", "data": list})
+}
+
+// AdminSyncShipment 主动向物流公司查询包裹轨迹，用于推送丢失时补偿
+func AdminSyncShipment(c *gin.Context) {
+	shipmentId, ok := parseUintParam(c, "shipmentId")
+	if !ok {
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	if err := fulfillment.Sync(c.Request.Context(), db, shipmentId); err != nil {
+		respondShipmentError(c, err, "同步物流轨迹失败", "shipmentId", shipmentId)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "同步成功"})
+}
+
+// CarrierNotify 处理物流公司的轨迹推送
+//
+// 校验签名后写入轨迹，重复推送按（包裹、时间、状态）去重，保证幂等
+func CarrierNotify(c *gin.Context) {
+	cr, err := carrier.Get(c.Param("carrier"))
+	if err != nil {
+		c.String(http.StatusNotFound, "unknown carrier")
+		return
+	}
+	ack := func(success bool) {
+		status, body := cr.PushAck(success)
+		c.String(status, body)
+	}
+
+	events, err := cr.VerifyPush(c.Request)
+	if err != nil {
+		slog.Error("物流推送验签失败", "carrier", cr.Name(), "error", err)
+		ack(false)
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		ack(false)
+		return
+	}
+	if err := fulfillment.Ingest(db, cr.Name(), events); err != nil {
+		slog.Error("写入物流轨迹失败", "carrier", cr.Name(), "error", err)
+		ack(false)
+		return
+	}
+	ack(true)
+}
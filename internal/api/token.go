@@ -0,0 +1,110 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sunzhaoc/plant_be/internal/auth"
+	"github.com/sunzhaoc/plant_be/pkg/utils"
+)
+
+const (
+	AccessTokenCookie  = "plant_token"
+	RefreshTokenCookie = "plant_refresh_token"
+)
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken"` // 优先读取Cookie，兼容非浏览器客户端在请求体中携带
+}
+
+// setAuthCookies 以 HttpOnly Cookie 下发 Access/Refresh Token
+func setAuthCookies(c *gin.Context, pair *auth.TokenPair) {
+	c.SetCookie(AccessTokenCookie, pair.AccessToken, int(utils.TokenExpire.Seconds()), "/", "", true, true)
+	c.SetCookie(RefreshTokenCookie, pair.RefreshToken, int(utils.RefreshTokenExpire.Seconds()), "/", "", true, true)
+}
+
+// clearAuthCookies 清除登录态 Cookie
+func clearAuthCookies(c *gin.Context) {
+	c.SetCookie(AccessTokenCookie, "", -1, "/", "", true, true)
+	c.SetCookie(RefreshTokenCookie, "", -1, "/", "", true, true)
+}
+
+// PostRefreshToken 使用 Refresh Token 换取新的 Access Token（Refresh Token 同时轮换）
+func PostRefreshToken(c *gin.Context) {
+	refreshToken, err := c.Cookie(RefreshTokenCookie)
+	if err != nil || refreshToken == "" {
+		var req RefreshTokenRequest
+		_ = c.ShouldBindJSON(&req)
+		refreshToken = req.RefreshToken
+	}
+	if refreshToken == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未携带Refresh Token"})
+		return
+	}
+
+	pair, uid, err := auth.Refresh(c.Request.Context(), refreshToken)
+	if errors.Is(err, auth.ErrRefreshTokenInvalid) || errors.Is(err, auth.ErrRefreshTokenReused) {
+		if errors.Is(err, auth.ErrRefreshTokenReused) {
+			slog.Warn("检测到Refresh Token重放，已注销全部会话", "uid", uid)
+		}
+		clearAuthCookies(c)
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "登录已过期，请重新登录"})
+		return
+	}
+	if err != nil {
+		slog.Error("刷新Token失败", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	setAuthCookies(c, pair)
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "刷新成功"})
+}
+
+// PostLogout 退出当前设备：吊销当前 Access Token 与 Refresh Token
+//
+// Access Token 已过期时同样允许退出，因此不挂载 JWTAuthMiddleware
+func PostLogout(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if accessToken, err := c.Cookie(AccessTokenCookie); err == nil && accessToken != "" {
+		if claims, err := utils.ParseToken(accessToken, true); err == nil {
+			if err := auth.RevokeAccessToken(ctx, claims); err != nil {
+				slog.Error("吊销Access Token失败", "uid", claims.UserID, "error", err)
+			}
+		}
+	}
+	if refreshToken, err := c.Cookie(RefreshTokenCookie); err == nil && refreshToken != "" {
+		if err := auth.RevokeRefreshToken(ctx, refreshToken); err != nil {
+			slog.Error("吊销Refresh Token失败", "error", err)
+		}
+	}
+
+	clearAuthCookies(c)
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "已退出登录"})
+}
+
+// PostLogoutAll 退出所有设备
+func PostLogoutAll(c *gin.Context) {
+	uid, exists := c.Get("userId")
+	if !exists || uid == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "用户未登录",
+			"error":   "Unauthorized",
+		})
+		return
+	}
+
+	if err := auth.RevokeAll(c.Request.Context(), uid.(uint)); err != nil {
+		slog.Error("注销全部会话失败", "uid", uid, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+
+	slog.Info("用户已退出所有设备", "uid", uid)
+	clearAuthCookies(c)
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "已退出所有设备"})
+}
@@ -0,0 +1,59 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"gorm.io/gorm"
+)
+
+// Record 写入一条后台操作审计日志
+//
+// 操作人取自 JWTAuthMiddleware 写入上下文的 userId/username；before/after 为 nil 时对应列存 NULL。
+// 应与业务变更在同一事务中调用，保证“改了就有记录”
+func Record(tx *gorm.DB, c *gin.Context, action string, targetType string, targetId uint64, before any, after any) error {
+	var operatorId uint64
+	if uid, ok := c.Get("userId"); ok {
+		if v, ok := uid.(uint); ok {
+			operatorId = uint64(v)
+		}
+	}
+
+	beforeData, err := marshal(before)
+	if err != nil {
+		return err
+	}
+	afterData, err := marshal(after)
+	if err != nil {
+		return err
+	}
+
+	log := models.AdminAuditLog{
+		OperatorId:   operatorId,
+		OperatorName: c.GetString("username"),
+		Action:       action,
+		TargetType:   targetType,
+		TargetId:     targetId,
+		Before:       beforeData,
+		After:        afterData,
+		ClientIp:     c.ClientIP(),
+	}
+	if err := tx.Create(&log).Error; err != nil {
+		return fmt.Errorf("写入审计日志失败: %w", err)
+	}
+	return nil
+}
+
+func marshal(v any) (*string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("序列化审计数据失败: %w", err)
+	}
+	s := string(data)
+	return &s, nil
+}
@@ -0,0 +1,243 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/sunzhaoc/plant_be/internal/rbac"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
+	"github.com/sunzhaoc/plant_be/pkg/db/redis"
+	"github.com/sunzhaoc/plant_be/pkg/utils"
+)
+
+// Redis Key 约定：
+//
+//	auth:refresh:<hash>       Refresh Token -> 会话信息（STRING，TTL=Refresh Token 有效期）
+//	auth:refresh:used:<hash>  已轮换的 Refresh Token -> 用户ID，用于识别重放
+//	auth:refresh:u:<uid>      用户持有的 Refresh Token 哈希集合（SET）
+//	auth:deny:<jti>           已吊销的 Access Token（TTL=剩余有效期）
+//	auth:revoke_before:<uid>  该时间（毫秒时间戳）及之前签发的 Access Token 全部失效
+const (
+	refreshKeyPrefix     = "auth:refresh:"
+	refreshUsedKeyPrefix = "auth:refresh:used:"
+	userRefreshKeyPrefix = "auth:refresh:u:"
+	denyKeyPrefix        = "auth:deny:"
+	revokeBeforePrefix   = "auth:revoke_before:"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("Refresh Token无效或已过期")
+	ErrRefreshTokenReused  = errors.New("Refresh Token重复使用，已注销该用户全部会话")
+)
+
+// TokenPair 一次签发的 Access Token 与 Refresh Token
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+// session Refresh Token 对应的会话信息，角色与权限不在此缓存，每次签发时从数据库重新读取
+type session struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IssueTokens 为用户签发一组新的 Access/Refresh Token，Access Token 携带用户当前的角色与权限
+func IssueTokens(ctx context.Context, userID uint, username string) (*TokenPair, error) {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		return nil, err
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		return nil, err
+	}
+	access, err := rbac.LoadAccess(db.WithContext(ctx), uint64(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := utils.GenerateToken(userID, username, access.Roles, access.Permissions)
+	if err != nil {
+		return nil, fmt.Errorf("生成Access Token失败: %w", err)
+	}
+
+	refreshToken := utils.RandomToken(32)
+	hash := hashToken(refreshToken)
+	data, _ := json.Marshal(session{UserID: userID, Username: username})
+	userKey := fmt.Sprintf("%s%d", userRefreshKeyPrefix, userID)
+
+	pipe := rdb.TxPipeline()
+	pipe.Set(ctx, refreshKeyPrefix+hash, data, utils.RefreshTokenExpire)
+	pipe.SAdd(ctx, userKey, hash)
+	pipe.Expire(ctx, userKey, utils.RefreshTokenExpire)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("保存Refresh Token失败: %w", err)
+	}
+	return &TokenPair{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// Refresh 使用 Refresh Token 换取新的一组 Token，旧 Refresh Token 立即失效（轮换）
+//
+// 已轮换的 Refresh Token 再次出现时视为泄露，注销该用户全部会话
+func Refresh(ctx context.Context, refreshToken string) (*TokenPair, uint, error) {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	hash := hashToken(refreshToken)
+	data, err := rdb.GetDel(ctx, refreshKeyPrefix+hash).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		uidStr, usedErr := rdb.Get(ctx, refreshUsedKeyPrefix+hash).Result()
+		if usedErr == nil {
+			if uid, parseErr := strconv.ParseUint(uidStr, 10, 64); parseErr == nil {
+				if err := RevokeAll(ctx, uint(uid)); err != nil {
+					return nil, 0, err
+				}
+				return nil, uint(uid), ErrRefreshTokenReused
+			}
+		}
+		return nil, 0, ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("读取Refresh Token失败: %w", err)
+	}
+
+	var s session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, 0, ErrRefreshTokenInvalid
+	}
+
+	pipe := rdb.TxPipeline()
+	pipe.Set(ctx, refreshUsedKeyPrefix+hash, s.UserID, utils.RefreshTokenExpire)
+	pipe.SRem(ctx, fmt.Sprintf("%s%d", userRefreshKeyPrefix, s.UserID), hash)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, 0, fmt.Errorf("标记Refresh Token失败: %w", err)
+	}
+
+	pair, err := IssueTokens(ctx, s.UserID, s.Username)
+	if err != nil {
+		return nil, 0, err
+	}
+	return pair, s.UserID, nil
+}
+
+// RevokeRefreshToken 吊销单个 Refresh Token
+func RevokeRefreshToken(ctx context.Context, refreshToken string) error {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		return err
+	}
+	hash := hashToken(refreshToken)
+	data, err := rdb.GetDel(ctx, refreshKeyPrefix+hash).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var s session
+	if json.Unmarshal(data, &s) == nil {
+		rdb.SRem(ctx, fmt.Sprintf("%s%d", userRefreshKeyPrefix, s.UserID), hash)
+	}
+	return nil
+}
+
+// RevokeAccessToken 将 Access Token 的 jti 加入黑名单直至其自然过期
+func RevokeAccessToken(ctx context.Context, claims *utils.Claims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil
+	}
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		return err
+	}
+	return rdb.Set(ctx, denyKeyPrefix+claims.ID, 1, ttl).Err()
+}
+
+// revokeAllScript 删除用户持有的全部 Refresh Token 与集合本身并写入吊销时间点，与刷新时的 SADD/SREM 互斥
+//
+// KEYS: userRefreshKey, revokeBeforeKey
+// ARGV: refreshKeyPrefix, revokeBefore（毫秒）, ttl（毫秒）
+// Refresh Token 的 Key 由集合成员拼出，无法事先声明
+var revokeAllScript = goredis.NewScript(`
+local hashes = redis.call('SMEMBERS', KEYS[1])
+for _, hash in ipairs(hashes) do
+	redis.call('DEL', ARGV[1] .. hash)
+end
+redis.call('DEL', KEYS[1])
+redis.call('SET', KEYS[2], ARGV[2], 'PX', ARGV[3])
+return #hashes
+`)
+
+// RevokeAll 注销用户在所有设备上的会话：删除全部 Refresh Token，并使此前签发的 Access Token 失效
+func RevokeAll(ctx context.Context, userID uint) error {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		return err
+	}
+	keys := []string{fmt.Sprintf("%s%d", userRefreshKeyPrefix, userID), revokeBeforeKey(userID)}
+	err = revokeAllScript.Run(ctx, rdb, keys, refreshKeyPrefix, time.Now().UnixMilli(), utils.TokenExpire.Milliseconds()).Err()
+	if err != nil {
+		return fmt.Errorf("注销用户会话失败: %w", err)
+	}
+	return nil
+}
+
+// RevokeAccessTokens 使用户此前签发的 Access Token 全部失效，Refresh Token 保留
+//
+// 用于角色变更后让客户端通过刷新重新获取携带最新权限的 Token，而不必重新登录
+func RevokeAccessTokens(ctx context.Context, userID uint) error {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		return err
+	}
+	// Access Token 最长存活 TokenExpire，之后标记可自动清除
+	if err := rdb.Set(ctx, revokeBeforeKey(userID), time.Now().UnixMilli(), utils.TokenExpire).Err(); err != nil {
+		return fmt.Errorf("吊销用户Access Token失败: %w", err)
+	}
+	return nil
+}
+
+func revokeBeforeKey(userID uint) string {
+	return fmt.Sprintf("%s%d", revokeBeforePrefix, userID)
+}
+
+// IsRevoked 判断 Access Token 是否已被吊销（单个吊销或全部注销）
+func IsRevoked(ctx context.Context, claims *utils.Claims) (bool, error) {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		return false, err
+	}
+	values, err := rdb.MGet(ctx, denyKeyPrefix+claims.ID, revokeBeforeKey(claims.UserID)).Result()
+	if err != nil {
+		return false, err
+	}
+	if values[0] != nil {
+		return true, nil
+	}
+	if values[1] != nil {
+		revokeBefore, err := strconv.ParseInt(fmt.Sprint(values[1]), 10, 64)
+		if err == nil && claims.IssuedAtMilli() <= revokeBefore {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,287 @@
+package cart
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/sunzhaoc/plant_be/pkg/db/redis"
+	"github.com/sunzhaoc/plant_be/pkg/money"
+)
+
+// ExpireTime 购物车在 Redis 中的保留时间，每次写入后顺延
+const ExpireTime = 7 * 24 * time.Hour
+
+// 登录合并时同一商品在本地与服务端都存在的处理规则
+const (
+	MergeMax    = "max"    // 取两者较大值
+	MergeSum    = "sum"    // 两者相加
+	MergeServer = "server" // 以服务端为准
+)
+
+// Redis 中的购物车数据版本
+//
+//	v1  cart:u:<uid>     -> {"<plantId>:<size>": quantity}，已废弃，由 MigrateLegacy 转换
+//	v2  cart:v2:u:<uid>  -> {"<plantId>:<skuId>": itemValue(JSON)}
+const (
+	legacyKeyPrefix = "cart:u:"
+	keyPrefix       = "cart:v2:u:"
+)
+
+// Item 购物车项
+type Item struct {
+	PlantId    uint64
+	SkuId      uint64
+	Quantity   int
+	PriceAtAdd money.Money // 加入购物车时的单价，用于提示降价/涨价
+	AddedAt    time.Time   // 首次加入时间
+	Selected   bool        // 是否勾选结算
+}
+
+// itemValue Hash 中保存的购物车项元数据
+type itemValue struct {
+	Quantity   int         `json:"quantity"`
+	PriceAtAdd money.Money `json:"price"`
+	AddedAt    int64       `json:"addedAt"`
+	Selected   bool        `json:"selected"`
+}
+
+// Upsert 新增或修改一个购物车项
+//
+// 已存在的项保留首次加入时间与加入价，Price 仅在新增时使用；Selected 为空时新增默认勾选、修改保持不变
+type Upsert struct {
+	PlantId  uint64
+	SkuId    uint64
+	Quantity int
+	Price    money.Money // 商品当前单价
+	Selected *bool
+}
+
+// ItemKey 购物车项标识
+type ItemKey struct {
+	PlantId uint64
+	SkuId   uint64
+}
+
+func ValidStrategy(strategy string) bool {
+	return strategy == MergeMax || strategy == MergeSum || strategy == MergeServer
+}
+
+// Key 用户购物车的 Redis Key
+func Key(userId uint64) string {
+	return fmt.Sprintf("%s%d", keyPrefix, userId)
+}
+
+// Field 购物车项在 Hash 中的字段名
+func Field(plantId uint64, skuId uint64) string {
+	return fmt.Sprintf("%d:%d", plantId, skuId)
+}
+
+// parseItem 解析 Hash 中的一项
+func parseItem(field string, value string) (Item, bool) {
+	plantStr, skuStr, ok := strings.Cut(field, ":")
+	if !ok {
+		return Item{}, false
+	}
+	plantId, err := strconv.ParseUint(plantStr, 10, 64)
+	if err != nil {
+		return Item{}, false
+	}
+	skuId, err := strconv.ParseUint(skuStr, 10, 64)
+	if err != nil {
+		return Item{}, false
+	}
+	var v itemValue
+	if err := json.Unmarshal([]byte(value), &v); err != nil || v.Quantity <= 0 {
+		return Item{}, false
+	}
+	return Item{
+		PlantId:    plantId,
+		SkuId:      skuId,
+		Quantity:   v.Quantity,
+		PriceAtAdd: v.PriceAtAdd,
+		AddedAt:    time.Unix(v.AddedAt, 0),
+		Selected:   v.Selected,
+	}, true
+}
+
+func parseItems(hash map[string]string) map[string]Item {
+	items := make(map[string]Item, len(hash))
+	for field, value := range hash {
+		if item, ok := parseItem(field, value); ok {
+			items[field] = item
+		}
+	}
+	return items
+}
+
+// encodeItem 序列化购物车项为 Hash 的值
+func encodeItem(item Item) string {
+	data, _ := json.Marshal(itemValue{
+		Quantity:   item.Quantity,
+		PriceAtAdd: item.PriceAtAdd,
+		AddedAt:    item.AddedAt.Unix(),
+		Selected:   item.Selected,
+	})
+	return string(data)
+}
+
+func toList(items map[string]Item) []Item {
+	list := make([]Item, 0, len(items))
+	for _, item := range items {
+		list = append(list, item)
+	}
+	return list
+}
+
+// Get 读取用户购物车，Redis 中已过期时从 MySQL 恢复
+func Get(ctx context.Context, userId uint64) ([]Item, error) {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		return nil, err
+	}
+	if err := Rehydrate(ctx, userId); err != nil {
+		return nil, err
+	}
+	hash, err := rdb.HGetAll(ctx, Key(userId)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("读取购物车失败: %w", err)
+	}
+	return toList(parseItems(hash)), nil
+}
+
+// update 在 WATCH 事务中读取购物车、由 fn 修改后写回并记录变更，返回修改后的完整购物车
+//
+// fn 返回需要写入与删除的字段；并发修改导致事务失败时重试
+func update(ctx context.Context, userId uint64, fn func(items map[string]Item) (map[string]Item, []string)) ([]Item, error) {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		return nil, err
+	}
+	// Redis 中购物车已过期时先从 MySQL 恢复，再在其上修改
+	if err := Rehydrate(ctx, userId); err != nil {
+		return nil, err
+	}
+	key := Key(userId)
+
+	var result []Item
+	txf := func(tx *goredis.Tx) error {
+		hash, err := tx.HGetAll(ctx, key).Result()
+		if err != nil {
+			return err
+		}
+		items := parseItems(hash)
+		changed, deleted := fn(items)
+
+		_, err = tx.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+			if len(changed) > 0 {
+				values := make(map[string]interface{}, len(changed))
+				for field, item := range changed {
+					values[field] = encodeItem(item)
+				}
+				pipe.HSet(ctx, key, values)
+			}
+			if len(deleted) > 0 {
+				pipe.HDel(ctx, key, deleted...)
+			}
+			if len(changed) > 0 || len(deleted) > 0 {
+				MarkDirty(ctx, pipe, userId)
+			}
+			pipe.Expire(ctx, key, ExpireTime)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for field, item := range changed {
+			items[field] = item
+		}
+		for _, field := range deleted {
+			delete(items, field)
+		}
+		result = toList(items)
+		return nil
+	}
+
+	for i := 0; i < 3; i++ {
+		err = rdb.Watch(ctx, txf, key)
+		if !errors.Is(err, goredis.TxFailedErr) {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("修改购物车失败: %w", err)
+	}
+	return result, nil
+}
+
+// applyUpsert 将 upsert 应用到已有购物车项（可能不存在）上
+func applyUpsert(existing Item, exists bool, u Upsert, quantity int) Item {
+	if !exists {
+		existing = Item{PlantId: u.PlantId, SkuId: u.SkuId, PriceAtAdd: u.Price, AddedAt: time.Now(), Selected: true}
+	}
+	existing.Quantity = min(quantity, CartCfg.MaxQuantity)
+	if u.Selected != nil {
+		existing.Selected = *u.Selected
+	}
+	return existing
+}
+
+// Apply 增量修改购物车：upserts 中的数量直接覆盖，deletes 中的项移除
+func Apply(ctx context.Context, userId uint64, upserts []Upsert, deletes []ItemKey) ([]Item, error) {
+	return update(ctx, userId, func(items map[string]Item) (map[string]Item, []string) {
+		changed := make(map[string]Item, len(upserts))
+		for _, u := range upserts {
+			field := Field(u.PlantId, u.SkuId)
+			existing, ok := items[field]
+			changed[field] = applyUpsert(existing, ok, u, u.Quantity)
+		}
+		deleted := make([]string, 0, len(deletes))
+		for _, d := range deletes {
+			field := Field(d.PlantId, d.SkuId)
+			delete(changed, field)
+			deleted = append(deleted, field)
+		}
+		return changed, deleted
+	})
+}
+
+// mergeQuantity 按规则合并同一商品的数量
+func mergeQuantity(strategy string, server int, local int) int {
+	switch strategy {
+	case MergeMax:
+		return max(server, local)
+	case MergeSum:
+		return server + local
+	}
+	return server
+}
+
+// Merge 将未登录时的本地购物车合并进服务端购物车，返回合并后的结果
+//
+// 服务端不存在的商品直接加入；两端都存在的按 strategy 处理数量，其余元数据以服务端为准
+func Merge(ctx context.Context, userId uint64, local []Upsert, strategy string) ([]Item, error) {
+	return update(ctx, userId, func(items map[string]Item) (map[string]Item, []string) {
+		changed := make(map[string]Item)
+		for _, u := range local {
+			field := Field(u.PlantId, u.SkuId)
+			existing, ok := items[field]
+			if !ok {
+				changed[field] = applyUpsert(existing, false, u, u.Quantity)
+				continue
+			}
+			quantity := min(mergeQuantity(strategy, existing.Quantity, u.Quantity), CartCfg.MaxQuantity)
+			if quantity != existing.Quantity {
+				existing.Quantity = quantity
+				changed[field] = existing
+			}
+		}
+		return changed, nil
+	})
+}
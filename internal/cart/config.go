@@ -0,0 +1,41 @@
+package cart
+
+import (
+	"log"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+type CartConfig struct {
+	MergeStrategy string        `mapstructure:"merge_strategy"` // 登录合并时的冲突规则：max | sum | server
+	MaxQuantity   int           `mapstructure:"max_quantity"`   // 单个购物车项数量上限
+	FlushInterval time.Duration `mapstructure:"flush_interval"` // 变更购物车刷入 MySQL 的间隔
+}
+
+var CartCfg = CartConfig{
+	MergeStrategy: MergeMax,
+	MaxQuantity:   99,
+	FlushInterval: time.Minute,
+}
+
+func Load() CartConfig {
+	// 配置文件路径和名称
+	viper.SetConfigName("config")   // 配置文件名（无后缀）
+	viper.SetConfigType("yaml")     // 配置文件类型
+	viper.AddConfigPath("./config") // 配置文件所在目录
+
+	// 读取配置文件
+	if err := viper.ReadInConfig(); err != nil {
+		log.Fatalf("读取配置文件失败: %v", err)
+	}
+
+	// 解析配置到结构体（未配置的项保留默认值）
+	if err := viper.UnmarshalKey("cart", &CartCfg); err != nil {
+		log.Fatalf("解析配置失败: %v", err)
+	}
+	if !ValidStrategy(CartCfg.MergeStrategy) {
+		log.Fatalf("购物车合并规则[%s]不支持", CartCfg.MergeStrategy)
+	}
+	return CartCfg
+}
@@ -0,0 +1,164 @@
+package cart
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
+	"github.com/sunzhaoc/plant_be/pkg/db/redis"
+	"github.com/sunzhaoc/plant_be/pkg/money"
+)
+
+// legacySku 旧版购物车规格解析结果
+type legacySku struct {
+	PlantId uint64
+	SkuId   uint64
+	Size    string
+	Price   money.Money
+}
+
+// MigrateLegacy 将 v1 购物车（按规格名称存储）转换为 v2（按 SKU 存储）
+//
+// 规格名称通过 plant_sku 解析为 skuId，已删除或改名的规格无法解析，直接丢弃；
+// v2 中已存在的项以 v2 为准。转换后删除 v1 Key 并记录变更，返回是否进行了转换
+func MigrateLegacy(ctx context.Context, userId uint64) (bool, error) {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		return false, err
+	}
+	legacyKey := fmt.Sprintf("%s%d", legacyKeyPrefix, userId)
+	hash, err := rdb.HGetAll(ctx, legacyKey).Result()
+	if err != nil {
+		return false, fmt.Errorf("读取旧版购物车失败: %w", err)
+	}
+	if len(hash) == 0 {
+		return false, nil
+	}
+
+	type legacyItem struct {
+		plantId  uint64
+		size     string
+		quantity int
+	}
+	legacyItems := make([]legacyItem, 0, len(hash))
+	pairs := make([][]interface{}, 0, len(hash))
+	for field, value := range hash {
+		// 规格名本身可能含冒号，只按第一个冒号切分
+		idStr, size, ok := strings.Cut(field, ":")
+		if !ok {
+			continue
+		}
+		plantId, err := strconv.ParseUint(idStr, 10, 64)
+		if err != nil {
+			continue
+		}
+		quantity, err := strconv.Atoi(value)
+		if err != nil || quantity <= 0 {
+			continue
+		}
+		legacyItems = append(legacyItems, legacyItem{plantId: plantId, size: size, quantity: quantity})
+		pairs = append(pairs, []interface{}{plantId, size})
+	}
+
+	// 按 "<plantId>:<size>" 索引解析出的 SKU
+	skuMap := make(map[string]legacySku)
+	if len(pairs) > 0 {
+		db, err := mysql.GetDB("ali")
+		if err != nil {
+			return false, err
+		}
+		var rows []legacySku
+		err = db.WithContext(ctx).Raw(`SELECT plant_id, id sku_id, size, price FROM plant.plant_sku WHERE (plant_id, size) IN ?`, pairs).
+			Scan(&rows).Error
+		if err != nil {
+			return false, fmt.Errorf("解析旧版购物车规格失败: %w", err)
+		}
+		for _, row := range rows {
+			skuMap[fmt.Sprintf("%d:%s", row.PlantId, row.Size)] = row
+		}
+	}
+
+	now := time.Now()
+	converted := make(map[string]Item, len(legacyItems))
+	for _, li := range legacyItems {
+		sku, ok := skuMap[fmt.Sprintf("%d:%s", li.plantId, li.size)]
+		if !ok {
+			continue
+		}
+		field := Field(li.plantId, sku.SkuId)
+		item := converted[field]
+		item.PlantId, item.SkuId, item.PriceAtAdd, item.AddedAt, item.Selected = li.plantId, sku.SkuId, sku.Price, now, true
+		item.Quantity = min(item.Quantity+li.quantity, CartCfg.MaxQuantity)
+		converted[field] = item
+	}
+
+	key := Key(userId)
+	err = rdb.Watch(ctx, func(tx *goredis.Tx) error {
+		existing, err := tx.HKeys(ctx, key).Result()
+		if err != nil {
+			return err
+		}
+		for _, field := range existing {
+			delete(converted, field)
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+			if len(converted) > 0 {
+				values := make(map[string]interface{}, len(converted))
+				for field, item := range converted {
+					values[field] = encodeItem(item)
+				}
+				pipe.HSet(ctx, key, values)
+				pipe.Expire(ctx, key, ExpireTime)
+			}
+			pipe.Del(ctx, legacyKey)
+			MarkDirty(ctx, pipe, userId)
+			return nil
+		})
+		return err
+	}, key, legacyKey)
+	if errors.Is(err, goredis.TxFailedErr) {
+		// 并发请求已完成转换或修改了购物车，下次读取时重试
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("转换旧版购物车失败: %w", err)
+	}
+	slog.Info("旧版购物车已转换", "uid", userId, "legacyCount", len(hash), "convertedCount", len(converted))
+	return true, nil
+}
+
+// MigrateAllLegacy 扫描并转换全部 v1 购物车，返回转换成功的用户数
+func MigrateAllLegacy(ctx context.Context) (int, error) {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	iter := rdb.Scan(ctx, 0, legacyKeyPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		userId, err := strconv.ParseUint(strings.TrimPrefix(key, legacyKeyPrefix), 10, 64)
+		if err != nil {
+			slog.Warn("解析购物车Key中的用户ID失败", "key", key, "error", err)
+			continue
+		}
+		migrated, err := MigrateLegacy(ctx, userId)
+		if err != nil {
+			slog.Error("转换旧版购物车失败", "uid", userId, "error", err)
+			continue
+		}
+		if migrated {
+			count++
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return count, fmt.Errorf("扫描旧版购物车失败: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,206 @@
+package cart
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"github.com/sunzhaoc/plant_be/pkg/db/redis"
+	"gorm.io/gorm"
+)
+
+// dirtyKey 待持久化的购物车变更集（HASH，field 为用户ID，value 为变更版本号）
+//
+// 每次写购物车时版本号加一；刷盘完成后仅当版本号未变才移除，刷盘期间的新变更留到下一轮
+const dirtyKey = "cart:dirty"
+
+// flushBatchSize 每次 HSCAN 读取的变更数
+const flushBatchSize = 100
+
+// clearDirtyScript 版本号未变时移除变更标记
+var clearDirtyScript = goredis.NewScript(`
+if redis.call('HGET', KEYS[1], ARGV[1]) == ARGV[2] then
+	return redis.call('HDEL', KEYS[1], ARGV[1])
+end
+return 0
+`)
+
+// MarkDirty 在写购物车的同一管道中记录变更，由 StartSyncWorker 异步刷入 MySQL
+func MarkDirty(ctx context.Context, pipe goredis.Pipeliner, userId uint64) {
+	pipe.HIncrBy(ctx, dirtyKey, strconv.FormatUint(userId, 10), 1)
+}
+
+// Rehydrate Redis 中购物车已过期时从 MySQL 恢复
+//
+// 购物车存在未刷盘的变更时不恢复（此时 Redis 为空说明用户已清空购物车），
+// 增量写入前也需调用，避免在空购物车上写入后刷盘覆盖掉持久化的数据
+func Rehydrate(ctx context.Context, userId uint64) error {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		return err
+	}
+	key := Key(userId)
+	exists, err := rdb.Exists(ctx, key).Result()
+	if err != nil || exists > 0 {
+		return err
+	}
+	// 旧版购物车尚未转换时以其为准（比 MySQL 中的数据新）
+	if migrated, err := MigrateLegacy(ctx, userId); err != nil || migrated {
+		return err
+	}
+	dirty, err := rdb.HExists(ctx, dirtyKey, strconv.FormatUint(userId, 10)).Result()
+	if err != nil || dirty {
+		return err
+	}
+
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		return err
+	}
+	var rows []models.CartItem
+	if err := db.WithContext(ctx).Where("user_id = ?", userId).Find(&rows).Error; err != nil {
+		return fmt.Errorf("读取持久化购物车失败: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	values := make(map[string]interface{}, len(rows))
+	for _, row := range rows {
+		values[Field(row.PlantId, row.SkuId)] = encodeItem(Item{
+			PlantId:    row.PlantId,
+			SkuId:      row.SkuId,
+			Quantity:   row.Quantity,
+			PriceAtAdd: row.PriceAtAdd,
+			AddedAt:    row.AddTime,
+			Selected:   row.Selected,
+		})
+	}
+
+	// 并发请求已写入时放弃恢复，以 Redis 为准
+	err = rdb.Watch(ctx, func(tx *goredis.Tx) error {
+		exists, err := tx.Exists(ctx, key).Result()
+		if err != nil || exists > 0 {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+			pipe.HSet(ctx, key, values)
+			pipe.Expire(ctx, key, ExpireTime)
+			return nil
+		})
+		return err
+	}, key)
+	if errors.Is(err, goredis.TxFailedErr) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("恢复购物车失败: %w", err)
+	}
+	slog.Info("购物车已从MySQL恢复", "uid", userId, "count", len(rows))
+	return nil
+}
+
+// StartSyncWorker 启动购物车定时刷盘任务，仅处理变更集中的用户，不扫描全部购物车
+func StartSyncWorker() {
+	ticker := time.NewTicker(CartCfg.FlushInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		flushDirty(context.Background())
+	}
+}
+
+// flushDirty 将变更集中的购物车逐个刷入 MySQL
+func flushDirty(ctx context.Context) {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		slog.Error("获取Redis客户端失败", "error", err)
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		return
+	}
+
+	startTime := time.Now()
+	var cursor uint64
+	successCount, failCount := 0, 0
+	for {
+		var fields []string
+		fields, cursor, err = rdb.HScan(ctx, dirtyKey, cursor, "*", flushBatchSize).Result()
+		if err != nil {
+			slog.Error("读取购物车变更集失败", "error", err)
+			return
+		}
+		// HSCAN 返回 field、value 交替排列
+		for i := 0; i+1 < len(fields); i += 2 {
+			userId, err := strconv.ParseUint(fields[i], 10, 64)
+			if err != nil {
+				rdb.HDel(ctx, dirtyKey, fields[i])
+				continue
+			}
+			if err := flushUser(ctx, rdb, db, userId, fields[i+1]); err != nil {
+				slog.Error("购物车刷盘失败，下一轮重试", "uid", userId, "error", err)
+				failCount++
+				continue
+			}
+			successCount++
+		}
+		if cursor == 0 {
+			break
+		}
+	}
+
+	if successCount > 0 || failCount > 0 {
+		slog.Info("购物车Redis->MySQL刷盘完成",
+			"successUserCount", successCount,
+			"failUserCount", failCount,
+			"costTime", time.Since(startTime).String(),
+		)
+	}
+}
+
+// flushUser 用 Redis 中的购物车整体替换该用户在 MySQL 中的记录
+func flushUser(ctx context.Context, rdb *goredis.Client, db *gorm.DB, userId uint64, version string) error {
+	// 变更来自旧版购物车时先转换，否则会以空的 v2 购物车覆盖 MySQL
+	if _, err := MigrateLegacy(ctx, userId); err != nil {
+		return err
+	}
+	hash, err := rdb.HGetAll(ctx, Key(userId)).Result()
+	if err != nil {
+		return fmt.Errorf("读取购物车失败: %w", err)
+	}
+	items := parseItems(hash)
+
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userId).Delete(&models.CartItem{}).Error; err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		rows := make([]models.CartItem, 0, len(items))
+		for _, item := range items {
+			rows = append(rows, models.CartItem{
+				UserId:     userId,
+				PlantId:    item.PlantId,
+				SkuId:      item.SkuId,
+				Quantity:   item.Quantity,
+				PriceAtAdd: item.PriceAtAdd,
+				Selected:   item.Selected,
+				AddTime:    item.AddedAt,
+			})
+		}
+		return tx.Create(&rows).Error
+	})
+	if err != nil {
+		return fmt.Errorf("写入MySQL失败: %w", err)
+	}
+	return clearDirtyScript.Run(ctx, rdb, []string{dirtyKey}, strconv.FormatUint(userId, 10), version).Err()
+}
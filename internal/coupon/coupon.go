@@ -0,0 +1,180 @@
+package coupon
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"github.com/sunzhaoc/plant_be/pkg/money"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrCouponNotFound  = errors.New("优惠券不存在")
+	ErrNotClaimable    = errors.New("优惠券已停止发放")
+	ErrSoldOut         = errors.New("优惠券已领完")
+	ErrClaimLimit      = errors.New("已达到领取上限")
+	ErrUnavailable     = errors.New("优惠券不可用")
+	ErrThresholdNotMet = errors.New("未达到优惠券使用门槛")
+)
+
+// Owned 用户领取的券及其模板
+type Owned struct {
+	models.UserCoupon
+	Coupon models.Coupon
+}
+
+// Usable 券当前是否在有效期内且未使用
+func (o Owned) Usable(now time.Time) bool {
+	return o.Status == models.UserCouponUnused && !now.Before(o.Coupon.StartTime) && now.Before(o.Coupon.EndTime)
+}
+
+// Discount 按券规则计算优惠金额
+//
+// 商品金额未达门槛返回 ErrThresholdNotMet；商品券优惠不超过商品金额，免运费券优惠等于运费
+func Discount(c models.Coupon, subtotal money.Money, shipping money.Money) (money.Money, error) {
+	if subtotal < c.Threshold {
+		return 0, ErrThresholdNotMet
+	}
+	var discount money.Money
+	switch c.Type {
+	case models.CouponFixed, models.CouponThreshold:
+		discount = c.Amount
+	case models.CouponPercent:
+		discount = subtotal.MulRatio(int64(c.PercentOff), 100)
+		if c.MaxDiscount > 0 && discount > c.MaxDiscount {
+			discount = c.MaxDiscount
+		}
+	case models.CouponFreeShipping:
+		return shipping, nil
+	default:
+		return 0, fmt.Errorf("未知的优惠券类型: %d", c.Type)
+	}
+	if discount > subtotal {
+		discount = subtotal
+	}
+	return discount, nil
+}
+
+// Claim 领取优惠券，锁定券模板行保证发放总量与每人限领
+func Claim(db *gorm.DB, userId uint64, couponId uint64) (*models.UserCoupon, error) {
+	var owned *models.UserCoupon
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var c models.Coupon
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", couponId).Take(&c).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrCouponNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("查询优惠券失败: %w", err)
+		}
+		if !c.IsActive || !time.Now().Before(c.EndTime) {
+			return ErrNotClaimable
+		}
+		if c.TotalQuantity > 0 && c.ClaimedCount >= c.TotalQuantity {
+			return ErrSoldOut
+		}
+		var claimed int64
+		if err := tx.Model(&models.UserCoupon{}).Where("coupon_id = ? AND user_id = ?", couponId, userId).Count(&claimed).Error; err != nil {
+			return fmt.Errorf("查询领取记录失败: %w", err)
+		}
+		if claimed >= int64(c.PerUserLimit) {
+			return ErrClaimLimit
+		}
+
+		owned = &models.UserCoupon{CouponId: couponId, UserId: userId}
+		if err := tx.Create(owned).Error; err != nil {
+			return fmt.Errorf("写入领取记录失败: %w", err)
+		}
+		return tx.Model(&c).UpdateColumn("claimed_count", gorm.Expr("claimed_count + 1")).Error
+	})
+	return owned, err
+}
+
+// ListOwned 查询用户领取的券，按领取时间倒序
+func ListOwned(db *gorm.DB, userId uint64) ([]Owned, error) {
+	var userCoupons []models.UserCoupon
+	if err := db.Where("user_id = ?", userId).Order("id DESC").Find(&userCoupons).Error; err != nil {
+		return nil, fmt.Errorf("查询用户优惠券失败: %w", err)
+	}
+	return attachCoupons(db, userCoupons)
+}
+
+// attachCoupons 补充券模板
+func attachCoupons(db *gorm.DB, userCoupons []models.UserCoupon) ([]Owned, error) {
+	owned := make([]Owned, 0, len(userCoupons))
+	if len(userCoupons) == 0 {
+		return owned, nil
+	}
+	couponIds := make([]uint64, 0, len(userCoupons))
+	for _, uc := range userCoupons {
+		couponIds = append(couponIds, uc.CouponId)
+	}
+	var coupons []models.Coupon
+	if err := db.Where("id IN ?", couponIds).Find(&coupons).Error; err != nil {
+		return nil, fmt.Errorf("查询优惠券失败: %w", err)
+	}
+	couponMap := make(map[uint64]models.Coupon, len(coupons))
+	for _, c := range coupons {
+		couponMap[c.Id] = c
+	}
+	for _, uc := range userCoupons {
+		if c, ok := couponMap[uc.CouponId]; ok {
+			owned = append(owned, Owned{UserCoupon: uc, Coupon: c})
+		}
+	}
+	return owned, nil
+}
+
+// Get 查询用户的某张券，不存在、不属于该用户、已使用或不在有效期内时返回 ErrUnavailable
+//
+// lock 为 true 时锁定该券（tx 须处于事务中），随后应在同一事务内调用 Redeem
+func Get(tx *gorm.DB, userId uint64, userCouponId uint64, lock bool) (*Owned, error) {
+	query := tx
+	if lock {
+		query = tx.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	var uc models.UserCoupon
+	err := query.Where("id = ? AND user_id = ?", userCouponId, userId).Take(&uc).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUnavailable
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询用户优惠券失败: %w", err)
+	}
+	owned, err := attachCoupons(tx, []models.UserCoupon{uc})
+	if err != nil {
+		return nil, err
+	}
+	if len(owned) == 0 || !owned[0].Usable(time.Now()) {
+		return nil, ErrUnavailable
+	}
+	return &owned[0], nil
+}
+
+// Redeem 在下单事务中核销优惠券
+func Redeem(tx *gorm.DB, userCouponId uint64, orderSn string) error {
+	result := tx.Model(&models.UserCoupon{}).
+		Where("id = ? AND status = ?", userCouponId, models.UserCouponUnused).
+		Updates(map[string]interface{}{"status": models.UserCouponUsed, "order_sn": orderSn, "use_time": time.Now()})
+	if result.Error != nil {
+		return fmt.Errorf("核销优惠券失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrUnavailable
+	}
+	return nil
+}
+
+// ReleaseByOrder 订单取消时在事务中退回已核销的券；券已过期时退回后也无法再使用
+func ReleaseByOrder(tx *gorm.DB, orderSn string) error {
+	err := tx.Model(&models.UserCoupon{}).
+		Where("order_sn = ? AND status = ?", orderSn, models.UserCouponUsed).
+		Updates(map[string]interface{}{"status": models.UserCouponUnused, "order_sn": nil, "use_time": nil}).Error
+	if err != nil {
+		return fmt.Errorf("退回优惠券失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package flashsale
+
+import (
+	"log"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+type FlashSaleConfig struct {
+	Workers      int           `mapstructure:"workers"`       // 异步下单并发数，限制秒杀对 MySQL 的压力
+	TicketTTL    time.Duration `mapstructure:"ticket_ttl"`    // 排队凭证保留时间，过期后无法再查询结果
+	RequeueAfter time.Duration `mapstructure:"requeue_after"` // 凭证处理超过该时间仍未完成（如实例崩溃）时重新入队
+}
+
+var FlashSaleCfg = FlashSaleConfig{
+	Workers:      4,
+	TicketTTL:    30 * time.Minute,
+	RequeueAfter: time.Minute,
+}
+
+func Load() FlashSaleConfig {
+	// 配置文件路径和名称
+	viper.SetConfigName("config")   // 配置文件名（无后缀）
+	viper.SetConfigType("yaml")     // 配置文件类型
+	viper.AddConfigPath("./config") // 配置文件所在目录
+
+	// 读取配置文件
+	if err := viper.ReadInConfig(); err != nil {
+		log.Fatalf("读取配置文件失败: %v", err)
+	}
+
+	// 解析配置到结构体（未配置的项保留默认值）
+	if err := viper.UnmarshalKey("flash_sale", &FlashSaleCfg); err != nil {
+		log.Fatalf("解析配置失败: %v", err)
+	}
+	return FlashSaleCfg
+}
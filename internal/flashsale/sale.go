@@ -0,0 +1,304 @@
+package flashsale
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"github.com/sunzhaoc/plant_be/pkg/db/redis"
+	"github.com/sunzhaoc/plant_be/pkg/money"
+	"github.com/sunzhaoc/plant_be/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// Redis Key 约定：
+//
+//	flash:sale:<saleId>     活动准入状态 remain/limit/start/end（HASH，活动结束一天后过期）
+//	flash:bought:<saleId>   每个用户已抢到的数量 uid -> quantity（HASH，与活动状态同时过期）
+//	flash:ticket:<ticketId> 排队凭证（HASH，TTL=TicketTTL）
+//	flash:queue             待下单的凭证（LIST，LPUSH 入队、右侧出队）
+//	flash:processing        处理中的凭证（LIST），实例崩溃后由 requeueStuck 重新入队
+//
+// 准入以 Redis 计数为准：抢到的名额在异步下单失败时退回，订单取消或超时后不退回
+const (
+	saleKeyPrefix   = "flash:sale:"
+	boughtKeyPrefix = "flash:bought:"
+	ticketKeyPrefix = "flash:ticket:"
+	queueKey        = "flash:queue"
+	processingKey   = "flash:processing"
+	saleStateLinger = 24 * time.Hour
+)
+
+// 凭证状态
+const (
+	TicketQueued     = "queued"     // 排队中
+	TicketProcessing = "processing" // 下单中
+	TicketSuccess    = "success"    // 下单成功
+	TicketFailed     = "failed"     // 下单失败，名额已退回
+)
+
+var (
+	ErrSaleNotFound  = errors.New("活动不存在")
+	ErrNotStarted    = errors.New("活动尚未开始")
+	ErrEnded         = errors.New("活动已结束")
+	ErrSoldOut       = errors.New("已抢完")
+	ErrLimitExceeded = errors.New("超出每人限购数量")
+)
+
+// Ticket 排队凭证
+type Ticket struct {
+	Id         string
+	SaleId     uint64
+	UserId     uint64
+	Quantity   uint
+	AddressId  uint64
+	PayChannel string
+	ClientIP   string
+	OrderSn    string // 准入时生成，重复处理时据此识别已创建的订单
+	Status     string
+	Message    string
+	PayAmount  money.Money
+	ExpireTime string
+	Prepay     string // 预支付信息（JSON）
+}
+
+func saleKey(saleId uint64) string {
+	return saleKeyPrefix + strconv.FormatUint(saleId, 10)
+}
+
+func boughtKey(saleId uint64) string {
+	return boughtKeyPrefix + strconv.FormatUint(saleId, 10)
+}
+
+func ticketKey(ticketId string) string {
+	return ticketKeyPrefix + ticketId
+}
+
+// initScript 活动状态不存在时按 MySQL 初始化
+//
+// KEYS: saleKey, boughtKey；ARGV: remain, limit, start, end, ttl, uid_1, qty_1, ...
+var initScript = goredis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'remain', ARGV[1], 'limit', ARGV[2], 'start', ARGV[3], 'end', ARGV[4])
+redis.call('EXPIRE', KEYS[1], ARGV[5])
+redis.call('DEL', KEYS[2])
+for i = 6, #ARGV, 2 do
+	redis.call('HSET', KEYS[2], ARGV[i], ARGV[i + 1])
+end
+if #ARGV > 5 then
+	redis.call('EXPIRE', KEYS[2], ARGV[5])
+end
+return 1
+`)
+
+// admitScript 校验活动时间、剩余名额与限购后扣减名额，写入凭证并入队
+//
+// KEYS: saleKey, boughtKey, ticketKey, queueKey
+// ARGV: uid, quantity, now, ticketId, ticketTTL, field_1, value_1, ...
+// 返回 0 成功；-1 活动状态未初始化；1 未开始；2 已结束；3 已抢完；4 超出限购
+var admitScript = goredis.NewScript(`
+local sale = redis.call('HMGET', KEYS[1], 'remain', 'limit', 'start', 'end')
+if not sale[1] then
+	return -1
+end
+local now = tonumber(ARGV[3])
+if now < tonumber(sale[3]) then
+	return 1
+end
+if now >= tonumber(sale[4]) then
+	return 2
+end
+local qty = tonumber(ARGV[2])
+if tonumber(sale[1]) < qty then
+	return 3
+end
+local bought = tonumber(redis.call('HGET', KEYS[2], ARGV[1]) or '0')
+if bought + qty > tonumber(sale[2]) then
+	return 4
+end
+redis.call('HINCRBY', KEYS[1], 'remain', -qty)
+redis.call('HINCRBY', KEYS[2], ARGV[1], qty)
+redis.call('EXPIRE', KEYS[2], redis.call('TTL', KEYS[1]))
+redis.call('HSET', KEYS[3], unpack(ARGV, 6))
+redis.call('EXPIRE', KEYS[3], ARGV[5])
+redis.call('LPUSH', KEYS[4], ARGV[4])
+return 0
+`)
+
+// refundScript 下单失败时退回名额，活动状态已过期时不处理
+//
+// KEYS: saleKey, boughtKey；ARGV: uid, quantity
+var refundScript = goredis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('HINCRBY', KEYS[1], 'remain', ARGV[2])
+redis.call('HINCRBY', KEYS[2], ARGV[1], -tonumber(ARGV[2]))
+return 1
+`)
+
+// Preload 按 MySQL 初始化活动的 Redis 准入状态，已存在时不覆盖
+//
+// 活动创建后立即调用，避免开抢瞬间大量请求同时回源 MySQL
+func Preload(ctx context.Context, saleId uint64) error {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		return err
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		return err
+	}
+
+	var sale models.FlashSale
+	err = db.WithContext(ctx).Where("id = ?", saleId).Take(&sale).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrSaleNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("查询秒杀活动失败: %w", err)
+	}
+
+	// Redis 状态丢失时，以已落库的活动订单恢复剩余名额与每人已购数量
+	var bought []struct {
+		UserId   uint64
+		Quantity uint
+	}
+	err = db.WithContext(ctx).Model(&models.FlashSaleOrder{}).
+		Select("user_id, SUM(quantity) quantity").
+		Where("sale_id = ?", saleId).
+		Group("user_id").
+		Scan(&bought).Error
+	if err != nil {
+		return fmt.Errorf("查询秒杀活动订单失败: %w", err)
+	}
+	var sold uint
+	args := []interface{}{0, sale.PerUserLimit, sale.StartTime.Unix(), sale.EndTime.Unix(), 0}
+	for _, b := range bought {
+		sold += b.Quantity
+		args = append(args, b.UserId, b.Quantity)
+	}
+	if sold < sale.Quantity {
+		args[0] = sale.Quantity - sold
+	}
+	ttl := time.Until(sale.EndTime.Add(saleStateLinger))
+	if ttl < time.Hour {
+		ttl = time.Hour
+	}
+	args[4] = int64(ttl.Seconds())
+
+	if err := initScript.Run(ctx, rdb, []string{saleKey(saleId), boughtKey(saleId)}, args...).Err(); err != nil {
+		return fmt.Errorf("初始化秒杀活动状态失败: %w", err)
+	}
+	return nil
+}
+
+// Admit 按 Redis 计数准入抢购请求，成功时扣减名额并返回排队凭证，由 StartWorker 异步下单
+func Admit(ctx context.Context, t Ticket) (string, error) {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		return "", err
+	}
+	t.Id = utils.RandomToken(16)
+	t.Status = TicketQueued
+	keys := []string{saleKey(t.SaleId), boughtKey(t.SaleId), ticketKey(t.Id), queueKey}
+	args := []interface{}{t.UserId, t.Quantity, time.Now().Unix(), t.Id, int64(FlashSaleCfg.TicketTTL.Seconds())}
+	args = append(args,
+		"saleId", t.SaleId,
+		"userId", t.UserId,
+		"quantity", t.Quantity,
+		"addressId", t.AddressId,
+		"payChannel", t.PayChannel,
+		"clientIp", t.ClientIP,
+		"orderSn", t.OrderSn,
+		"status", t.Status,
+	)
+
+	for attempt := 0; attempt < 2; attempt++ {
+		code, err := admitScript.Run(ctx, rdb, keys, args...).Int()
+		if err != nil {
+			return "", fmt.Errorf("秒杀准入失败: %w", err)
+		}
+		switch code {
+		case 0:
+			return t.Id, nil
+		case 1:
+			return "", ErrNotStarted
+		case 2:
+			return "", ErrEnded
+		case 3:
+			return "", ErrSoldOut
+		case 4:
+			return "", ErrLimitExceeded
+		}
+		// 活动状态未初始化，从 MySQL 加载后重试
+		if err := Preload(ctx, t.SaleId); err != nil {
+			return "", err
+		}
+	}
+	return "", errors.New("秒杀准入失败: 活动状态初始化后仍不存在")
+}
+
+// Remaining 活动剩余名额，活动状态未初始化时返回 false
+func Remaining(ctx context.Context, saleId uint64) (int64, bool, error) {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		return 0, false, err
+	}
+	remain, err := rdb.HGet(ctx, saleKey(saleId), "remain").Int64()
+	if errors.Is(err, goredis.Nil) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return remain, true, nil
+}
+
+// GetTicket 查询排队凭证，凭证不存在或已过期时返回 nil
+func GetTicket(ctx context.Context, ticketId string) (*Ticket, error) {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		return nil, err
+	}
+	fields, err := rdb.HGetAll(ctx, ticketKey(ticketId)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	t := &Ticket{
+		Id:         ticketId,
+		PayChannel: fields["payChannel"],
+		ClientIP:   fields["clientIp"],
+		OrderSn:    fields["orderSn"],
+		Status:     fields["status"],
+		Message:    fields["message"],
+		ExpireTime: fields["expireTime"],
+		Prepay:     fields["prepay"],
+	}
+	t.SaleId, _ = strconv.ParseUint(fields["saleId"], 10, 64)
+	t.UserId, _ = strconv.ParseUint(fields["userId"], 10, 64)
+	t.AddressId, _ = strconv.ParseUint(fields["addressId"], 10, 64)
+	quantity, _ := strconv.ParseUint(fields["quantity"], 10, 32)
+	t.Quantity = uint(quantity)
+	t.PayAmount, _ = money.Parse(fields["payAmount"])
+	return t, nil
+}
+
+// refund 下单失败后退回名额
+func refund(ctx context.Context, t *Ticket) error {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		return err
+	}
+	return refundScript.Run(ctx, rdb, []string{saleKey(t.SaleId), boughtKey(t.SaleId)}, t.UserId, t.Quantity).Err()
+}
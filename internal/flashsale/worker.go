@@ -0,0 +1,282 @@
+package flashsale
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/sunzhaoc/plant_be/internal/inventory"
+	"github.com/sunzhaoc/plant_be/internal/orders"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"github.com/sunzhaoc/plant_be/pkg/db/redis"
+	"github.com/sunzhaoc/plant_be/pkg/payment"
+	"gorm.io/gorm"
+)
+
+// popTimeout 阻塞出队的等待时间
+const popTimeout = 5 * time.Second
+
+// claimScript 抢占凭证：排队中，或下单中但已超过 RequeueAfter（原处理实例视为已崩溃）
+//
+// KEYS: ticketKey；ARGV: now, staleBefore
+var claimScript = goredis.NewScript(`
+local t = redis.call('HMGET', KEYS[1], 'status', 'startedAt')
+if t[1] == 'queued' or (t[1] == 'processing' and tonumber(t[2] or '0') < tonumber(ARGV[2])) then
+	redis.call('HSET', KEYS[1], 'status', 'processing', 'startedAt', ARGV[1])
+	return 1
+end
+return 0
+`)
+
+// StartWorker 启动秒杀异步下单任务
+//
+// Workers 个协程从队列取凭证下单，下单并发与请求洪峰解耦；
+// 另定期将处理中列表里的滞留凭证重新入队
+func StartWorker() {
+	for i := 0; i < FlashSaleCfg.Workers; i++ {
+		go consume()
+	}
+
+	ticker := time.NewTicker(FlashSaleCfg.RequeueAfter)
+	defer ticker.Stop()
+	for range ticker.C {
+		requeueStuck(context.Background())
+	}
+}
+
+// consume 循环出队并处理凭证
+func consume() {
+	for {
+		ctx := context.Background()
+		rdb, err := redis.GetDb("ali")
+		if err != nil {
+			slog.Error("获取Redis客户端失败", "error", err)
+			time.Sleep(popTimeout)
+			continue
+		}
+		ticketId, err := rdb.BLMove(ctx, queueKey, processingKey, "RIGHT", "LEFT", popTimeout).Result()
+		if errors.Is(err, goredis.Nil) {
+			continue
+		}
+		if err != nil {
+			slog.Error("读取秒杀队列失败", "error", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		process(ctx, ticketId)
+		if err := rdb.LRem(ctx, processingKey, 1, ticketId).Err(); err != nil {
+			slog.Error("移除处理中的秒杀凭证失败", "ticketId", ticketId, "error", err)
+		}
+	}
+}
+
+// requeueStuck 已结束或已过期的凭证移出处理中列表，其余超时未完成的重新入队
+//
+// 重新入队的凭证由 claimScript 保证不会被正在处理的实例之外的实例重复下单
+func requeueStuck(ctx context.Context) {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		slog.Error("获取Redis客户端失败", "error", err)
+		return
+	}
+	ticketIds, err := rdb.LRange(ctx, processingKey, 0, -1).Result()
+	if err != nil {
+		slog.Error("读取处理中的秒杀凭证失败", "error", err)
+		return
+	}
+	staleBefore := time.Now().Add(-FlashSaleCfg.RequeueAfter).Unix()
+	for _, ticketId := range ticketIds {
+		t, err := rdb.HMGet(ctx, ticketKey(ticketId), "status", "startedAt").Result()
+		if err != nil {
+			slog.Error("查询秒杀凭证失败", "ticketId", ticketId, "error", err)
+			continue
+		}
+		status, _ := t[0].(string)
+		startedAt, _ := t[1].(string)
+		switch status {
+		case TicketQueued, TicketProcessing:
+			started, _ := strconv.ParseInt(startedAt, 10, 64)
+			if started == 0 {
+				// 出队后尚未抢占：先记录时间，超时仍未处理再重新入队
+				rdb.HSetNX(ctx, ticketKey(ticketId), "startedAt", time.Now().Unix())
+				continue
+			}
+			if started >= staleBefore {
+				continue
+			}
+			pipe := rdb.TxPipeline()
+			pipe.LRem(ctx, processingKey, 1, ticketId)
+			pipe.RPush(ctx, queueKey, ticketId)
+			if _, err := pipe.Exec(ctx); err != nil {
+				slog.Error("秒杀凭证重新入队失败", "ticketId", ticketId, "error", err)
+				continue
+			}
+			slog.Warn("秒杀凭证处理超时，已重新入队", "ticketId", ticketId)
+		default:
+			if err := rdb.LRem(ctx, processingKey, 1, ticketId).Err(); err != nil {
+				slog.Error("移除处理中的秒杀凭证失败", "ticketId", ticketId, "error", err)
+			}
+		}
+	}
+}
+
+// process 为凭证创建订单并发起支付
+func process(ctx context.Context, ticketId string) {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		slog.Error("获取Redis客户端失败", "error", err)
+		return
+	}
+	now := time.Now()
+	claimed, err := claimScript.Run(ctx, rdb, []string{ticketKey(ticketId)}, now.Unix(), now.Add(-FlashSaleCfg.RequeueAfter).Unix()).Int()
+	if err != nil {
+		slog.Error("抢占秒杀凭证失败", "ticketId", ticketId, "error", err)
+		return
+	}
+	if claimed == 0 {
+		return
+	}
+	t, err := GetTicket(ctx, ticketId)
+	if err != nil || t == nil {
+		slog.Error("查询秒杀凭证失败", "ticketId", ticketId, "error", err)
+		return
+	}
+
+	order, msg, err := placeOrder(ctx, t)
+	if err != nil {
+		// 重新入队的凭证可能与仍在处理的实例并发下单，对方订单已提交时本次的唯一键冲突不算失败，不能退回名额
+		existing, findErr := findOrder(ctx, t.OrderSn)
+		switch {
+		case findErr != nil:
+			slog.Error("秒杀下单失败且无法确认订单是否已创建", "ticketId", ticketId, "orderSn", t.OrderSn, "error", err, "findError", findErr)
+			finish(ctx, t, map[string]interface{}{"status": TicketFailed, "message": "下单结果未知，请在订单列表中查看"})
+			return
+		case existing != nil:
+			slog.Warn("秒杀凭证已由其他实例下单成功", "ticketId", ticketId, "orderSn", t.OrderSn, "error", err)
+			order, err = existing, nil
+		}
+	}
+	if err != nil || msg != "" {
+		if err != nil {
+			slog.Error("秒杀下单失败", "ticketId", ticketId, "orderSn", t.OrderSn, "error", err)
+			msg = "下单失败，请稍后重试"
+		}
+		if err := refund(ctx, t); err != nil {
+			slog.Error("秒杀名额退回失败", "ticketId", ticketId, "saleId", t.SaleId, "error", err)
+		}
+		finish(ctx, t, map[string]interface{}{"status": TicketFailed, "message": msg})
+		return
+	}
+
+	fields := map[string]interface{}{
+		"status":     TicketSuccess,
+		"message":    "下单成功",
+		"payAmount":  order.PayAmount.String(),
+		"expireTime": orders.ExpireAt(order).Format("2006-01-02 15:04:05"),
+	}
+	// 发起支付失败时订单保持待支付，可通过 /api/order/:orderSn/pay 重新发起支付
+	if provider, err := payment.Get(t.PayChannel); err == nil {
+		prepay, err := provider.CreatePrepay(ctx, orders.NewPrepayRequest(order, t.ClientIP))
+		if err != nil {
+			slog.Error("创建预支付单失败", "orderSn", order.OrderSn, "provider", provider.Name(), "error", err)
+			fields["message"] = "下单成功，发起支付失败，请在订单详情中重新支付"
+		} else if data, err := json.Marshal(prepay); err == nil {
+			fields["prepay"] = string(data)
+		}
+	}
+	finish(ctx, t, fields)
+}
+
+// placeOrder 按活动价下单并记录活动订单；msg 非空表示业务原因失败
+func placeOrder(ctx context.Context, t *Ticket) (*models.Orders, string, error) {
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 重复处理（如处理超时后重新入队）时订单可能已创建
+	existing, err := findOrder(ctx, t.OrderSn)
+	if err != nil || existing != nil {
+		return existing, "", err
+	}
+
+	var sale models.FlashSale
+	err = db.WithContext(ctx).Where("id = ?", t.SaleId).Take(&sale).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSaleNotFound.Error(), nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	var address models.UserAddress
+	err = db.WithContext(ctx).Where("id = ? AND user_id = ?", t.AddressId, t.UserId).Take(&address).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, "收货地址不存在", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	order, err := orders.Place(ctx, db, orders.PlaceRequest{
+		OrderSn:    t.OrderSn,
+		UserId:     t.UserId,
+		PayChannel: t.PayChannel,
+		Address:    address,
+		Lines:      []orders.PlaceLine{{SkuId: sale.SkuId, Quantity: t.Quantity, Price: sale.SalePrice}},
+		AfterCreate: func(tx *gorm.DB, order *models.Orders) error {
+			return tx.Create(&models.FlashSaleOrder{
+				SaleId:   sale.Id,
+				UserId:   t.UserId,
+				OrderSn:  order.OrderSn,
+				Quantity: t.Quantity,
+			}).Error
+		},
+	})
+	var invalid *orders.ErrInvalidItem
+	var insufficient *inventory.ErrInsufficientStock
+	switch {
+	case errors.Is(err, orders.ErrInvalidProvince):
+		return nil, err.Error(), nil
+	case errors.As(err, &invalid):
+		return nil, invalid.Reason, nil
+	case errors.As(err, &insufficient):
+		return nil, "库存不足", nil
+	case err != nil:
+		return nil, "", err
+	}
+	return order, "", nil
+}
+
+// findOrder 按订单号查询已创建的订单，不存在时返回 nil
+func findOrder(ctx context.Context, orderSn string) (*models.Orders, error) {
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		return nil, err
+	}
+	var order models.Orders
+	err = db.WithContext(ctx).Where("order_sn = ?", orderSn).Take(&order).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// finish 更新凭证结果
+func finish(ctx context.Context, t *Ticket, fields map[string]interface{}) {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		slog.Error("获取Redis客户端失败", "error", err)
+		return
+	}
+	if err := rdb.HSet(ctx, ticketKey(t.Id), fields).Err(); err != nil {
+		slog.Error("更新秒杀凭证失败", "ticketId", t.Id, "error", err)
+	}
+}
@@ -0,0 +1,394 @@
+package fulfillment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/sunzhaoc/plant_be/internal/orders"
+	"github.com/sunzhaoc/plant_be/pkg/carrier"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrOrderNotShippable  = errors.New("当前订单状态不允许发货")
+	ErrCarrierUnsupported = errors.New("物流公司不存在")
+	ErrDuplicateTracking  = errors.New("运单号已被使用")
+	ErrItemNotFound       = errors.New("订单商品不存在")
+	ErrQuantityExceeded   = errors.New("发货数量超过待发货数量")
+	ErrNothingToShip      = errors.New("订单商品已全部发货")
+	ErrNoShipment         = errors.New("订单尚未创建发货包裹")
+	ErrShipmentNotFound   = errors.New("发货包裹不存在")
+)
+
+// ShipLine 包裹中一个订单项的件数
+type ShipLine struct {
+	OrderItemId uint64
+	Quantity    uint
+}
+
+// ShipRequest 创建发货包裹
+type ShipRequest struct {
+	OrderId    uint64
+	Carrier    string
+	TrackingNo string
+	Lines      []ShipLine // 为空时发出全部待发货商品
+	Remark     string
+}
+
+// Hook 在发货事务内调用（可选），用于写入审计日志等与变更同生共死的记录
+type Hook func(tx *gorm.DB, shipment models.Shipment) error
+
+// Detail 包裹及其内容与轨迹
+type Detail struct {
+	models.Shipment
+	Items  []models.ShipmentItem
+	Events []models.ShipmentEvent // 按轨迹时间倒序
+}
+
+// pending 各订单项的待发货件数：购买件数 - 已发货件数 - 发货前已退款件数
+func pending(tx *gorm.DB, orderId uint64) (map[uint64]uint, error) {
+	var items []models.OrderItem
+	if err := tx.Select("id", "quantity").Where("order_id = ?", orderId).Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("查询订单项失败: %w", err)
+	}
+	type row struct {
+		OrderItemId uint64
+		Quantity    uint
+	}
+	var shipped []row
+	err := tx.Raw(`SELECT si.order_item_id, SUM(si.quantity) quantity FROM plant.shipment_items si
+		JOIN plant.shipments s ON s.id = si.shipment_id
+		WHERE s.order_id = ? GROUP BY si.order_item_id`, orderId).Scan(&shipped).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询已发货件数失败: %w", err)
+	}
+	var refunded []row
+	err = tx.Raw(`SELECT order_item_id, SUM(quantity) quantity FROM plant.after_sales
+		WHERE order_id = ? AND type = ? AND status = ? GROUP BY order_item_id`,
+		orderId, models.AfterSaleRefundOnly, models.AfterSaleCompleted).Scan(&refunded).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询已退款件数失败: %w", err)
+	}
+
+	remaining := make(map[uint64]uint, len(items))
+	for _, item := range items {
+		remaining[item.Id] = item.Quantity
+	}
+	for _, list := range [][]row{shipped, refunded} {
+		for _, r := range list {
+			if left, ok := remaining[r.OrderItemId]; ok {
+				if r.Quantity >= left {
+					remaining[r.OrderItemId] = 0
+				} else {
+					remaining[r.OrderItemId] = left - r.Quantity
+				}
+			}
+		}
+	}
+	return remaining, nil
+}
+
+// Ship 创建发货包裹，订单商品全部发出后订单流转为已发货，部分发货时订单保持待发货
+//
+// 事务提交后向物流公司订阅轨迹推送，订阅失败可通过 Sync 主动拉取
+func Ship(db *gorm.DB, req ShipRequest, op orders.Operator, hook Hook) (*models.Shipment, error) {
+	c, err := carrier.Get(req.Carrier)
+	if err != nil {
+		return nil, ErrCarrierUnsupported
+	}
+	req.TrackingNo = strings.TrimSpace(req.TrackingNo)
+
+	var shipment *models.Shipment
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var order models.Orders
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "order_sn", "order_status").
+			Where("id = ?", req.OrderId).
+			Take(&order).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return orders.ErrOrderNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("查询订单失败: %w", err)
+		}
+		if order.OrderStatus != models.OrderStatusPaid && order.OrderStatus != models.OrderStatusShipped {
+			return fmt.Errorf("%w: %s", ErrOrderNotShippable, order.OrderStatus.Label())
+		}
+
+		var used int64
+		if err := tx.Model(&models.Shipment{}).Where("carrier = ? AND tracking_no = ?", req.Carrier, req.TrackingNo).Count(&used).Error; err != nil {
+			return fmt.Errorf("查询运单号失败: %w", err)
+		}
+		if used > 0 {
+			return ErrDuplicateTracking
+		}
+
+		remaining, err := pending(tx, order.Id)
+		if err != nil {
+			return err
+		}
+		// 未指定包裹内容时发出全部待发货商品；同一订单项多行时合并件数
+		quantities := make(map[uint64]uint)
+		if len(req.Lines) == 0 {
+			for itemId, left := range remaining {
+				if left > 0 {
+					quantities[itemId] = left
+				}
+			}
+			if len(quantities) == 0 {
+				return ErrNothingToShip
+			}
+		}
+		for _, line := range req.Lines {
+			if _, ok := remaining[line.OrderItemId]; !ok {
+				return ErrItemNotFound
+			}
+			quantities[line.OrderItemId] += line.Quantity
+		}
+		for itemId, quantity := range quantities {
+			if quantity == 0 || quantity > remaining[itemId] {
+				return ErrQuantityExceeded
+			}
+		}
+
+		shipment = &models.Shipment{
+			OrderId:    order.Id,
+			OrderSn:    order.OrderSn,
+			Carrier:    req.Carrier,
+			TrackingNo: req.TrackingNo,
+			Status:     string(carrier.StatusAccepted),
+			ShippedAt:  time.Now(),
+			OperatorId: op.Id,
+			Remark:     req.Remark,
+		}
+		if err := tx.Create(shipment).Error; err != nil {
+			return fmt.Errorf("写入发货包裹失败: %w", err)
+		}
+		items := make([]models.ShipmentItem, 0, len(quantities))
+		allShipped := true
+		for itemId, left := range remaining {
+			if quantity, ok := quantities[itemId]; ok {
+				items = append(items, models.ShipmentItem{ShipmentId: shipment.Id, OrderItemId: itemId, Quantity: quantity})
+				left -= quantity
+			}
+			if left > 0 {
+				allShipped = false
+			}
+		}
+		if err := tx.Create(&items).Error; err != nil {
+			return fmt.Errorf("写入包裹内容失败: %w", err)
+		}
+
+		if allShipped && order.OrderStatus == models.OrderStatusPaid {
+			_, err := orders.Transition(tx, orders.Change{
+				OrderId:  order.Id,
+				To:       models.OrderStatusShipped,
+				Operator: op,
+				Remark:   fmt.Sprintf("已发货，%s运单号%s", req.Carrier, req.TrackingNo),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if hook != nil {
+			return hook(tx, *shipment)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.Subscribe(context.Background(), shipment.TrackingNo); err != nil {
+		slog.Error("订阅物流轨迹失败", "carrier", shipment.Carrier, "trackingNo", shipment.TrackingNo, "error", err)
+	}
+	return shipment, nil
+}
+
+// MarkShipped 部分发货后不再发出剩余商品时（如缺货待退款），手动将订单标记为已发货
+func MarkShipped(db *gorm.DB, orderId uint64, op orders.Operator, remark string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Shipment{}).Where("order_id = ?", orderId).Count(&count).Error; err != nil {
+			return fmt.Errorf("查询发货包裹失败: %w", err)
+		}
+		if count == 0 {
+			return ErrNoShipment
+		}
+		if remark == "" {
+			remark = "部分发货，标记为已发货"
+		}
+		_, err := orders.Transition(tx, orders.Change{
+			OrderId:  orderId,
+			To:       models.OrderStatusShipped,
+			Operator: op,
+			Remark:   remark,
+		})
+		return err
+	})
+}
+
+// Ingest 写入物流公司推送或查询到的轨迹，重复的轨迹忽略
+//
+// 包裹签收后记录签收时间；订单处于已发货且全部包裹均已签收时，订单流转为已送达。
+// 未知运单的轨迹记录日志后忽略，避免物流方反复重推
+func Ingest(db *gorm.DB, carrierName string, events []carrier.Event) error {
+	byTracking := make(map[string][]carrier.Event)
+	for _, e := range events {
+		byTracking[e.TrackingNo] = append(byTracking[e.TrackingNo], e)
+	}
+	for trackingNo, list := range byTracking {
+		var shipment models.Shipment
+		err := db.Where("carrier = ? AND tracking_no = ?", carrierName, trackingNo).Take(&shipment).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			slog.Warn("收到未知运单的物流轨迹", "carrier", carrierName, "trackingNo", trackingNo)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("查询发货包裹失败: %w", err)
+		}
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			return ingestShipment(tx, shipment, list)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ingestShipment(tx *gorm.DB, shipment models.Shipment, events []carrier.Event) error {
+	// 锁定包裹，串行化同一运单的并发推送
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", shipment.Id).Take(&shipment).Error; err != nil {
+		return fmt.Errorf("查询发货包裹失败: %w", err)
+	}
+	inserted := false
+	for _, e := range events {
+		event := models.ShipmentEvent{
+			ShipmentId:  shipment.Id,
+			EventTime:   e.Time,
+			Status:      string(e.Status),
+			Location:    e.Location,
+			Description: e.Description,
+		}
+		result := tx.Clauses(clause.Insert{Modifier: "IGNORE"}).Create(&event)
+		if result.Error != nil {
+			return fmt.Errorf("写入物流轨迹失败: %w", result.Error)
+		}
+		if result.RowsAffected > 0 {
+			inserted = true
+		}
+	}
+	if !inserted {
+		return nil
+	}
+
+	// 包裹状态取时间最新的轨迹，推送乱序时不回退
+	var latest models.ShipmentEvent
+	if err := tx.Where("shipment_id = ?", shipment.Id).Order("event_time DESC, id DESC").Take(&latest).Error; err != nil {
+		return fmt.Errorf("查询最新物流轨迹失败: %w", err)
+	}
+	updates := map[string]interface{}{"status": latest.Status}
+	delivered := latest.Status == string(carrier.StatusDelivered)
+	if delivered && shipment.DeliveredAt == nil {
+		updates["delivered_at"] = latest.EventTime
+	}
+	if err := tx.Model(&shipment).Updates(updates).Error; err != nil {
+		return fmt.Errorf("更新发货包裹失败: %w", err)
+	}
+	if !delivered {
+		return nil
+	}
+
+	var order models.Orders
+	if err := tx.Select("id", "order_status").Where("id = ?", shipment.OrderId).Take(&order).Error; err != nil {
+		return fmt.Errorf("查询订单失败: %w", err)
+	}
+	if order.OrderStatus != models.OrderStatusShipped {
+		return nil
+	}
+	var undelivered int64
+	err := tx.Model(&models.Shipment{}).Where("order_id = ? AND delivered_at IS NULL", order.Id).Count(&undelivered).Error
+	if err != nil {
+		return fmt.Errorf("查询未签收包裹失败: %w", err)
+	}
+	if undelivered > 0 {
+		return nil
+	}
+	_, err = orders.Transition(tx, orders.Change{
+		OrderId:  order.Id,
+		To:       models.OrderStatusDelivered,
+		Operator: orders.System,
+		Remark:   "包裹已全部签收",
+	})
+	if errors.Is(err, orders.ErrIllegalTransition) {
+		// 并发请求中订单已被用户确认收货
+		return nil
+	}
+	return err
+}
+
+// Sync 向物流公司主动查询包裹轨迹并写入，用于推送丢失或订阅失败时补偿
+func Sync(ctx context.Context, db *gorm.DB, shipmentId uint64) error {
+	var shipment models.Shipment
+	err := db.Where("id = ?", shipmentId).Take(&shipment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrShipmentNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("查询发货包裹失败: %w", err)
+	}
+	c, err := carrier.Get(shipment.Carrier)
+	if err != nil {
+		return ErrCarrierUnsupported
+	}
+	events, err := c.Track(ctx, shipment.TrackingNo)
+	if err != nil {
+		return fmt.Errorf("查询物流轨迹失败: %w", err)
+	}
+	return Ingest(db, shipment.Carrier, events)
+}
+
+// Shipments 订单的全部包裹，按发货时间排序
+func Shipments(db *gorm.DB, orderId uint64) ([]Detail, error) {
+	var shipments []models.Shipment
+	if err := db.Where("order_id = ?", orderId).Order("id").Find(&shipments).Error; err != nil {
+		return nil, fmt.Errorf("查询发货包裹失败: %w", err)
+	}
+	if len(shipments) == 0 {
+		return []Detail{}, nil
+	}
+	ids := make([]uint64, 0, len(shipments))
+	for _, s := range shipments {
+		ids = append(ids, s.Id)
+	}
+	var items []models.ShipmentItem
+	if err := db.Where("shipment_id IN ?", ids).Order("id").Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("查询包裹内容失败: %w", err)
+	}
+	var events []models.ShipmentEvent
+	if err := db.Where("shipment_id IN ?", ids).Order("event_time DESC, id DESC").Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("查询物流轨迹失败: %w", err)
+	}
+
+	index := make(map[uint64]int, len(shipments))
+	details := make([]Detail, 0, len(shipments))
+	for i, s := range shipments {
+		index[s.Id] = i
+		details = append(details, Detail{Shipment: s, Items: []models.ShipmentItem{}, Events: []models.ShipmentEvent{}})
+	}
+	for _, item := range items {
+		d := &details[index[item.ShipmentId]]
+		d.Items = append(d.Items, item)
+	}
+	for _, e := range events {
+		d := &details[index[e.ShipmentId]]
+		d.Events = append(d.Events, e)
+	}
+	return details, nil
+}
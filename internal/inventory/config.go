@@ -0,0 +1,36 @@
+package inventory
+
+import (
+	"log"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+type InventoryConfig struct {
+	ReserveGrace      time.Duration `mapstructure:"reserve_grace"`      // 预占到期后留给订单取消流程的宽限时间，之后由对账任务清理
+	ReconcileInterval time.Duration `mapstructure:"reconcile_interval"` // Redis 可用库存与 MySQL 对账间隔
+}
+
+var InventoryCfg = InventoryConfig{
+	ReserveGrace:      5 * time.Minute,
+	ReconcileInterval: 10 * time.Minute,
+}
+
+func Load() InventoryConfig {
+	// 配置文件路径和名称
+	viper.SetConfigName("config")   // 配置文件名（无后缀）
+	viper.SetConfigType("yaml")     // 配置文件类型
+	viper.AddConfigPath("./config") // 配置文件所在目录
+
+	// 读取配置文件
+	if err := viper.ReadInConfig(); err != nil {
+		log.Fatalf("读取配置文件失败: %v", err)
+	}
+
+	// 解析配置到结构体（未配置的项保留默认值）
+	if err := viper.UnmarshalKey("inventory", &InventoryCfg); err != nil {
+		log.Fatalf("解析配置失败: %v", err)
+	}
+	return InventoryCfg
+}
@@ -0,0 +1,170 @@
+package inventory
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"github.com/sunzhaoc/plant_be/pkg/db/redis"
+)
+
+// reconcileBatchSize 每次对账读取的 SKU/预占数
+const reconcileBatchSize = 200
+
+// lastDrift 上一轮观察到的偏差，连续两轮相同才修正，避免把下单途中（已预扣、未落库）的预占误判为偏差
+var lastDrift = make(map[uint64]int64)
+
+// StartReconcileWorker 启动库存对账任务
+//
+// 1. 清理到期仍未结算的 Redis 预占：按 MySQL 记录归还或结算
+// 2. 修正 inv:avail 计数与 plant_sku.stock - 预占中数量 之间的偏差
+func StartReconcileWorker() {
+	ticker := time.NewTicker(InventoryCfg.ReconcileInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx := context.Background()
+		cleanupStale(ctx)
+		repairDrift(ctx)
+	}
+}
+
+// cleanupStale 处理过了宽限期仍未结算的预占
+//
+// MySQL 无记录：下单事务失败且未能归还，直接归还；已释放：取消后归还 Redis 失败，补做归还；
+// 已确认：支付后结算 Redis 失败，补做结算；仍在预占中：订单尚未被取消，留给订单超时任务处理
+func cleanupStale(ctx context.Context) {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		slog.Error("获取Redis客户端失败", "error", err)
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		return
+	}
+
+	orderSns, err := rdb.ZRangeByScore(ctx, pendingResvKey, &goredis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(time.Now().Add(-InventoryCfg.ReserveGrace).Unix(), 10),
+		Count: reconcileBatchSize,
+	}).Result()
+	if err != nil {
+		slog.Error("读取未结算库存预占失败", "error", err)
+		return
+	}
+
+	for _, orderSn := range orderSns {
+		var statuses []models.ReservationStatus
+		err := db.Model(&models.StockReservation{}).Where("order_sn = ?", orderSn).Distinct().Pluck("status", &statuses).Error
+		if err != nil {
+			slog.Error("查询库存预占记录失败", "orderSn", orderSn, "error", err)
+			continue
+		}
+		switch {
+		case len(statuses) == 0 || (len(statuses) == 1 && statuses[0] == models.ReservationReleased):
+			err = Release(ctx, orderSn)
+		case len(statuses) == 1 && statuses[0] == models.ReservationConfirmed:
+			err = Settle(ctx, orderSn)
+		default:
+			continue
+		}
+		if err != nil {
+			slog.Error("清理未结算库存预占失败", "orderSn", orderSn, "error", err)
+			continue
+		}
+		slog.Warn("已清理未结算的库存预占", "orderSn", orderSn, "statuses", statuses)
+	}
+}
+
+// repairDrift 对比 Redis 可用库存与 MySQL，偏差连续两轮一致时修正
+func repairDrift(ctx context.Context) {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		slog.Error("获取Redis客户端失败", "error", err)
+		return
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		return
+	}
+
+	type skuStock struct {
+		Id       uint64
+		Stock    int64
+		Reserved int64
+	}
+	var lastId uint64
+	seen := make(map[uint64]struct{})
+	for {
+		var rows []skuStock
+		err := db.Raw(`SELECT s.id, s.stock, COALESCE(SUM(r.quantity), 0) reserved
+			FROM plant.plant_sku s
+			LEFT JOIN plant.stock_reservations r ON r.sku_id = s.id AND r.status = ?
+			WHERE s.id > ?
+			GROUP BY s.id
+			ORDER BY s.id
+			LIMIT ?`, models.ReservationReserved, lastId, reconcileBatchSize).Scan(&rows).Error
+		if err != nil {
+			slog.Error("查询SKU库存失败", "error", err)
+			return
+		}
+		if len(rows) == 0 {
+			break
+		}
+		lastId = rows[len(rows)-1].Id
+
+		keys := make([]string, 0, len(rows))
+		for _, row := range rows {
+			keys = append(keys, availKey(row.Id))
+		}
+		values, err := rdb.MGet(ctx, keys...).Result()
+		if err != nil {
+			slog.Error("读取可用库存计数失败", "error", err)
+			return
+		}
+		for i, row := range rows {
+			seen[row.Id] = struct{}{}
+			if values[i] == nil {
+				// 计数未初始化，下单时按需加载
+				delete(lastDrift, row.Id)
+				continue
+			}
+			avail, err := strconv.ParseInt(values[i].(string), 10, 64)
+			if err != nil {
+				continue
+			}
+			drift := row.Stock - row.Reserved - avail
+			if drift == 0 {
+				delete(lastDrift, row.Id)
+				continue
+			}
+			if lastDrift[row.Id] != drift {
+				lastDrift[row.Id] = drift
+				continue
+			}
+			repair(ctx, row.Id, drift)
+			delete(lastDrift, row.Id)
+		}
+	}
+	for skuId := range lastDrift {
+		if _, ok := seen[skuId]; !ok {
+			delete(lastDrift, skuId)
+		}
+	}
+}
+
+// repair 按偏差修正可用库存计数
+func repair(ctx context.Context, skuId uint64, drift int64) {
+	if err := AdjustAvailable(ctx, skuId, drift); err != nil {
+		slog.Error("修正可用库存失败", "skuId", skuId, "drift", drift, "error", err)
+		return
+	}
+	slog.Warn("可用库存与MySQL不一致，已修正", "skuId", skuId, "drift", drift)
+}
@@ -0,0 +1,263 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
+	"github.com/sunzhaoc/plant_be/pkg/db/redis"
+)
+
+// Redis Key 约定：
+//
+//	inv:avail:<skuId>     SKU 可用库存 = plant_sku.stock - 预占中的数量（STRING，不过期）
+//	inv:resv:<orderSn>    订单预占明细 skuId -> quantity（HASH，TTL=支付时限+宽限期），存在即表示 Redis 侧尚未结算
+//	inv:resv:pending      未结算的预占（ZSET，member 为订单号，score 为到期时间戳），供对账任务清理
+const (
+	availKeyPrefix   = "inv:avail:"
+	resvKeyPrefix    = "inv:resv:"
+	pendingResvKey   = "inv:resv:pending"
+	maxReserveRetry  = 3
+	availInitTimeout = 3 * time.Second
+)
+
+// ErrInsufficientStock 可用库存不足
+type ErrInsufficientStock struct {
+	SkuId uint64
+}
+
+func (e *ErrInsufficientStock) Error() string {
+	return fmt.Sprintf("SKU[%d]库存不足", e.SkuId)
+}
+
+// Line 预占明细
+type Line struct {
+	SkuId    uint64
+	Quantity uint
+}
+
+func availKey(skuId uint64) string {
+	return availKeyPrefix + strconv.FormatUint(skuId, 10)
+}
+
+func resvKey(orderSn string) string {
+	return resvKeyPrefix + orderSn
+}
+
+// reserveScript 检查全部 SKU 可用库存后一次性扣减并记录预占明细
+//
+// KEYS: avail_1..avail_n, resvKey, pendingKey
+// ARGV: qty_1..qty_n, sku_1..sku_n, ttl, expireAt, orderSn
+// 返回 0 成功；-i 表示第 i 个 SKU 计数未初始化；i 表示第 i 个 SKU 库存不足
+var reserveScript = goredis.NewScript(`
+local n = #KEYS - 2
+if redis.call('EXISTS', KEYS[n + 1]) == 1 then
+	return 0
+end
+for i = 1, n do
+	local avail = redis.call('GET', KEYS[i])
+	if not avail then
+		return -i
+	end
+	if tonumber(avail) < tonumber(ARGV[i]) then
+		return i
+	end
+end
+for i = 1, n do
+	redis.call('DECRBY', KEYS[i], ARGV[i])
+	redis.call('HSET', KEYS[n + 1], ARGV[n + i], ARGV[i])
+end
+redis.call('EXPIRE', KEYS[n + 1], ARGV[2 * n + 1])
+redis.call('ZADD', KEYS[n + 2], ARGV[2 * n + 2], ARGV[2 * n + 3])
+return 0
+`)
+
+// releaseScript 将订单预占的数量加回可用库存，预占明细不存在时（已结算）不做任何事
+//
+// KEYS: resvKey, pendingKey；ARGV: orderSn, availKeyPrefix
+var releaseScript = goredis.NewScript(`
+local items = redis.call('HGETALL', KEYS[1])
+for i = 1, #items, 2 do
+	redis.call('INCRBY', ARGV[2] .. items[i], items[i + 1])
+end
+redis.call('DEL', KEYS[1])
+redis.call('ZREM', KEYS[2], ARGV[1])
+return #items / 2
+`)
+
+// adjustScript 仅在计数存在时调整，避免凭空创建错误的计数
+var adjustScript = goredis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return redis.call('INCRBY', KEYS[1], ARGV[1])
+end
+return 0
+`)
+
+// mergeLines 合并同一 SKU 的多行，并按 SKU 排序
+func mergeLines(lines []Line) []Line {
+	quantities := make(map[uint64]uint, len(lines))
+	for _, line := range lines {
+		quantities[line.SkuId] += line.Quantity
+	}
+	merged := make([]Line, 0, len(quantities))
+	for skuId, quantity := range quantities {
+		merged = append(merged, Line{SkuId: skuId, Quantity: quantity})
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].SkuId < merged[j].SkuId })
+	return merged
+}
+
+// Reserve 在 Redis 中原子预扣订单所需库存，任一 SKU 不足时全部不扣减并返回 *ErrInsufficientStock
+//
+// 预扣成功后调用方需在下单事务中调用 RecordReservation 落库；事务失败时调用 Release 归还。
+// 同一订单号重复调用不会重复扣减
+func Reserve(ctx context.Context, orderSn string, lines []Line, ttl time.Duration) error {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		return err
+	}
+	lines = mergeLines(lines)
+	n := len(lines)
+	keys := make([]string, 0, n+2)
+	args := make([]interface{}, 2*n, 2*n+3)
+	for i, line := range lines {
+		keys = append(keys, availKey(line.SkuId))
+		args[i] = line.Quantity
+		args[n+i] = line.SkuId
+	}
+	keys = append(keys, resvKey(orderSn), pendingResvKey)
+	expireAt := time.Now().Add(ttl)
+	args = append(args, int64((ttl + InventoryCfg.ReserveGrace).Seconds()), expireAt.Unix(), orderSn)
+
+	for attempt := 0; attempt < maxReserveRetry; attempt++ {
+		code, err := reserveScript.Run(ctx, rdb, keys, args...).Int()
+		if err != nil {
+			return fmt.Errorf("预占库存失败: %w", err)
+		}
+		switch {
+		case code == 0:
+			return nil
+		case code > 0:
+			return &ErrInsufficientStock{SkuId: lines[code-1].SkuId}
+		default:
+			// 计数未初始化，从 MySQL 加载后重试
+			if err := initAvailable(ctx, lines[-code-1].SkuId); err != nil {
+				return err
+			}
+		}
+	}
+	return errors.New("预占库存失败: 可用库存初始化后仍不存在")
+}
+
+// initAvailable 按 MySQL 初始化 SKU 可用库存计数，已存在时不覆盖
+//
+// 计数不存在时不可能有该 SKU 的 Redis 预占，因此 stock 减去预占中的数量即为准确值
+func initAvailable(ctx context.Context, skuId uint64) error {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		return err
+	}
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		return err
+	}
+	queryCtx, cancel := context.WithTimeout(ctx, availInitTimeout)
+	defer cancel()
+
+	var row struct {
+		Stock    int64
+		Reserved int64
+	}
+	err = db.WithContext(queryCtx).Raw(`SELECT s.stock,
+			COALESCE((SELECT SUM(r.quantity) FROM plant.stock_reservations r WHERE r.sku_id = s.id AND r.status = 0), 0) reserved
+		FROM plant.plant_sku s WHERE s.id = ?`, skuId).Scan(&row).Error
+	if err != nil {
+		return fmt.Errorf("查询SKU[%d]库存失败: %w", skuId, err)
+	}
+	// SKU 不存在时初始化为 0，由调用方按库存不足处理
+	return rdb.SetNX(ctx, availKey(skuId), row.Stock-row.Reserved, 0).Err()
+}
+
+// Release 归还订单在 Redis 中的预占，重复调用或已结算时不做任何事
+func Release(ctx context.Context, orderSn string) error {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		return err
+	}
+	if err := releaseScript.Run(ctx, rdb, []string{resvKey(orderSn), pendingResvKey}, orderSn, availKeyPrefix).Err(); err != nil {
+		return fmt.Errorf("释放预占库存失败: %w", err)
+	}
+	return nil
+}
+
+// Settle 支付确认后结算 Redis 侧预占：可用库存已在预占时扣除，只需删除明细
+func Settle(ctx context.Context, orderSn string) error {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		return err
+	}
+	pipe := rdb.TxPipeline()
+	pipe.Del(ctx, resvKey(orderSn))
+	pipe.ZRem(ctx, pendingResvKey, orderSn)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("结算预占库存失败: %w", err)
+	}
+	return nil
+}
+
+// AdjustAvailable 库存被直接修改（如后台调整）后同步可用库存计数，计数未初始化时不处理
+func AdjustAvailable(ctx context.Context, skuId uint64, delta int64) error {
+	if delta == 0 {
+		return nil
+	}
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		return err
+	}
+	return adjustScript.Run(ctx, rdb, []string{availKey(skuId)}, delta).Err()
+}
+
+// Available 查询 SKU 当前可用库存（不预占），计数未初始化时从 MySQL 加载
+func Available(ctx context.Context, skuIds []uint64) (map[uint64]int64, error) {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		return nil, err
+	}
+	available := make(map[uint64]int64, len(skuIds))
+	if len(skuIds) == 0 {
+		return available, nil
+	}
+	keys := make([]string, 0, len(skuIds))
+	for _, skuId := range skuIds {
+		keys = append(keys, availKey(skuId))
+	}
+	values, err := rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("查询可用库存失败: %w", err)
+	}
+	for i, skuId := range skuIds {
+		if values[i] == nil {
+			if err := initAvailable(ctx, skuId); err != nil {
+				return nil, err
+			}
+			n, err := rdb.Get(ctx, availKey(skuId)).Int64()
+			if err != nil {
+				return nil, fmt.Errorf("查询SKU[%d]可用库存失败: %w", skuId, err)
+			}
+			available[skuId] = n
+			continue
+		}
+		s, _ := values[i].(string)
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("解析SKU[%d]可用库存失败: %w", skuId, err)
+		}
+		available[skuId] = n
+	}
+	return available, nil
+}
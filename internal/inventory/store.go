@@ -0,0 +1,112 @@
+package inventory
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RecordReservation 在下单事务中落库预占记录
+func RecordReservation(tx *gorm.DB, orderSn string, lines []Line, expireAt time.Time) error {
+	lines = mergeLines(lines)
+	rows := make([]models.StockReservation, 0, len(lines))
+	for _, line := range lines {
+		rows = append(rows, models.StockReservation{
+			OrderSn:    orderSn,
+			SkuId:      line.SkuId,
+			Quantity:   line.Quantity,
+			Status:     models.ReservationReserved,
+			ExpireTime: expireAt,
+		})
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return fmt.Errorf("写入库存预占记录失败: %w", err)
+	}
+	return nil
+}
+
+// Reserved SKU 被待支付订单预占的数量，调用方需先锁定 SKU 行
+func Reserved(tx *gorm.DB, skuId uint64) (int64, error) {
+	var reserved int64
+	err := tx.Model(&models.StockReservation{}).
+		Where("sku_id = ? AND status = ?", skuId, models.ReservationReserved).
+		Select("COALESCE(SUM(quantity), 0)").Scan(&reserved).Error
+	if err != nil {
+		return 0, fmt.Errorf("查询SKU[%d]预占数量失败: %w", skuId, err)
+	}
+	return reserved, nil
+}
+
+// lockReservations 锁定订单的预占记录
+func lockReservations(tx *gorm.DB, orderSn string) ([]models.StockReservation, error) {
+	var rows []models.StockReservation
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_sn = ?", orderSn).Find(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询库存预占记录失败: %w", err)
+	}
+	return rows, nil
+}
+
+// Confirm 支付成功后在事务中确认预占：扣减 plant_sku.stock 并标记为已确认，库存不足时记录超卖异常
+//
+// 返回订单是否有预占记录；没有时为预占机制上线前创建的订单，库存已在下单时扣减。
+// 事务提交后调用 Settle 结算 Redis 侧
+func Confirm(tx *gorm.DB, orderSn string) (bool, error) {
+	rows, err := lockReservations(tx, orderSn)
+	if err != nil || len(rows) == 0 {
+		return false, err
+	}
+	for _, row := range rows {
+		if row.Status != models.ReservationReserved {
+			continue
+		}
+		// 后台扣减库存时已排除预占数量，正常不会不足；一旦出现按超卖记录订单异常，库存扣至 0 由人工处理
+		result := tx.Exec("UPDATE plant.plant_sku SET stock = stock - ? WHERE id = ? AND stock >= ?", row.Quantity, row.SkuId, row.Quantity)
+		if result.Error != nil {
+			return true, fmt.Errorf("扣减SKU[%d]库存失败: %w", row.SkuId, result.Error)
+		}
+		if result.RowsAffected == 0 {
+			slog.Error("确认预占时库存不足，订单超卖", "orderSn", orderSn, "skuId", row.SkuId, "quantity", row.Quantity)
+			if err := tx.Exec("UPDATE plant.plant_sku SET stock = 0 WHERE id = ?", row.SkuId).Error; err != nil {
+				return true, fmt.Errorf("扣减SKU[%d]库存失败: %w", row.SkuId, err)
+			}
+			exception := models.OrderException{
+				OrderSn: orderSn,
+				Kind:    models.ExceptionOversold,
+				Detail:  fmt.Sprintf("SKU[%d]库存不足，支付确认时需扣减%d件", row.SkuId, row.Quantity),
+			}
+			if err := tx.Create(&exception).Error; err != nil {
+				return true, fmt.Errorf("记录订单异常失败: %w", err)
+			}
+		}
+	}
+	err = tx.Model(&models.StockReservation{}).
+		Where("order_sn = ? AND status = ?", orderSn, models.ReservationReserved).
+		Update("status", models.ReservationConfirmed).Error
+	if err != nil {
+		return true, fmt.Errorf("确认库存预占失败: %w", err)
+	}
+	return true, nil
+}
+
+// ReleaseReservation 订单取消时在事务中释放预占，plant_sku.stock 不变
+//
+// 返回订单是否有预占记录；没有时为预占机制上线前创建的订单，需由调用方归还下单时扣减的库存。
+// 事务提交后调用 Release 归还 Redis 侧
+func ReleaseReservation(tx *gorm.DB, orderSn string) (bool, error) {
+	rows, err := lockReservations(tx, orderSn)
+	if err != nil || len(rows) == 0 {
+		return false, err
+	}
+	err = tx.Model(&models.StockReservation{}).
+		Where("order_sn = ? AND status = ?", orderSn, models.ReservationReserved).
+		Update("status", models.ReservationReleased).Error
+	if err != nil {
+		return true, fmt.Errorf("释放库存预占失败: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/sunzhaoc/plant_be/pkg/db/redis"
+)
+
+// IdempotencyHeader 客户端为每次业务操作生成的唯一键（如 UUID），重试时携带同一个值
+const IdempotencyHeader = "Idempotency-Key"
+
+var (
+	idempotencyKeyPrefix = "idem:"
+	idempotencyTTL       = 24 * time.Hour   // 已完成请求的响应保留时间
+	idempotencyLockTTL   = 60 * time.Second // 处理中标记的最长保留时间，防止实例崩溃后永久 409
+	maxIdempotencyKeyLen = 64
+)
+
+// idempotencyCommittedKey gin.Context 中标记请求已产生持久副作用的键
+const idempotencyCommittedKey = "idempotencyCommitted"
+
+// MarkIdempotencyCommitted 标记请求已产生持久副作用（如订单已写入），之后即使返回 5xx 也保存响应，
+// 同一个键重试时重放该响应而不是再次执行
+func MarkIdempotencyCommitted(c *gin.Context) {
+	c.Set(idempotencyCommittedKey, true)
+}
+
+// idempotencyRecord Redis 中保存的请求状态与首次响应
+type idempotencyRecord struct {
+	Pending     bool   `json:"pending,omitempty"`
+	RequestHash string `json:"requestHash"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// responseRecorder 在写出响应的同时保留一份副本
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// IdempotencyMiddleware 按 (用户, 路由, Idempotency-Key) 去重写操作
+//
+// 首个请求的响应保存 idempotencyTTL，期间的重试直接重放（响应头 Idempotent-Replayed: true）；
+// 首个请求仍在处理时返回 409；同一个键携带不同请求体时返回 422。
+// 5xx 响应不保存，客户端可用同一个键重试；处理器已调用 MarkIdempotencyCommitted 时照常保存。
+// 未携带请求头时不做处理。
+// 需放在 JWTAuthMiddleware 之后；未登录接口按客户端IP区分
+func IdempotencyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"success": false, "message": "Idempotency-Key 过长"})
+			return
+		}
+		rdb, err := redis.GetDb("ali")
+		if err != nil {
+			// Redis 不可用时放行，退化为无幂等保护
+			slog.Error("获取Redis客户端失败", "error", err)
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"success": false, "message": "读取请求失败"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		bodySum := sha256.Sum256(body)
+		requestHash := hex.EncodeToString(bodySum[:])
+
+		ctx := c.Request.Context()
+		redisKey := idempotencyRedisKey(c, key)
+		pending, _ := json.Marshal(idempotencyRecord{Pending: true, RequestHash: requestHash})
+		acquired, err := rdb.SetNX(ctx, redisKey, pending, idempotencyLockTTL).Result()
+		if err != nil {
+			slog.Error("写入幂等键失败", "key", redisKey, "error", err)
+			c.Next()
+			return
+		}
+		if !acquired {
+			replayIdempotent(c, rdb, redisKey, requestHash)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// 请求已结束，使用独立的 context 保存结果
+		saveCtx := context.Background()
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError && !c.GetBool(idempotencyCommittedKey) {
+			if err := rdb.Del(saveCtx, redisKey).Err(); err != nil {
+				slog.Error("删除幂等键失败", "key", redisKey, "error", err)
+			}
+			return
+		}
+		record, _ := json.Marshal(idempotencyRecord{
+			RequestHash: requestHash,
+			Status:      status,
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		})
+		if err := rdb.Set(saveCtx, redisKey, record, idempotencyTTL).Err(); err != nil {
+			slog.Error("保存幂等响应失败", "key", redisKey, "error", err)
+		}
+	}
+}
+
+// idempotencyRedisKey 用户（未登录时为客户端IP）、方法、路由与客户端键共同决定存储位置
+func idempotencyRedisKey(c *gin.Context, key string) string {
+	owner := "ip:" + c.ClientIP()
+	if uid, ok := c.Get("userId"); ok {
+		if v, ok := uid.(uint); ok {
+			owner = "u:" + strconv.FormatUint(uint64(v), 10)
+		}
+	}
+	sum := sha256.Sum256([]byte(owner + "|" + c.Request.Method + " " + c.FullPath() + "|" + key))
+	return idempotencyKeyPrefix + hex.EncodeToString(sum[:])
+}
+
+// replayIdempotent 处理重复请求：处理中返回 409，已完成则重放首次响应
+func replayIdempotent(c *gin.Context, rdb *goredis.Client, redisKey string, requestHash string) {
+	data, err := rdb.Get(c.Request.Context(), redisKey).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		// 首个请求恰好失败并删除了键，按处理中返回，客户端稍后重试
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"success": false, "message": "请求正在处理中，请稍后重试"})
+		return
+	}
+	var record idempotencyRecord
+	if err == nil {
+		err = json.Unmarshal(data, &record)
+	}
+	if err != nil {
+		slog.Error("读取幂等响应失败", "key", redisKey, "error", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"success": false, "message": "服务器内部错误"})
+		return
+	}
+	if record.RequestHash != requestHash {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"success": false, "message": "Idempotency-Key 已用于其他请求"})
+		return
+	}
+	if record.Pending {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"success": false, "message": "请求正在处理中，请稍后重试"})
+		return
+	}
+	c.Header("Idempotent-Replayed", "true")
+	c.Data(record.Status, record.ContentType, record.Body)
+	c.Abort()
+}
@@ -1,10 +1,11 @@
 package middleware
 
 import (
+	"log/slog"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/sunzhaoc/plant_be/internal/auth"
 	"github.com/sunzhaoc/plant_be/pkg/utils"
 )
 
@@ -28,10 +29,8 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 		}
 
 		// 解析Token
-		token, err := jwt.ParseWithClaims(tokenStr, &utils.Claims{}, func(token *jwt.Token) (interface{}, error) {
-			return utils.GetJWTSecretKey(), nil
-		})
-		if err != nil || !token.Valid {
+		claims, err := utils.ParseToken(tokenStr, false)
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"success": false,
 				"message": "Token无效或已过期",
@@ -40,17 +39,30 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		if claims, ok := token.Claims.(*utils.Claims); ok {
-			c.Set("userId", claims.UserID)
-			c.Set("username", claims.Username)
-			c.Next()
-		} else {
+		// 校验Token是否已被吊销（退出登录/退出所有设备）
+		revoked, err := auth.IsRevoked(c.Request.Context(), claims)
+		if err != nil {
+			slog.Error("校验Token吊销状态失败", "uid", claims.UserID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "服务器内部错误",
+			})
+			c.Abort()
+			return
+		}
+		if revoked {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"success": false,
-				"message": "Token解析失败",
+				"message": "Token已失效，请重新登录",
 			})
 			c.Abort()
-
+			return
 		}
+
+		c.Set("userId", claims.UserID)
+		c.Set("username", claims.Username)
+		c.Set("roles", claims.Roles)
+		c.Set("permissions", claims.Permissions)
+		c.Next()
 	}
 }
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole 要求用户至少拥有其中一个角色，需挂载在 JWTAuthMiddleware 之后
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		owned := c.GetStringSlice("roles")
+		for _, role := range roles {
+			if slices.Contains(owned, role) {
+				c.Next()
+				return
+			}
+		}
+		abortForbidden(c)
+	}
+}
+
+// RequirePermission 要求用户拥有全部列出的权限，需挂载在 JWTAuthMiddleware 之后
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		owned := c.GetStringSlice("permissions")
+		for _, permission := range permissions {
+			if !slices.Contains(owned, permission) {
+				abortForbidden(c)
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+func abortForbidden(c *gin.Context) {
+	c.JSON(http.StatusForbidden, gin.H{
+		"success": false,
+		"message": "无权限访问",
+	})
+	c.Abort()
+}
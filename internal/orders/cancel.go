@@ -0,0 +1,80 @@
+package orders
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/sunzhaoc/plant_be/internal/coupon"
+	"github.com/sunzhaoc/plant_be/internal/inventory"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"gorm.io/gorm"
+)
+
+// Cancel 取消待支付订单并释放库存
+//
+// 状态流转与库存释放在同一事务内完成，订单不处于待支付时返回 ErrIllegalTransition，库存不会被重复释放。
+// 事务提交后归还 Redis 侧的预占，因此 db 不应处于外层事务中；归还失败由库存对账任务补偿
+func Cancel(db *gorm.DB, orderId uint64, op Operator, remark string) error {
+	return CancelWith(db, orderId, op, remark, nil)
+}
+
+// CancelWith 同 Cancel，hook（可选）在取消事务内调用，用于写入审计日志等与取消同生共死的记录
+func CancelWith(db *gorm.DB, orderId uint64, op Operator, remark string, hook func(tx *gorm.DB) error) error {
+	var orderSn string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if _, err := Transition(tx, Change{
+			OrderId:  orderId,
+			To:       models.OrderStatusCancelled,
+			Operator: op,
+			Remark:   remark,
+		}); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Orders{}).Where("id = ?", orderId).Pluck("order_sn", &orderSn).Error; err != nil {
+			return fmt.Errorf("查询订单号失败: %w", err)
+		}
+		if err := coupon.ReleaseByOrder(tx, orderSn); err != nil {
+			return err
+		}
+		reserved, err := inventory.ReleaseReservation(tx, orderSn)
+		if err != nil {
+			return err
+		}
+		// 预占机制上线前创建的订单在下单时已扣减库存
+		if !reserved {
+			if err := restoreStock(tx, orderId); err != nil {
+				return err
+			}
+		}
+		if hook != nil {
+			return hook(tx)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := inventory.Release(context.Background(), orderSn); err != nil {
+		slog.Error("订单取消后归还预占库存失败", "orderSn", orderSn, "error", err)
+	}
+	return nil
+}
+
+// restoreStock 按订单项归还 plant_sku 库存
+func restoreStock(tx *gorm.DB, orderId uint64) error {
+	var items []models.OrderItem
+	if err := tx.Select("sku_id", "quantity").Where("order_id = ?", orderId).Find(&items).Error; err != nil {
+		return fmt.Errorf("查询订单项失败: %w", err)
+	}
+	for _, item := range items {
+		if err := tx.Exec("UPDATE plant.plant_sku SET stock = stock + ? WHERE id = ?", item.Quantity, item.SkuId).Error; err != nil {
+			return fmt.Errorf("归还SKU[%d]库存失败: %w", item.SkuId, err)
+		}
+		// 归还的库存同步到可用库存计数
+		if err := inventory.AdjustAvailable(context.Background(), item.SkuId, int64(item.Quantity)); err != nil {
+			slog.Error("同步可用库存失败", "skuId", item.SkuId, "error", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package orders
+
+import (
+	"log"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/sunzhaoc/plant_be/pkg/utils"
+)
+
+type OrderConfig struct {
+	PayTimeout    time.Duration `mapstructure:"pay_timeout"`    // 待支付订单的支付时限
+	ScanInterval  time.Duration `mapstructure:"scan_interval"`  // 过期队列轮询间隔
+	SweepInterval time.Duration `mapstructure:"sweep_interval"` // 数据库兜底扫描间隔
+	QuoteTTL      time.Duration `mapstructure:"quote_ttl"`      // 结算预览报价的有效期
+	QuoteSecret   string        `mapstructure:"-"`              // 报价签名密钥，取自环境变量 ORDER_QUOTE_SECRET
+}
+
+var OrderCfg = OrderConfig{
+	PayTimeout:    30 * time.Minute,
+	ScanInterval:  5 * time.Second,
+	SweepInterval: 5 * time.Minute,
+	QuoteTTL:      10 * time.Minute,
+}
+
+func Load() OrderConfig {
+	// 配置文件路径和名称
+	viper.SetConfigName("config")   // 配置文件名（无后缀）
+	viper.SetConfigType("yaml")     // 配置文件类型
+	viper.AddConfigPath("./config") // 配置文件所在目录
+
+	// 读取配置文件
+	if err := viper.ReadInConfig(); err != nil {
+		log.Fatalf("读取配置文件失败: %v", err)
+	}
+
+	// 解析配置到结构体（未配置的项保留默认值）
+	if err := viper.UnmarshalKey("order", &OrderCfg); err != nil {
+		log.Fatalf("解析配置失败: %v", err)
+	}
+
+	// 报价令牌决定下单金额校验，密钥不与其他功能共用
+	OrderCfg.QuoteSecret = os.Getenv("ORDER_QUOTE_SECRET")
+	if OrderCfg.QuoteSecret == "" {
+		if utils.IsProduction() {
+			log.Fatal("生产环境必须设置环境变量 ORDER_QUOTE_SECRET")
+		}
+		OrderCfg.QuoteSecret = utils.RandomToken(32)
+		slog.Warn("未设置 ORDER_QUOTE_SECRET，使用随机报价密钥（重启或多实例间报价令牌不通用）")
+	}
+	return OrderCfg
+}
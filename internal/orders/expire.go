@@ -0,0 +1,155 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"github.com/sunzhaoc/plant_be/pkg/db/redis"
+	"github.com/sunzhaoc/plant_be/pkg/payment"
+	"gorm.io/gorm"
+)
+
+// expireQueueKey 待支付订单过期队列（ZSET，member 为 order_sn，score 为过期时间戳）
+const expireQueueKey = "order:expire"
+
+// expireRetryDelay 处理失败后的重试间隔
+const expireRetryDelay = time.Minute
+
+// expireBatchSize 每轮最多处理的订单数
+const expireBatchSize = 100
+
+// ScheduleExpire 将订单加入过期队列
+func ScheduleExpire(ctx context.Context, orderSn string, expireAt time.Time) error {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		return err
+	}
+	return rdb.ZAdd(ctx, expireQueueKey, goredis.Z{Score: float64(expireAt.Unix()), Member: orderSn}).Err()
+}
+
+// StartExpireWorker 启动超时未支付订单的自动取消任务
+//
+// 多实例同时运行时，通过 ZREM 的返回值抢占订单，同一订单只会被一个实例处理；
+// 另有数据库兜底扫描，将漏入队列的超时订单重新入队
+func StartExpireWorker() {
+	scanTicker := time.NewTicker(OrderCfg.ScanInterval)
+	defer scanTicker.Stop()
+	sweepTicker := time.NewTicker(OrderCfg.SweepInterval)
+	defer sweepTicker.Stop()
+
+	for {
+		select {
+		case <-scanTicker.C:
+			processExpired(context.Background())
+		case <-sweepTicker.C:
+			sweepExpired(context.Background())
+		}
+	}
+}
+
+// processExpired 取出已到期的订单逐个取消
+func processExpired(ctx context.Context) {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		slog.Error("获取Redis客户端失败", "error", err)
+		return
+	}
+
+	orderSns, err := rdb.ZRangeByScore(ctx, expireQueueKey, &goredis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(time.Now().Unix(), 10),
+		Count: expireBatchSize,
+	}).Result()
+	if err != nil {
+		slog.Error("读取订单过期队列失败", "error", err)
+		return
+	}
+
+	for _, orderSn := range orderSns {
+		// 抢占：只有成功移除的实例负责处理该订单
+		removed, err := rdb.ZRem(ctx, expireQueueKey, orderSn).Result()
+		if err != nil || removed == 0 {
+			continue
+		}
+		if err := expireOrder(ctx, orderSn); err != nil {
+			slog.Error("超时订单取消失败，稍后重试", "orderSn", orderSn, "error", err)
+			if err := ScheduleExpire(ctx, orderSn, time.Now().Add(expireRetryDelay)); err != nil {
+				slog.Error("超时订单重新入队失败", "orderSn", orderSn, "error", err)
+			}
+		}
+	}
+}
+
+// expireOrder 关闭渠道交易后取消订单并归还库存
+func expireOrder(ctx context.Context, orderSn string) error {
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		return err
+	}
+
+	var order models.Orders
+	err = db.Select("id", "order_status", "pay_channel").Where("order_sn = ?", orderSn).Take(&order).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("查询订单失败: %w", err)
+	}
+	if order.OrderStatus != models.OrderStatusPendingPayment {
+		return nil
+	}
+
+	// 先关闭渠道交易，避免取消后用户仍能完成支付；关闭失败（如已支付）则等待支付通知
+	if provider, err := payment.Get(order.PayChannel); err == nil {
+		if err := provider.Close(ctx, orderSn); err != nil {
+			return fmt.Errorf("关闭渠道交易失败: %w", err)
+		}
+	}
+
+	err = Cancel(db, order.Id, System, "超时未支付，自动取消")
+	if errors.Is(err, ErrIllegalTransition) {
+		// 关闭交易期间订单已被支付或取消
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	slog.Info("超时未支付订单已取消", "orderSn", orderSn)
+	return nil
+}
+
+// sweepExpired 兜底扫描已超时但不在过期队列中的待支付订单
+func sweepExpired(ctx context.Context) {
+	db, err := mysql.GetDB("ali")
+	if err != nil {
+		slog.Error("数据库连接失败", "error", err)
+		return
+	}
+
+	var orderSns []string
+	deadline := time.Now().Add(-OrderCfg.PayTimeout - OrderCfg.SweepInterval)
+	err = db.Model(&models.Orders{}).
+		Where("order_status = ? AND create_time < ?", models.OrderStatusPendingPayment, deadline).
+		Order("id").
+		Limit(expireBatchSize).
+		Pluck("order_sn", &orderSns).Error
+	if err != nil {
+		slog.Error("扫描超时待支付订单失败", "error", err)
+		return
+	}
+	for _, orderSn := range orderSns {
+		if err := ScheduleExpire(ctx, orderSn, time.Now()); err != nil {
+			slog.Error("超时订单入队失败", "orderSn", orderSn, "error", err)
+		}
+	}
+	if len(orderSns) > 0 {
+		slog.Info("兜底扫描到超时待支付订单", "count", len(orderSns))
+	}
+}
@@ -0,0 +1,229 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/sunzhaoc/plant_be/internal/coupon"
+	"github.com/sunzhaoc/plant_be/internal/inventory"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"github.com/sunzhaoc/plant_be/pkg/money"
+	"github.com/sunzhaoc/plant_be/pkg/payment"
+	"github.com/sunzhaoc/plant_be/pkg/region"
+	"gorm.io/gorm"
+)
+
+var ErrEmptyOrder = errors.New("购物车为空")
+
+var ErrInvalidProvince = errors.New("收货地址的省份无效，请重新编辑收货地址")
+
+// ErrInvalidItem 下单明细校验失败
+type ErrInvalidItem struct {
+	SkuId  uint64
+	Reason string
+}
+
+func (e *ErrInvalidItem) Error() string {
+	return e.Reason
+}
+
+// PlaceLine 下单明细
+type PlaceLine struct {
+	SkuId    uint64
+	Quantity uint
+	Price    money.Money // 成交单价，为 0 时按 SKU 当前售价
+}
+
+// PlaceRequest 下单参数
+type PlaceRequest struct {
+	OrderSn    string
+	UserId     uint64
+	PayChannel string
+	Address    models.UserAddress // 收货地址，按结构化字段快照
+	Lines      []PlaceLine
+	// UserCouponId 使用的用户优惠券（可选），在下单事务内锁定并核销
+	UserCouponId uint64
+	// QuoteToken 结算预览签发的报价令牌（可选），传入时实付金额须与报价一致
+	QuoteToken string
+	// AfterCreate 在下单事务内、订单与订单项写入后调用（可选），用于写入与订单同生共死的业务记录
+	AfterCreate func(tx *gorm.DB, order *models.Orders) error
+}
+
+// Place 预占库存并写入待支付订单（订单主表、状态记录、商品快照、预占记录），提交后加入超时取消队列
+//
+// 收货省份无法识别返回 ErrInvalidProvince，明细校验失败返回 *ErrInvalidItem，库存不足返回 *inventory.ErrInsufficientStock，
+// 无法配送到收货地区返回 *shipping.ErrUndeliverable，
+// 优惠券不可用返回 coupon.ErrUnavailable / coupon.ErrThresholdNotMet，
+// 报价令牌无效返回 ErrQuoteInvalid / ErrQuoteExpired，金额与报价不一致返回 *ErrPriceChanged；
+// 事务失败时归还 Redis 侧预占。发起支付由调用方在返回后完成
+func Place(ctx context.Context, db *gorm.DB, req PlaceRequest) (*models.Orders, error) {
+	// 运费与配送限制按省份代码匹配，旧地址未记录代码时按名称识别
+	province, ok := region.Resolve(req.Address.ProvinceCode, req.Address.Province)
+	if !ok {
+		return nil, ErrInvalidProvince
+	}
+	req.Address.ProvinceCode, req.Address.Province = province.Code, province.Name
+
+	// 1. 校验明细并在 Redis 中原子预占库存
+	var quoted money.Money
+	if req.QuoteToken != "" {
+		amount, err := VerifyQuote(req.QuoteToken, req.UserId, req.Lines, req.UserCouponId)
+		if err != nil {
+			return nil, err
+		}
+		quoted = amount
+	}
+	skuMap, err := loadSkus(ctx, db, req.Lines)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]inventory.Line, 0, len(req.Lines))
+	for _, line := range req.Lines {
+		lines = append(lines, inventory.Line{SkuId: line.SkuId, Quantity: line.Quantity})
+	}
+	if err := inventory.Reserve(ctx, req.OrderSn, lines, OrderCfg.PayTimeout); err != nil {
+		return nil, err
+	}
+	// 下单事务未提交时归还预占
+	committed := false
+	defer func() {
+		if committed {
+			return
+		}
+		if err := inventory.Release(context.Background(), req.OrderSn); err != nil {
+			slog.Error("下单失败后归还预占库存失败", "orderSn", req.OrderSn, "error", err)
+		}
+	}()
+
+	// 2. 订单、优惠券核销与预占记录在同一事务内写入
+	order := &models.Orders{
+		OrderSn:              req.OrderSn,
+		UserId:               req.UserId,
+		OrderStatus:          models.OrderStatusPendingPayment,
+		PayChannel:           req.PayChannel,
+		ReceiverName:         req.Address.Receiver,
+		ReceiverPhone:        req.Address.Phone,
+		ReceiverAddress:      req.Address.FullAddress(),
+		ReceiverProvince:     req.Address.Province,
+		ReceiverProvinceCode: req.Address.ProvinceCode,
+		ReceiverCity:         req.Address.City,
+		ReceiverArea:         req.Address.Area,
+		ReceiverDetail:       req.Address.DetailAddress,
+	}
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		pricing, err := price(tx, req.UserId, req.Address.ProvinceCode, req.Lines, skuMap, req.UserCouponId, true)
+		if err != nil {
+			return err
+		}
+		if req.QuoteToken != "" && pricing.PayAmount != quoted {
+			return &ErrPriceChanged{Quoted: quoted, Current: pricing.PayAmount}
+		}
+		order.TotalAmount = pricing.Subtotal
+		order.ShippingFee = pricing.Shipping
+		order.DiscountAmount = pricing.Discount
+		order.PayAmount = pricing.PayAmount
+
+		if err := inventory.RecordReservation(tx, req.OrderSn, lines, time.Now().Add(OrderCfg.PayTimeout)); err != nil {
+			return err
+		}
+		if err := tx.Create(order).Error; err != nil {
+			return fmt.Errorf("插入订单主表失败: %w", err)
+		}
+		if err := RecordCreated(tx, order, User(req.UserId)); err != nil {
+			return err
+		}
+		if err := createItems(tx, order, req.Lines, skuMap, pricing.ItemDiscounts); err != nil {
+			return err
+		}
+		if pricing.Coupon != nil {
+			if err := coupon.Redeem(tx, pricing.Coupon.Id, order.OrderSn); err != nil {
+				return err
+			}
+		}
+		if req.AfterCreate != nil {
+			return req.AfterCreate(tx, order)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	committed = true
+
+	// 4. 加入超时取消队列，入队失败由兜底扫描补偿
+	if err := ScheduleExpire(ctx, order.OrderSn, ExpireAt(order)); err != nil {
+		slog.Error("订单加入过期队列失败", "orderSn", order.OrderSn, "error", err)
+	}
+	return order, nil
+}
+
+// unitPrice 明细成交单价
+func unitPrice(line PlaceLine, skuMap map[uint64]models.PlantSku) money.Money {
+	if line.Price > 0 {
+		return line.Price
+	}
+	return skuMap[line.SkuId].Price
+}
+
+// createItems 写入订单商品快照
+func createItems(tx *gorm.DB, order *models.Orders, lines []PlaceLine, skuMap map[uint64]models.PlantSku, discounts []money.Money) error {
+	plantIds := make([]uint64, 0, len(lines))
+	seen := make(map[uint64]struct{}, len(lines))
+	for _, line := range lines {
+		plantId := skuMap[line.SkuId].PlantId
+		if _, ok := seen[plantId]; !ok {
+			seen[plantId] = struct{}{}
+			plantIds = append(plantIds, plantId)
+		}
+	}
+	var plantList []models.Plant
+	if err := tx.Select("id", "name", "latin_name", "main_img_url").Where("id IN ?", plantIds).Find(&plantList).Error; err != nil {
+		return fmt.Errorf("批量查询植物信息失败: %w", err)
+	}
+	plantMap := make(map[uint64]models.Plant, len(plantList))
+	for _, plant := range plantList {
+		plantMap[plant.Id] = plant
+	}
+
+	orderItems := make([]models.OrderItem, 0, len(lines))
+	for i, line := range lines {
+		sku := skuMap[line.SkuId]
+		plant := plantMap[sku.PlantId]
+		orderItems = append(orderItems, models.OrderItem{
+			OrderId:        order.Id,                // 关联订单ID
+			PlantId:        sku.PlantId,             // 植物ID
+			SkuId:          sku.Id,                  // 规格ID
+			PlantName:      plant.Name,              // 植物名称（快照）
+			PlantLatinName: plant.LatinName,         // 拉丁学名（快照）
+			SkuSize:        sku.Size,                // 规格名称（快照）
+			MainImgUrl:     plant.MainImgUrl,        // 主图（快照）
+			Price:          unitPrice(line, skuMap), // 下单时单价（快照）
+			DiscountAmount: discounts[i],            // 分摊的优惠金额
+			Quantity:       line.Quantity,           // 购买数量
+		})
+	}
+	if err := tx.CreateInBatches(&orderItems, 100).Error; err != nil { // 批量插入（每次最多100条）
+		return fmt.Errorf("批量插入订单项失败: %w", err)
+	}
+	return nil
+}
+
+// ExpireAt 待支付订单的支付截止时间
+func ExpireAt(order *models.Orders) time.Time {
+	return order.CreateTime.Add(OrderCfg.PayTimeout)
+}
+
+// NewPrepayRequest 按订单构造渠道预支付请求
+func NewPrepayRequest(order *models.Orders, clientIP string) payment.PrepayRequest {
+	return payment.PrepayRequest{
+		OrderSn:   order.OrderSn,
+		Amount:    order.PayAmount,
+		Subject:   fmt.Sprintf("antplant订单%s", order.OrderSn),
+		ClientIP:  clientIP,
+		ExpireAt:  ExpireAt(order),
+		NotifyURL: payment.NotifyURL(order.PayChannel),
+	}
+}
@@ -0,0 +1,129 @@
+package orders
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sunzhaoc/plant_be/internal/coupon"
+	"github.com/sunzhaoc/plant_be/internal/shipping"
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"github.com/sunzhaoc/plant_be/pkg/money"
+	"gorm.io/gorm"
+)
+
+// PricedItem 按当前单价计价的下单明细
+type PricedItem struct {
+	PlaceLine
+	PlantId   uint64
+	Size      string
+	UnitPrice money.Money
+	Amount    money.Money // 单价 × 数量
+}
+
+// Pricing 订单金额计算结果
+type Pricing struct {
+	Items         []PricedItem  // 与下单明细一一对应
+	Subtotal      money.Money   // 商品金额
+	Shipping      money.Money   // 运费
+	Discount      money.Money   // 优惠金额
+	PayAmount     money.Money   // 实付金额 = 商品金额 + 运费 - 优惠
+	ItemDiscounts []money.Money // 商品券优惠按行金额分摊，与下单明细一一对应
+	Coupon        *coupon.Owned // 使用的优惠券（可选）
+}
+
+// loadSkus 查询并校验下单明细对应的 SKU（库存由预占层保证，不锁定 plant_sku 行）
+func loadSkus(ctx context.Context, db *gorm.DB, lines []PlaceLine) (map[uint64]models.PlantSku, error) {
+	if len(lines) == 0 {
+		return nil, ErrEmptyOrder
+	}
+	skuIds := make([]uint64, 0, len(lines))
+	for _, line := range lines {
+		skuIds = append(skuIds, line.SkuId)
+	}
+	var skuList []models.PlantSku
+	if err := db.WithContext(ctx).Select("id", "plant_id", "size", "price", "weight_grams").Where("id IN ?", skuIds).Find(&skuList).Error; err != nil {
+		return nil, fmt.Errorf("批量查询SKU失败: %w", err)
+	}
+	skuMap := make(map[uint64]models.PlantSku, len(skuList))
+	for _, sku := range skuList {
+		skuMap[sku.Id] = sku
+	}
+	for _, line := range lines {
+		if _, ok := skuMap[line.SkuId]; !ok {
+			return nil, &ErrInvalidItem{SkuId: line.SkuId, Reason: fmt.Sprintf("规格ID %d 不存在", line.SkuId)}
+		}
+		if line.Quantity == 0 {
+			return nil, &ErrInvalidItem{SkuId: line.SkuId, Reason: "购买数量必须大于0"}
+		}
+	}
+	return skuMap, nil
+}
+
+// Quote 计算下单明细的应付金额，不锁定、不核销优惠券，用于下单前的价格预览
+//
+// shipTo 为收货省份代码，为空时不计算运费；无法配送返回 *shipping.ErrUndeliverable，
+// 优惠券不可用返回 coupon.ErrUnavailable，未达门槛返回 coupon.ErrThresholdNotMet
+func Quote(ctx context.Context, db *gorm.DB, userId uint64, shipTo string, lines []PlaceLine, userCouponId uint64) (*Pricing, error) {
+	skuMap, err := loadSkus(ctx, db, lines)
+	if err != nil {
+		return nil, err
+	}
+	return price(db.WithContext(ctx), userId, shipTo, lines, skuMap, userCouponId, false)
+}
+
+// price 计算金额；lock 为 true 时锁定优惠券，须在下单事务内调用
+func price(tx *gorm.DB, userId uint64, shipTo string, lines []PlaceLine, skuMap map[uint64]models.PlantSku, userCouponId uint64, lock bool) (*Pricing, error) {
+	p := &Pricing{
+		Items:         make([]PricedItem, 0, len(lines)),
+		ItemDiscounts: make([]money.Money, len(lines)),
+	}
+	lineAmounts := make([]money.Money, 0, len(lines))
+	for _, line := range lines {
+		sku := skuMap[line.SkuId]
+		item := PricedItem{PlaceLine: line, PlantId: sku.PlantId, Size: sku.Size, UnitPrice: unitPrice(line, skuMap)}
+		item.Amount = item.UnitPrice.Mul(int64(line.Quantity))
+		p.Items = append(p.Items, item)
+		lineAmounts = append(lineAmounts, item.Amount)
+		p.Subtotal += item.Amount
+	}
+
+	if shipTo != "" {
+		shipItems := make([]shipping.Item, 0, len(p.Items))
+		for _, item := range p.Items {
+			shipItems = append(shipItems, shipping.Item{
+				PlantId:     item.PlantId,
+				Quantity:    item.Quantity,
+				WeightGrams: skuMap[item.SkuId].WeightGrams,
+				Amount:      item.Amount,
+			})
+		}
+		fee, err := shipping.Fee(tx, shipTo, shipItems, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		p.Shipping = fee
+	}
+
+	if userCouponId != 0 {
+		owned, err := coupon.Get(tx, userId, userCouponId, lock)
+		if err != nil {
+			return nil, err
+		}
+		discount, err := coupon.Discount(owned.Coupon, p.Subtotal, p.Shipping)
+		if err != nil {
+			return nil, err
+		}
+		// 实付至少 0.01 元，避免支付渠道拒绝零元订单
+		if limit := p.Subtotal + p.Shipping - money.Fen; discount > limit {
+			discount = limit
+		}
+		p.Discount = discount
+		p.Coupon = owned
+		if owned.Coupon.Type != models.CouponFreeShipping {
+			p.ItemDiscounts = money.Allocate(discount, lineAmounts)
+		}
+	}
+	p.PayAmount = p.Subtotal + p.Shipping - p.Discount
+	return p, nil
+}
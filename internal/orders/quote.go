@@ -0,0 +1,101 @@
+package orders
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sunzhaoc/plant_be/pkg/money"
+)
+
+var (
+	ErrQuoteInvalid = errors.New("报价无效，请重新确认订单")
+	ErrQuoteExpired = errors.New("报价已过期，请重新确认订单")
+)
+
+// ErrPriceChanged 下单时的实付金额与预览报价不一致
+type ErrPriceChanged struct {
+	Quoted  money.Money
+	Current money.Money
+}
+
+func (e *ErrPriceChanged) Error() string {
+	return fmt.Sprintf("订单金额已变化: 报价 %s，当前 %s", e.Quoted, e.Current)
+}
+
+// quoteClaims 报价令牌内容，Digest 绑定下单明细与优惠券，防止报价被用于其他购物车
+type quoteClaims struct {
+	UserId    uint64      `json:"u"`
+	Digest    string      `json:"d"`
+	PayAmount money.Money `json:"a"`
+	ExpiresAt int64       `json:"e"`
+}
+
+// quoteDigest 按 SKU 合并、排序后的明细与优惠券计算摘要
+func quoteDigest(lines []PlaceLine, userCouponId uint64) string {
+	quantities := make(map[uint64]uint, len(lines))
+	for _, line := range lines {
+		quantities[line.SkuId] += line.Quantity
+	}
+	skuIds := make([]uint64, 0, len(quantities))
+	for skuId := range quantities {
+		skuIds = append(skuIds, skuId)
+	}
+	sort.Slice(skuIds, func(i, j int) bool { return skuIds[i] < skuIds[j] })
+	var b strings.Builder
+	for _, skuId := range skuIds {
+		fmt.Fprintf(&b, "%d:%d,", skuId, quantities[skuId])
+	}
+	fmt.Fprintf(&b, "c%d", userCouponId)
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:16])
+}
+
+func signQuote(payload string) string {
+	mac := hmac.New(sha256.New, []byte(OrderCfg.QuoteSecret))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SignQuote 为预览结果签发报价令牌，有效期 QuoteTTL，返回令牌与过期时间
+func SignQuote(userId uint64, lines []PlaceLine, userCouponId uint64, payAmount money.Money) (string, time.Time) {
+	expireAt := time.Now().Add(OrderCfg.QuoteTTL)
+	raw, _ := json.Marshal(quoteClaims{
+		UserId:    userId,
+		Digest:    quoteDigest(lines, userCouponId),
+		PayAmount: payAmount,
+		ExpiresAt: expireAt.Unix(),
+	})
+	payload := base64.RawURLEncoding.EncodeToString(raw)
+	return payload + "." + signQuote(payload), expireAt
+}
+
+// VerifyQuote 校验报价令牌的签名、有效期及其对应的用户与下单明细，返回报价时的实付金额
+func VerifyQuote(token string, userId uint64, lines []PlaceLine, userCouponId uint64) (money.Money, error) {
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(signQuote(payload))) {
+		return 0, ErrQuoteInvalid
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return 0, ErrQuoteInvalid
+	}
+	var claims quoteClaims
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		return 0, ErrQuoteInvalid
+	}
+	if claims.UserId != userId || claims.Digest != quoteDigest(lines, userCouponId) {
+		return 0, ErrQuoteInvalid
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return 0, ErrQuoteExpired
+	}
+	return claims.PayAmount, nil
+}
@@ -0,0 +1,26 @@
+package orders
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// RecordSales 订单支付成功后按订单项累加植物销量（plants.sales_count），须在支付事务内调用
+//
+// 销量用于列表按人气排序，事后退款不回退
+func RecordSales(tx *gorm.DB, orderId uint64) error {
+	err := tx.Exec(`
+	UPDATE plant.plants p
+	JOIN (
+		SELECT plant_id, SUM(quantity) qty
+		FROM plant.order_items
+		WHERE order_id = ?
+		GROUP BY plant_id
+	) s ON s.plant_id = p.id
+	SET p.sales_count = p.sales_count + s.qty`, orderId).Error
+	if err != nil {
+		return fmt.Errorf("累加植物销量失败: %w", err)
+	}
+	return nil
+}
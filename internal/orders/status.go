@@ -0,0 +1,142 @@
+package orders
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 操作人类型
+const (
+	OperatorUser   = "user"
+	OperatorAdmin  = "admin"
+	OperatorSystem = "system"
+)
+
+var (
+	ErrOrderNotFound     = errors.New("订单不存在")
+	ErrIllegalTransition = errors.New("订单状态不允许该操作")
+)
+
+// Operator 触发状态变更的操作人
+type Operator struct {
+	Type string
+	Id   uint64
+}
+
+// System 系统任务（支付回调、超时取消等）
+var System = Operator{Type: OperatorSystem}
+
+// User 用户本人操作
+func User(uid uint64) Operator {
+	return Operator{Type: OperatorUser, Id: uid}
+}
+
+// Admin 后台管理员操作
+func Admin(uid uint64) Operator {
+	return Operator{Type: OperatorAdmin, Id: uid}
+}
+
+// Change 一次状态变更
+type Change struct {
+	OrderId  uint64
+	To       models.OrderStatus
+	Operator Operator
+	Remark   string
+	Fields   map[string]interface{} // 与状态一同更新的其他订单字段（可选）
+}
+
+// Transition 校验并执行订单状态流转，同时写入 order_status_history
+//
+// 在 tx 所在事务内锁定订单行（tx 不在事务中时自动开启），非法流转返回 ErrIllegalTransition；
+// 返回变更前的状态，调用方可据此判断重复请求（from == To）并做幂等处理
+func Transition(tx *gorm.DB, ch Change) (models.OrderStatus, error) {
+	var from models.OrderStatus
+	err := tx.Transaction(func(tx *gorm.DB) error {
+		var order models.Orders
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "order_sn", "order_status").
+			Where("id = ?", ch.OrderId).
+			Take(&order).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrOrderNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("查询订单失败: %w", err)
+		}
+		from = order.OrderStatus
+
+		if !from.CanTransitionTo(ch.To) {
+			return fmt.Errorf("%w: %s -> %s", ErrIllegalTransition, from.Label(), ch.To.Label())
+		}
+		// 退款中只能完成退款或回到发起退款前的状态
+		if from == models.OrderStatusRefunding && ch.To != models.OrderStatusRefunded {
+			before, err := RefundingFrom(tx, order.Id)
+			if err != nil {
+				return err
+			}
+			if before != ch.To {
+				return fmt.Errorf("%w: %s -> %s", ErrIllegalTransition, from.Label(), ch.To.Label())
+			}
+		}
+
+		updates := make(map[string]interface{}, len(ch.Fields)+1)
+		for k, v := range ch.Fields {
+			updates[k] = v
+		}
+		updates["order_status"] = ch.To
+		if err := tx.Model(&models.Orders{}).Where("id = ?", order.Id).Updates(updates).Error; err != nil {
+			return fmt.Errorf("更新订单状态失败: %w", err)
+		}
+
+		history := models.OrderStatusHistory{
+			OrderId:      order.Id,
+			OrderSn:      order.OrderSn,
+			FromStatus:   &from,
+			ToStatus:     ch.To,
+			OperatorType: ch.Operator.Type,
+			OperatorId:   ch.Operator.Id,
+			Remark:       ch.Remark,
+		}
+		if err := tx.Create(&history).Error; err != nil {
+			return fmt.Errorf("写入订单状态记录失败: %w", err)
+		}
+		return nil
+	})
+	return from, err
+}
+
+// RefundingFrom 订单最近一次进入退款中之前的状态
+func RefundingFrom(tx *gorm.DB, orderId uint64) (models.OrderStatus, error) {
+	var history models.OrderStatusHistory
+	err := tx.Select("from_status").
+		Where("order_id = ? AND to_status = ?", orderId, models.OrderStatusRefunding).
+		Order("id DESC").
+		Take(&history).Error
+	if err != nil {
+		return 0, fmt.Errorf("查询申请售后前的订单状态失败: %w", err)
+	}
+	if history.FromStatus == nil {
+		return 0, errors.New("申请售后前的订单状态缺失")
+	}
+	return *history.FromStatus, nil
+}
+
+// RecordCreated 记录订单创建（初始状态），需在创建订单的事务内调用
+func RecordCreated(tx *gorm.DB, order *models.Orders, op Operator) error {
+	history := models.OrderStatusHistory{
+		OrderId:      order.Id,
+		OrderSn:      order.OrderSn,
+		ToStatus:     order.OrderStatus,
+		OperatorType: op.Type,
+		OperatorId:   op.Id,
+		Remark:       "创建订单",
+	}
+	if err := tx.Create(&history).Error; err != nil {
+		return fmt.Errorf("写入订单状态记录失败: %w", err)
+	}
+	return nil
+}
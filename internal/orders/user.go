@@ -0,0 +1,109 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"github.com/sunzhaoc/plant_be/pkg/payment"
+	"gorm.io/gorm"
+)
+
+var ErrPaymentPending = errors.New("订单支付结果确认中，请稍后再试")
+
+var ErrOrderExpired = errors.New("订单已超过支付期限，请重新下单")
+
+var ErrPrepayFailed = errors.New("发起支付失败，请稍后重试")
+
+// FindForUser 按订单号查询用户本人的订单，不存在或不属于该用户时返回 ErrOrderNotFound
+func FindForUser(db *gorm.DB, userId uint64, orderSn string) (*models.Orders, error) {
+	var order models.Orders
+	err := db.Where("order_sn = ? AND user_id = ?", orderSn, userId).Take(&order).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+	return &order, nil
+}
+
+// UserCancel 用户取消本人的待支付订单并释放库存
+//
+// 与超时取消一致，先关闭渠道交易再取消订单；关闭失败多为用户已完成支付，返回 ErrPaymentPending 等待支付通知
+func UserCancel(ctx context.Context, db *gorm.DB, userId uint64, orderSn string) error {
+	order, err := FindForUser(db, userId, orderSn)
+	if err != nil {
+		return err
+	}
+	return closeAndCancel(ctx, db, order, User(userId), "用户取消订单", nil)
+}
+
+// AdminCancel 后台取消待支付订单，hook（可选）在取消事务内调用
+func AdminCancel(ctx context.Context, db *gorm.DB, orderId uint64, op Operator, remark string, hook func(tx *gorm.DB) error) error {
+	var order models.Orders
+	err := db.Where("id = ?", orderId).Take(&order).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrOrderNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("查询订单失败: %w", err)
+	}
+	return closeAndCancel(ctx, db, &order, op, remark, hook)
+}
+
+// closeAndCancel 关闭渠道交易后取消待支付订单
+func closeAndCancel(ctx context.Context, db *gorm.DB, order *models.Orders, op Operator, remark string, hook func(tx *gorm.DB) error) error {
+	if order.OrderStatus != models.OrderStatusPendingPayment {
+		return fmt.Errorf("%w: %s", ErrIllegalTransition, order.OrderStatus.Label())
+	}
+	if provider, err := payment.Get(order.PayChannel); err == nil {
+		if err := provider.Close(ctx, order.OrderSn); err != nil {
+			return fmt.Errorf("%w: %v", ErrPaymentPending, err)
+		}
+	}
+	return CancelWith(db, order.Id, op, remark, hook)
+}
+
+// Repay 为本人的待支付订单重新向下单时的支付渠道发起预支付（首次发起失败或支付页面关闭后使用）
+func Repay(ctx context.Context, db *gorm.DB, userId uint64, orderSn string, clientIP string) (*models.Orders, *payment.PrepayResult, error) {
+	order, err := FindForUser(db, userId, orderSn)
+	if err != nil {
+		return nil, nil, err
+	}
+	if order.OrderStatus != models.OrderStatusPendingPayment {
+		return nil, nil, fmt.Errorf("%w: %s", ErrIllegalTransition, order.OrderStatus.Label())
+	}
+	if !time.Now().Before(ExpireAt(order)) {
+		return nil, nil, ErrOrderExpired
+	}
+	provider, err := payment.Get(order.PayChannel)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrPrepayFailed, err)
+	}
+	prepay, err := provider.CreatePrepay(ctx, NewPrepayRequest(order, clientIP))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrPrepayFailed, err)
+	}
+	return order, prepay, nil
+}
+
+// Confirm 用户确认收货，已发货或已送达的订单流转为已完成
+func Confirm(db *gorm.DB, userId uint64, orderSn string) error {
+	order, err := FindForUser(db, userId, orderSn)
+	if err != nil {
+		return err
+	}
+	if order.OrderStatus != models.OrderStatusShipped && order.OrderStatus != models.OrderStatusDelivered {
+		return fmt.Errorf("%w: %s", ErrIllegalTransition, order.OrderStatus.Label())
+	}
+	_, err = Transition(db, Change{
+		OrderId:  order.Id,
+		To:       models.OrderStatusCompleted,
+		Operator: User(userId),
+		Remark:   "用户确认收货",
+	})
+	return err
+}
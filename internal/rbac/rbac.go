@@ -0,0 +1,91 @@
+package rbac
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"gorm.io/gorm"
+)
+
+// 预置角色编码，与 migrations/006_rbac.sql 保持一致
+const (
+	RoleAdmin     = "admin"
+	RoleWarehouse = "warehouse"
+)
+
+// 预置权限编码
+const (
+	PermCatalogWrite    = "catalog:write"    // 商品、SKU、图片维护
+	PermOrderRead       = "order:read"       // 后台查看订单
+	PermOrderManage     = "order:manage"     // 后台修改订单
+	PermOrderShip       = "order:ship"       // 订单发货
+	PermRBACManage      = "rbac:manage"      // 给用户授予/撤销角色
+	PermPromotionManage = "promotion:manage" // 优惠券等营销活动维护
+)
+
+var ErrRoleNotFound = errors.New("角色不存在")
+
+// Access 用户的角色与权限集合，登录时写入 Token
+type Access struct {
+	Roles       []string
+	Permissions []string
+}
+
+// LoadAccess 查询用户当前的角色与权限
+func LoadAccess(db *gorm.DB, userId uint64) (*Access, error) {
+	access := &Access{Roles: []string{}, Permissions: []string{}}
+	err := db.Raw(`SELECT r.code FROM plant.user_roles ur
+		JOIN plant.roles r ON r.id = ur.role_id
+		WHERE ur.user_id = ? ORDER BY r.code`, userId).Scan(&access.Roles).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询用户角色失败: %w", err)
+	}
+	err = db.Raw(`SELECT DISTINCT p.code FROM plant.user_roles ur
+		JOIN plant.role_permissions rp ON rp.role_id = ur.role_id
+		JOIN plant.permissions p ON p.id = rp.permission_id
+		WHERE ur.user_id = ? ORDER BY p.code`, userId).Scan(&access.Permissions).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询用户权限失败: %w", err)
+	}
+	return access, nil
+}
+
+// findRole 按编码查询角色
+func findRole(tx *gorm.DB, code string) (*models.Role, error) {
+	var role models.Role
+	err := tx.Where("code = ?", code).Take(&role).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRoleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// Grant 授予用户角色，已拥有时不做变更，返回是否新授予
+func Grant(tx *gorm.DB, userId uint64, roleCode string) (bool, error) {
+	role, err := findRole(tx, roleCode)
+	if err != nil {
+		return false, err
+	}
+	result := tx.Exec("INSERT IGNORE INTO plant.user_roles (user_id, role_id) VALUES (?, ?)", userId, role.Id)
+	if result.Error != nil {
+		return false, fmt.Errorf("授予角色失败: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Revoke 撤销用户角色，未拥有时不做变更，返回是否实际撤销
+func Revoke(tx *gorm.DB, userId uint64, roleCode string) (bool, error) {
+	role, err := findRole(tx, roleCode)
+	if err != nil {
+		return false, err
+	}
+	result := tx.Where("user_id = ? AND role_id = ?", userId, role.Id).Delete(&models.UserRole{})
+	if result.Error != nil {
+		return false, fmt.Errorf("撤销角色失败: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,218 @@
+package shipping
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sunzhaoc/plant_be/pkg/db/mysql/models"
+	"github.com/sunzhaoc/plant_be/pkg/money"
+	"github.com/sunzhaoc/plant_be/pkg/region"
+	"gorm.io/gorm"
+)
+
+// ErrUndeliverable 商品无法配送到收货地区（模板不配送或季节性限制）
+type ErrUndeliverable struct {
+	PlantId uint64
+	Reason  string
+}
+
+func (e *ErrUndeliverable) Error() string {
+	return e.Reason
+}
+
+// Item 计算运费的商品明细
+type Item struct {
+	PlantId     uint64
+	Quantity    uint
+	WeightGrams uint        // 单件计费重量
+	Amount      money.Money // 商品金额，用于判断包邮门槛
+}
+
+// group 同一运费模板下的商品合计
+type group struct {
+	template models.ShippingTemplate
+	quantity uint
+	grams    uint
+	amount   money.Money
+}
+
+// MatchRegion 收货省份代码是否在逗号分隔的省份列表中
+//
+// 列表项为省份代码，兼容旧配置中的全称或简称；无法识别的列表项忽略
+func MatchRegion(regions string, provinceCode string) bool {
+	if provinceCode == "" {
+		return false
+	}
+	for _, item := range strings.Split(regions, ",") {
+		if p, ok := region.Lookup(item); ok && p.Code == provinceCode {
+			return true
+		}
+	}
+	return false
+}
+
+// inSeason 当前日期是否在 MM-DD 表示的区间内（含两端），起始晚于结束时表示跨年
+func inSeason(now time.Time, startDay string, endDay string) bool {
+	today := now.Format("01-02")
+	if startDay <= endDay {
+		return today >= startDay && today <= endDay
+	}
+	return today >= startDay || today <= endDay
+}
+
+// ValidDay 校验 MM-DD 格式的日期
+func ValidDay(day string) bool {
+	_, err := time.Parse("01-02", day)
+	return err == nil && len(day) == 5
+}
+
+// Fee 计算商品配送到收货省份（省份代码）的运费
+//
+// 商品按植物的运费模板分组（未指定时使用默认模板，无默认模板时该组免运费），各组运费相加；
+// 商品被模板排除或处于季节性限制期内时返回 *ErrUndeliverable
+func Fee(db *gorm.DB, provinceCode string, items []Item, now time.Time) (money.Money, error) {
+	if len(items) == 0 {
+		return 0, nil
+	}
+	province := region.Name(provinceCode)
+	plantIds := make([]uint64, 0, len(items))
+	for _, item := range items {
+		plantIds = append(plantIds, item.PlantId)
+	}
+
+	var restrictions []models.PlantShippingRestriction
+	if err := db.Where("plant_id IN ?", plantIds).Find(&restrictions).Error; err != nil {
+		return 0, fmt.Errorf("查询配送限制失败: %w", err)
+	}
+	for _, r := range restrictions {
+		if MatchRegion(r.Regions, provinceCode) && inSeason(now, r.StartDay, r.EndDay) {
+			reason := r.Reason
+			if reason == "" {
+				reason = fmt.Sprintf("%s至%s期间不发往%s", r.StartDay, r.EndDay, province)
+			}
+			return 0, &ErrUndeliverable{PlantId: r.PlantId, Reason: reason}
+		}
+	}
+
+	var plantList []models.Plant
+	if err := db.Select("id", "name", "shipping_template_id").Where("id IN ?", plantIds).Find(&plantList).Error; err != nil {
+		return 0, fmt.Errorf("查询植物运费模板失败: %w", err)
+	}
+	plantMap := make(map[uint64]models.Plant, len(plantList))
+	templateIdSet := make(map[uint64]struct{})
+	for _, plant := range plantList {
+		plantMap[plant.Id] = plant
+		if plant.ShippingTemplateId != nil {
+			templateIdSet[*plant.ShippingTemplateId] = struct{}{}
+		}
+	}
+	templateIds := make([]uint64, 0, len(templateIdSet))
+	for id := range templateIdSet {
+		templateIds = append(templateIds, id)
+	}
+	var templates []models.ShippingTemplate
+	if err := db.Where("id IN ? OR is_default = ?", templateIds, true).Order("id").Find(&templates).Error; err != nil {
+		return 0, fmt.Errorf("查询运费模板失败: %w", err)
+	}
+	templateMap := make(map[uint64]models.ShippingTemplate, len(templates))
+	var defaultTemplate *models.ShippingTemplate
+	for i, t := range templates {
+		templateMap[t.Id] = t
+		if t.IsDefault && defaultTemplate == nil {
+			defaultTemplate = &templates[i]
+		}
+	}
+
+	groups := make(map[uint64]*group)
+	for _, item := range items {
+		plant := plantMap[item.PlantId]
+		var template *models.ShippingTemplate
+		if plant.ShippingTemplateId != nil {
+			if t, ok := templateMap[*plant.ShippingTemplateId]; ok {
+				template = &t
+			}
+		}
+		if template == nil {
+			template = defaultTemplate
+		}
+		if template == nil {
+			continue
+		}
+		if MatchRegion(template.ExcludedRegions, provinceCode) {
+			return 0, &ErrUndeliverable{PlantId: item.PlantId, Reason: fmt.Sprintf("%s暂不支持配送至%s", plant.Name, province)}
+		}
+		g, ok := groups[template.Id]
+		if !ok {
+			g = &group{template: *template}
+			groups[template.Id] = g
+		}
+		g.quantity += item.Quantity
+		g.grams += item.WeightGrams * item.Quantity
+		g.amount += item.Amount
+	}
+	if len(groups) == 0 {
+		return 0, nil
+	}
+
+	groupIds := make([]uint64, 0, len(groups))
+	for id := range groups {
+		groupIds = append(groupIds, id)
+	}
+	var rules []models.ShippingTemplateRule
+	if err := db.Where("template_id IN ?", groupIds).Order("id").Find(&rules).Error; err != nil {
+		return 0, fmt.Errorf("查询运费规则失败: %w", err)
+	}
+	rulesByTemplate := make(map[uint64][]models.ShippingTemplateRule, len(groups))
+	for _, r := range rules {
+		rulesByTemplate[r.TemplateId] = append(rulesByTemplate[r.TemplateId], r)
+	}
+
+	var total money.Money
+	for id, g := range groups {
+		if g.template.FreeThreshold > 0 && g.amount >= g.template.FreeThreshold {
+			continue
+		}
+		rule, ok := matchRule(rulesByTemplate[id], provinceCode)
+		if !ok {
+			// 模板未配置适用规则视为免运费
+			continue
+		}
+		units := g.quantity
+		if g.template.ChargeMode == models.ChargeByWeight {
+			units = g.grams
+		}
+		total += ruleFee(rule, units)
+	}
+	return total, nil
+}
+
+// matchRule 优先匹配列出收货省份的规则，其次使用地区为空的兜底规则
+func matchRule(rules []models.ShippingTemplateRule, provinceCode string) (models.ShippingTemplateRule, bool) {
+	var fallback *models.ShippingTemplateRule
+	for i, r := range rules {
+		if strings.TrimSpace(r.Regions) == "" {
+			if fallback == nil {
+				fallback = &rules[i]
+			}
+			continue
+		}
+		if MatchRegion(r.Regions, provinceCode) {
+			return r, true
+		}
+	}
+	if fallback != nil {
+		return *fallback, true
+	}
+	return models.ShippingTemplateRule{}, false
+}
+
+// ruleFee 首重/首件内收取首费，超出部分按续重/续件向上取整计费
+func ruleFee(rule models.ShippingTemplateRule, units uint) money.Money {
+	fee := rule.FirstFee
+	if units <= rule.FirstUnit || rule.AdditionalUnit == 0 {
+		return fee
+	}
+	extra := (units - rule.FirstUnit + rule.AdditionalUnit - 1) / rule.AdditionalUnit
+	return fee + rule.AdditionalFee.Mul(int64(extra))
+}
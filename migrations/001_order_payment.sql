@@ -0,0 +1,5 @@
+-- 订单支付信息：渠道、渠道交易号、支付时间
+ALTER TABLE plant.orders
+    ADD COLUMN pay_channel VARCHAR(32)  NOT NULL DEFAULT '' COMMENT '支付渠道' AFTER receiver_address,
+    ADD COLUMN trade_no    VARCHAR(64)  NOT NULL DEFAULT '' COMMENT '渠道交易号' AFTER pay_channel,
+    ADD COLUMN pay_time    DATETIME     NULL COMMENT '支付时间' AFTER trade_no;
@@ -0,0 +1,15 @@
+-- 订单状态变更记录
+CREATE TABLE IF NOT EXISTS plant.order_status_history
+(
+    id            BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '主键ID',
+    order_id      BIGINT UNSIGNED NOT NULL COMMENT '订单ID',
+    order_sn      VARCHAR(32)     NOT NULL COMMENT '订单号',
+    from_status   TINYINT         NULL COMMENT '变更前状态（NULL表示创建）',
+    to_status     TINYINT         NOT NULL COMMENT '变更后状态',
+    operator_type VARCHAR(16)     NOT NULL DEFAULT 'system' COMMENT '操作人类型 user/admin/system',
+    operator_id   BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '操作人ID',
+    remark        VARCHAR(255)    NOT NULL DEFAULT '' COMMENT '备注',
+    create_time   DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '变更时间',
+    KEY idx_order_id (order_id)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='订单状态变更记录';
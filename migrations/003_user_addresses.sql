@@ -0,0 +1,24 @@
+-- 用户收货地址簿
+CREATE TABLE IF NOT EXISTS plant.user_addresses
+(
+    id             BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '主键ID',
+    user_id        BIGINT UNSIGNED NOT NULL COMMENT '用户ID',
+    receiver       VARCHAR(32)     NOT NULL COMMENT '收货人姓名',
+    phone          VARCHAR(20)     NOT NULL COMMENT '联系电话',
+    province       VARCHAR(32)     NOT NULL COMMENT '省',
+    city           VARCHAR(32)     NOT NULL COMMENT '市',
+    area           VARCHAR(32)     NOT NULL DEFAULT '' COMMENT '区/县',
+    detail_address VARCHAR(200)    NOT NULL COMMENT '详细地址',
+    is_default     TINYINT(1)      NOT NULL DEFAULT 0 COMMENT '是否默认地址',
+    create_time    DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    update_time    DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    KEY idx_user_id (user_id)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='用户收货地址';
+
+-- 订单收货地址结构化快照
+ALTER TABLE plant.orders
+    ADD COLUMN receiver_province VARCHAR(32)  NOT NULL DEFAULT '' COMMENT '收货地址快照：省' AFTER receiver_address,
+    ADD COLUMN receiver_city     VARCHAR(32)  NOT NULL DEFAULT '' COMMENT '收货地址快照：市' AFTER receiver_province,
+    ADD COLUMN receiver_area     VARCHAR(32)  NOT NULL DEFAULT '' COMMENT '收货地址快照：区/县' AFTER receiver_city,
+    ADD COLUMN receiver_detail   VARCHAR(200) NOT NULL DEFAULT '' COMMENT '收货地址快照：详细地址' AFTER receiver_area;
@@ -0,0 +1,37 @@
+-- 植物分类与标签，用于目录筛选
+CREATE TABLE IF NOT EXISTS plant.plant_categories
+(
+    id          BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '主键ID',
+    name        VARCHAR(32) NOT NULL COMMENT '分类名称',
+    sort        INT         NOT NULL DEFAULT 0 COMMENT '排序',
+    create_time DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    UNIQUE KEY uk_name (name)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='植物分类';
+
+CREATE TABLE IF NOT EXISTS plant.plant_tags
+(
+    id          BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '主键ID',
+    name        VARCHAR(32) NOT NULL COMMENT '标签名称',
+    create_time DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    UNIQUE KEY uk_name (name)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='植物标签';
+
+CREATE TABLE IF NOT EXISTS plant.plant_tag_relations
+(
+    plant_id BIGINT UNSIGNED NOT NULL COMMENT '植物ID',
+    tag_id   BIGINT UNSIGNED NOT NULL COMMENT '标签ID',
+    PRIMARY KEY (plant_id, tag_id),
+    KEY idx_tag_id (tag_id)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='植物-标签关联';
+
+ALTER TABLE plant.plants
+    ADD COLUMN category_id BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '分类ID',
+    ADD KEY idx_on_sale_category (is_on_sale, category_id),
+    ADD KEY idx_on_sale_price (is_on_sale, min_price);
+
+-- 人气排序按订单项聚合销量
+ALTER TABLE plant.order_items
+    ADD KEY idx_plant_id (plant_id);
@@ -0,0 +1,21 @@
+-- 用户角色
+ALTER TABLE plant.users
+    ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'user' COMMENT '用户角色：user/admin';
+
+-- 后台操作审计日志
+CREATE TABLE IF NOT EXISTS plant.admin_audit_logs
+(
+    id            BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '主键ID',
+    operator_id   BIGINT UNSIGNED NOT NULL COMMENT '操作人ID',
+    operator_name VARCHAR(50)     NOT NULL DEFAULT '' COMMENT '操作人用户名',
+    action        VARCHAR(64)     NOT NULL COMMENT '操作',
+    target_type   VARCHAR(32)     NOT NULL COMMENT '操作对象类型',
+    target_id     BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '操作对象ID',
+    before_data   JSON            NULL COMMENT '变更前数据',
+    after_data    JSON            NULL COMMENT '变更后数据',
+    client_ip     VARCHAR(64)     NOT NULL DEFAULT '' COMMENT '操作IP',
+    create_time   DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '操作时间',
+    KEY idx_target (target_type, target_id),
+    KEY idx_operator (operator_id, create_time)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='后台操作审计日志';
@@ -0,0 +1,72 @@
+-- 基于角色的权限控制：角色、权限点及其与用户的关联
+CREATE TABLE IF NOT EXISTS plant.roles
+(
+    id          BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '主键ID',
+    code        VARCHAR(32) NOT NULL COMMENT '角色编码',
+    name        VARCHAR(32) NOT NULL COMMENT '角色名称',
+    create_time DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    UNIQUE KEY uk_code (code)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='角色';
+
+CREATE TABLE IF NOT EXISTS plant.permissions
+(
+    id          BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '主键ID',
+    code        VARCHAR(64) NOT NULL COMMENT '权限编码',
+    name        VARCHAR(32) NOT NULL COMMENT '权限名称',
+    create_time DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    UNIQUE KEY uk_code (code)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='权限点';
+
+CREATE TABLE IF NOT EXISTS plant.role_permissions
+(
+    role_id       BIGINT UNSIGNED NOT NULL COMMENT '角色ID',
+    permission_id BIGINT UNSIGNED NOT NULL COMMENT '权限ID',
+    PRIMARY KEY (role_id, permission_id)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='角色-权限关联';
+
+CREATE TABLE IF NOT EXISTS plant.user_roles
+(
+    user_id     BIGINT UNSIGNED NOT NULL COMMENT '用户ID',
+    role_id     BIGINT UNSIGNED NOT NULL COMMENT '角色ID',
+    create_time DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '授权时间',
+    PRIMARY KEY (user_id, role_id),
+    KEY idx_role_id (role_id)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='用户-角色关联';
+
+-- 预置角色与权限
+INSERT IGNORE INTO plant.roles (code, name)
+VALUES ('admin', '管理员'),
+       ('warehouse', '仓库人员');
+
+INSERT IGNORE INTO plant.permissions (code, name)
+VALUES ('catalog:write', '商品管理'),
+       ('order:read', '查看订单'),
+       ('order:manage', '订单管理'),
+       ('order:ship', '订单发货'),
+       ('rbac:manage', '角色授权');
+
+INSERT IGNORE INTO plant.role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM plant.roles r
+         JOIN plant.permissions p
+WHERE r.code = 'admin';
+
+INSERT IGNORE INTO plant.role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM plant.roles r
+         JOIN plant.permissions p ON p.code IN ('order:read', 'order:ship')
+WHERE r.code = 'warehouse';
+
+-- 迁移 005 中的 users.role，之后角色统一由 user_roles 维护
+INSERT IGNORE INTO plant.user_roles (user_id, role_id)
+SELECT u.id, r.id
+FROM plant.users u
+         JOIN plant.roles r ON r.code = u.role
+WHERE u.role <> 'user';
+
+ALTER TABLE plant.users
+    DROP COLUMN role;
@@ -0,0 +1,11 @@
+-- 购物车持久化：Redis 为主存储，定时将有变更的购物车刷入此表，Redis 过期后从此表恢复
+CREATE TABLE IF NOT EXISTS plant.cart_items
+(
+    user_id     BIGINT UNSIGNED NOT NULL COMMENT '用户ID',
+    plant_id    BIGINT UNSIGNED NOT NULL COMMENT '植物ID',
+    size        VARCHAR(32)     NOT NULL COMMENT '规格名称',
+    quantity    INT UNSIGNED    NOT NULL COMMENT '数量',
+    update_time DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    PRIMARY KEY (user_id, plant_id, size)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='购物车';
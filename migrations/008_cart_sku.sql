@@ -0,0 +1,24 @@
+-- 购物车改为按 SKU 标识，并记录加入时间、加入价与勾选状态
+ALTER TABLE plant.cart_items
+    ADD COLUMN sku_id       BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT 'SKU ID' AFTER plant_id,
+    ADD COLUMN price_at_add DECIMAL(10, 2)  NOT NULL DEFAULT 0 COMMENT '加入购物车时的单价' AFTER quantity,
+    ADD COLUMN selected     TINYINT(1)      NOT NULL DEFAULT 1 COMMENT '是否勾选结算' AFTER price_at_add,
+    ADD COLUMN add_time     DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '首次加入时间' AFTER selected;
+
+-- 按规格名称解析 SKU，已删除或改名的规格无法解析，直接清理
+UPDATE plant.cart_items c
+    JOIN plant.plant_sku s ON s.plant_id = c.plant_id AND s.size = c.size
+SET c.sku_id       = s.id,
+    c.price_at_add = s.price;
+
+DELETE
+FROM plant.cart_items
+WHERE sku_id = 0;
+
+ALTER TABLE plant.cart_items
+    DROP PRIMARY KEY,
+    DROP COLUMN size,
+    ADD PRIMARY KEY (user_id, plant_id, sku_id);
+
+-- Redis 中的旧版购物车（cart:u:<uid>，按规格名称存储）由 go run ./cmd/cartmigrate 转换，
+-- 未转换的购物车也会在首次读取或刷盘时自动转换
@@ -0,0 +1,15 @@
+-- 库存预占记录：下单时预占，支付后确认（扣减 plant_sku.stock），取消/超时后释放
+CREATE TABLE IF NOT EXISTS plant.stock_reservations
+(
+    id          BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '主键ID',
+    order_sn    VARCHAR(32)     NOT NULL COMMENT '订单号',
+    sku_id      BIGINT UNSIGNED NOT NULL COMMENT 'SKU ID',
+    quantity    INT UNSIGNED    NOT NULL COMMENT '预占数量',
+    status      TINYINT         NOT NULL DEFAULT 0 COMMENT '状态：0预占中 1已确认 2已释放',
+    expire_time DATETIME        NOT NULL COMMENT '预占到期时间',
+    create_time DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    update_time DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    UNIQUE KEY uk_order_sku (order_sn, sku_id),
+    KEY idx_sku_status (sku_id, status)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='库存预占记录';
@@ -0,0 +1,28 @@
+-- 限量秒杀活动：Redis 计数准入、排队异步下单，订单通过 flash_sale_orders 关联活动
+CREATE TABLE IF NOT EXISTS plant.flash_sales
+(
+    id             BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '主键ID',
+    sku_id         BIGINT UNSIGNED NOT NULL COMMENT 'SKU ID',
+    sale_price     DECIMAL(10, 2)  NOT NULL COMMENT '秒杀价',
+    quantity       INT UNSIGNED    NOT NULL COMMENT '活动限量',
+    per_user_limit INT UNSIGNED    NOT NULL DEFAULT 1 COMMENT '每人限购',
+    start_time     DATETIME        NOT NULL COMMENT '开始时间',
+    end_time       DATETIME        NOT NULL COMMENT '结束时间',
+    create_time    DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    update_time    DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    KEY idx_start_time (start_time)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='限量秒杀活动';
+
+CREATE TABLE IF NOT EXISTS plant.flash_sale_orders
+(
+    id          BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '主键ID',
+    sale_id     BIGINT UNSIGNED NOT NULL COMMENT '活动ID',
+    user_id     BIGINT UNSIGNED NOT NULL COMMENT '用户ID',
+    order_sn    VARCHAR(32)     NOT NULL COMMENT '订单号',
+    quantity    INT UNSIGNED    NOT NULL COMMENT '购买数量',
+    create_time DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    UNIQUE KEY uk_order_sn (order_sn),
+    KEY idx_sale_user (sale_id, user_id)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='秒杀活动订单';
@@ -0,0 +1,22 @@
+-- 金额列统一为 DECIMAL(12,2)：代码侧以整数分（pkg/money）读写，读取时按十进制字符串精确解析
+-- 执行前先检查是否存在超过两位小数的历史数据，MODIFY 时会被四舍五入：
+--   SELECT id, price FROM plant.plant_sku WHERE price <> ROUND(price, 2);
+--   SELECT id, total_amount, pay_amount FROM plant.orders WHERE total_amount <> ROUND(total_amount, 2) OR pay_amount <> ROUND(pay_amount, 2);
+ALTER TABLE plant.plant_sku
+    MODIFY COLUMN price DECIMAL(12, 2) NOT NULL DEFAULT 0 COMMENT '单价（元）';
+
+ALTER TABLE plant.plants
+    MODIFY COLUMN min_price DECIMAL(12, 2) NOT NULL DEFAULT 0 COMMENT '起始价格（各SKU最低价）';
+
+ALTER TABLE plant.orders
+    MODIFY COLUMN total_amount DECIMAL(12, 2) NOT NULL DEFAULT 0 COMMENT '商品总额（元）',
+    MODIFY COLUMN pay_amount   DECIMAL(12, 2) NOT NULL DEFAULT 0 COMMENT '实付金额（元）';
+
+ALTER TABLE plant.order_items
+    MODIFY COLUMN price DECIMAL(12, 2) NOT NULL DEFAULT 0 COMMENT '下单时单价（元）';
+
+ALTER TABLE plant.cart_items
+    MODIFY COLUMN price_at_add DECIMAL(12, 2) NOT NULL DEFAULT 0 COMMENT '加入购物车时的单价';
+
+ALTER TABLE plant.flash_sales
+    MODIFY COLUMN sale_price DECIMAL(12, 2) NOT NULL COMMENT '秒杀价';
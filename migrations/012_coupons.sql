@@ -0,0 +1,52 @@
+-- 优惠券：coupons 为券模板，user_coupons 为用户领取的券，下单时在订单事务内核销
+CREATE TABLE IF NOT EXISTS plant.coupons
+(
+    id             BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '主键ID',
+    name           VARCHAR(64)     NOT NULL COMMENT '名称',
+    type           TINYINT         NOT NULL COMMENT '类型：1立减 2折扣 3满减 4免运费',
+    amount         DECIMAL(12, 2)  NOT NULL DEFAULT 0 COMMENT '立减/满减金额',
+    percent_off    INT UNSIGNED    NOT NULL DEFAULT 0 COMMENT '折扣券减免百分比（如 15 表示 85 折）',
+    max_discount   DECIMAL(12, 2)  NOT NULL DEFAULT 0 COMMENT '折扣券最高优惠，0 表示不限',
+    threshold      DECIMAL(12, 2)  NOT NULL DEFAULT 0 COMMENT '使用门槛（商品金额），0 表示无门槛',
+    total_quantity INT UNSIGNED    NOT NULL DEFAULT 0 COMMENT '发放总量，0 表示不限',
+    claimed_count  INT UNSIGNED    NOT NULL DEFAULT 0 COMMENT '已领取数量',
+    per_user_limit INT UNSIGNED    NOT NULL DEFAULT 1 COMMENT '每人限领',
+    start_time     DATETIME        NOT NULL COMMENT '生效时间',
+    end_time       DATETIME        NOT NULL COMMENT '失效时间',
+    is_active      TINYINT(1)      NOT NULL DEFAULT 1 COMMENT '是否可领取',
+    create_time    DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    update_time    DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间'
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='优惠券';
+
+CREATE TABLE IF NOT EXISTS plant.user_coupons
+(
+    id          BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '主键ID',
+    coupon_id   BIGINT UNSIGNED NOT NULL COMMENT '优惠券ID',
+    user_id     BIGINT UNSIGNED NOT NULL COMMENT '用户ID',
+    status      TINYINT         NOT NULL DEFAULT 0 COMMENT '状态：0未使用 1已使用',
+    order_sn    VARCHAR(32)     NULL COMMENT '核销订单号',
+    use_time    DATETIME        NULL COMMENT '核销时间',
+    create_time DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '领取时间',
+    update_time DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    KEY idx_user_status (user_id, status),
+    KEY idx_coupon_user (coupon_id, user_id),
+    KEY idx_order_sn (order_sn)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='用户优惠券';
+
+-- 订单优惠金额，order_items.discount_amount 为按商品金额分摊到各行的优惠，用于售后按行退款
+ALTER TABLE plant.orders
+    ADD COLUMN discount_amount DECIMAL(12, 2) NOT NULL DEFAULT 0 COMMENT '优惠金额' AFTER total_amount;
+
+ALTER TABLE plant.order_items
+    ADD COLUMN discount_amount DECIMAL(12, 2) NOT NULL DEFAULT 0 COMMENT '分摊的优惠金额' AFTER price;
+
+INSERT IGNORE INTO plant.permissions (code, name)
+VALUES ('promotion:manage', '营销活动管理');
+
+INSERT IGNORE INTO plant.role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM plant.roles r
+         JOIN plant.permissions p ON p.code = 'promotion:manage'
+WHERE r.code = 'admin';
@@ -0,0 +1,50 @@
+-- 运费模板：按重量（首重/续重）或按件计费，按收货省份匹配规则，支持包邮门槛与不配送地区；
+-- 植物未指定模板时使用默认模板，无默认模板时免运费
+CREATE TABLE IF NOT EXISTS plant.shipping_templates
+(
+    id               BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '主键ID',
+    name             VARCHAR(64)     NOT NULL COMMENT '名称',
+    charge_mode      TINYINT         NOT NULL COMMENT '计费方式：1按重量 2按件',
+    free_threshold   DECIMAL(12, 2)  NOT NULL DEFAULT 0 COMMENT '包邮门槛（该模板下商品金额），0 表示不包邮',
+    excluded_regions VARCHAR(1024)   NOT NULL DEFAULT '' COMMENT '不配送的省份，逗号分隔',
+    is_default       TINYINT(1)      NOT NULL DEFAULT 0 COMMENT '是否默认模板',
+    create_time      DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    update_time      DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间'
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='运费模板';
+
+CREATE TABLE IF NOT EXISTS plant.shipping_template_rules
+(
+    id              BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '主键ID',
+    template_id     BIGINT UNSIGNED NOT NULL COMMENT '模板ID',
+    regions         VARCHAR(1024)   NOT NULL DEFAULT '' COMMENT '适用省份，逗号分隔，为空表示其他地区',
+    first_unit      INT UNSIGNED    NOT NULL COMMENT '首重（克）/首件（件）',
+    first_fee       DECIMAL(12, 2)  NOT NULL COMMENT '首重/首件运费',
+    additional_unit INT UNSIGNED    NOT NULL DEFAULT 1 COMMENT '续重（克）/续件（件）',
+    additional_fee  DECIMAL(12, 2)  NOT NULL DEFAULT 0 COMMENT '续重/续件运费',
+    KEY idx_template_id (template_id)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='运费模板规则';
+
+-- 植物季节性配送限制，如冬季不发往寒冷省份；起止日期为 MM-DD，起始晚于结束时表示跨年
+CREATE TABLE IF NOT EXISTS plant.plant_shipping_restrictions
+(
+    id          BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '主键ID',
+    plant_id    BIGINT UNSIGNED NOT NULL COMMENT '植物ID',
+    regions     VARCHAR(1024)   NOT NULL COMMENT '限制的省份，逗号分隔',
+    start_day   CHAR(5)         NOT NULL COMMENT '开始日期 MM-DD',
+    end_day     CHAR(5)         NOT NULL COMMENT '结束日期 MM-DD（含）',
+    reason      VARCHAR(128)    NOT NULL DEFAULT '' COMMENT '限制原因，展示给用户',
+    create_time DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    KEY idx_plant_id (plant_id)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='植物季节性配送限制';
+
+ALTER TABLE plant.plants
+    ADD COLUMN shipping_template_id BIGINT UNSIGNED NULL COMMENT '运费模板ID，为空使用默认模板';
+
+ALTER TABLE plant.plant_sku
+    ADD COLUMN weight_grams INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '计费重量（克，含包装）' AFTER stock;
+
+ALTER TABLE plant.orders
+    ADD COLUMN shipping_fee DECIMAL(12, 2) NOT NULL DEFAULT 0 COMMENT '运费' AFTER total_amount;
@@ -0,0 +1,33 @@
+-- 售后申请：按订单项发起，审核通过后原路退款（可选归还库存），换货由客服线下补发
+CREATE TABLE IF NOT EXISTS plant.after_sales
+(
+    id            BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '主键ID',
+    order_id      BIGINT UNSIGNED NOT NULL COMMENT '订单ID',
+    order_sn      VARCHAR(32)     NOT NULL COMMENT '订单号',
+    order_item_id BIGINT UNSIGNED NOT NULL COMMENT '订单项ID',
+    user_id       BIGINT UNSIGNED NOT NULL COMMENT '用户ID',
+    type          TINYINT         NOT NULL COMMENT '类型：1仅退款 2退货退款 3换货',
+    quantity      INT UNSIGNED    NOT NULL COMMENT '售后数量',
+    reason        VARCHAR(64)     NOT NULL COMMENT '售后原因',
+    description   VARCHAR(512)    NOT NULL DEFAULT '' COMMENT '问题描述',
+    images        TEXT            NULL COMMENT '凭证图片 OSS Key（JSON 数组）',
+    refund_amount DECIMAL(12, 2)  NOT NULL DEFAULT 0 COMMENT '退款金额（申请时为可退金额，审核时可调低）',
+    restock       TINYINT(1)      NOT NULL DEFAULT 0 COMMENT '退款后是否归还库存',
+    status        TINYINT         NOT NULL DEFAULT 0 COMMENT '状态：0待审核 1已同意 2已驳回 3已完成 4已撤销',
+    admin_remark  VARCHAR(255)    NOT NULL DEFAULT '' COMMENT '审核备注',
+    handler_id    BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '审核人ID',
+    handle_time   DATETIME        NULL COMMENT '审核时间',
+    refund_no     VARCHAR(32)     NOT NULL DEFAULT '' COMMENT '退款单号，重试退款时保持不变',
+    refund_error  VARCHAR(255)    NOT NULL DEFAULT '' COMMENT '最近一次退款失败原因',
+    complete_time DATETIME        NULL COMMENT '完成时间',
+    create_time   DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    update_time   DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    KEY idx_order_id (order_id),
+    KEY idx_order_item_id (order_item_id),
+    KEY idx_user_id (user_id),
+    KEY idx_status (status)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='售后申请';
+
+ALTER TABLE plant.orders
+    ADD COLUMN refund_amount DECIMAL(12, 2) NOT NULL DEFAULT 0 COMMENT '累计退款金额' AFTER pay_amount;
@@ -0,0 +1,3 @@
+-- 订单列表按状态筛选与游标分页：WHERE user_id = ? [AND order_status = ?] AND id < ? ORDER BY id DESC
+ALTER TABLE plant.orders
+    ADD KEY idx_user_status_id (user_id, order_status, id);
@@ -0,0 +1,45 @@
+-- 发货包裹：一个订单可拆分为多个包裹，每个包裹对应一个运单
+CREATE TABLE IF NOT EXISTS plant.shipments
+(
+    id           BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '主键ID',
+    order_id     BIGINT UNSIGNED NOT NULL COMMENT '订单ID',
+    order_sn     VARCHAR(32)     NOT NULL COMMENT '订单号',
+    carrier      VARCHAR(32)     NOT NULL COMMENT '物流公司编码',
+    tracking_no  VARCHAR(64)     NOT NULL COMMENT '运单号',
+    status       VARCHAR(32)     NOT NULL DEFAULT 'accepted' COMMENT '最新轨迹状态',
+    shipped_at   DATETIME        NOT NULL COMMENT '发货时间',
+    delivered_at DATETIME        NULL COMMENT '签收时间',
+    operator_id  BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '发货操作人ID',
+    remark       VARCHAR(255)    NOT NULL DEFAULT '' COMMENT '备注',
+    create_time  DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    update_time  DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    UNIQUE KEY uk_carrier_tracking (carrier, tracking_no),
+    KEY idx_order_id (order_id)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='发货包裹';
+
+-- 包裹内容：包裹中各订单项的件数
+CREATE TABLE IF NOT EXISTS plant.shipment_items
+(
+    id            BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '主键ID',
+    shipment_id   BIGINT UNSIGNED NOT NULL COMMENT '包裹ID',
+    order_item_id BIGINT UNSIGNED NOT NULL COMMENT '订单项ID',
+    quantity      INT UNSIGNED    NOT NULL COMMENT '件数',
+    KEY idx_shipment_id (shipment_id),
+    KEY idx_order_item_id (order_item_id)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='包裹内容';
+
+-- 物流轨迹：物流方推送或主动查询写入，重复推送按（包裹、时间、状态）去重
+CREATE TABLE IF NOT EXISTS plant.shipment_events
+(
+    id          BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '主键ID',
+    shipment_id BIGINT UNSIGNED NOT NULL COMMENT '包裹ID',
+    event_time  DATETIME        NOT NULL COMMENT '轨迹时间',
+    status      VARCHAR(32)     NOT NULL COMMENT '轨迹状态',
+    location    VARCHAR(128)    NOT NULL DEFAULT '' COMMENT '所在地',
+    description VARCHAR(255)    NOT NULL DEFAULT '' COMMENT '轨迹描述',
+    create_time DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    UNIQUE KEY uk_shipment_event (shipment_id, event_time, status)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='物流轨迹';
@@ -0,0 +1,16 @@
+-- 订单内部备注：仅后台可见
+CREATE TABLE IF NOT EXISTS plant.order_notes
+(
+    id          BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '主键ID',
+    order_id    BIGINT UNSIGNED NOT NULL COMMENT '订单ID',
+    admin_id    BIGINT UNSIGNED NOT NULL COMMENT '备注人ID',
+    content     VARCHAR(500)    NOT NULL COMMENT '备注内容',
+    create_time DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    KEY idx_order_id (order_id)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='订单内部备注';
+
+-- 后台按收货人手机号、下单时间检索订单
+ALTER TABLE plant.orders
+    ADD KEY idx_receiver_phone (receiver_phone),
+    ADD KEY idx_create_time (create_time);
@@ -0,0 +1,85 @@
+-- 收货地址与订单快照记录省级行政区划代码（GB/T 2260），运费模板与配送限制按代码匹配
+ALTER TABLE plant.user_addresses
+    ADD COLUMN province_code CHAR(6) NOT NULL DEFAULT '' COMMENT '省级行政区划代码' AFTER province;
+
+ALTER TABLE plant.orders
+    ADD COLUMN receiver_province_code CHAR(6) NOT NULL DEFAULT '' COMMENT '收货地址快照：省级行政区划代码' AFTER receiver_province;
+
+-- 按省份全称或简称回填已有数据，无法识别的保持为空（下单时要求重新编辑地址）
+UPDATE plant.user_addresses
+SET province_code = CASE
+    WHEN province IN ('北京市', '北京') THEN '110000'
+    WHEN province IN ('天津市', '天津') THEN '120000'
+    WHEN province IN ('河北省', '河北') THEN '130000'
+    WHEN province IN ('山西省', '山西') THEN '140000'
+    WHEN province IN ('内蒙古自治区', '内蒙古') THEN '150000'
+    WHEN province IN ('辽宁省', '辽宁') THEN '210000'
+    WHEN province IN ('吉林省', '吉林') THEN '220000'
+    WHEN province IN ('黑龙江省', '黑龙江') THEN '230000'
+    WHEN province IN ('上海市', '上海') THEN '310000'
+    WHEN province IN ('江苏省', '江苏') THEN '320000'
+    WHEN province IN ('浙江省', '浙江') THEN '330000'
+    WHEN province IN ('安徽省', '安徽') THEN '340000'
+    WHEN province IN ('福建省', '福建') THEN '350000'
+    WHEN province IN ('江西省', '江西') THEN '360000'
+    WHEN province IN ('山东省', '山东') THEN '370000'
+    WHEN province IN ('河南省', '河南') THEN '410000'
+    WHEN province IN ('湖北省', '湖北') THEN '420000'
+    WHEN province IN ('湖南省', '湖南') THEN '430000'
+    WHEN province IN ('广东省', '广东') THEN '440000'
+    WHEN province IN ('广西壮族自治区', '广西') THEN '450000'
+    WHEN province IN ('海南省', '海南') THEN '460000'
+    WHEN province IN ('重庆市', '重庆') THEN '500000'
+    WHEN province IN ('四川省', '四川') THEN '510000'
+    WHEN province IN ('贵州省', '贵州') THEN '520000'
+    WHEN province IN ('云南省', '云南') THEN '530000'
+    WHEN province IN ('西藏自治区', '西藏') THEN '540000'
+    WHEN province IN ('陕西省', '陕西') THEN '610000'
+    WHEN province IN ('甘肃省', '甘肃') THEN '620000'
+    WHEN province IN ('青海省', '青海') THEN '630000'
+    WHEN province IN ('宁夏回族自治区', '宁夏') THEN '640000'
+    WHEN province IN ('新疆维吾尔自治区', '新疆') THEN '650000'
+    WHEN province IN ('台湾省', '台湾') THEN '710000'
+    WHEN province IN ('香港特别行政区', '香港') THEN '810000'
+    WHEN province IN ('澳门特别行政区', '澳门') THEN '820000'
+    ELSE '' END
+WHERE province_code = '';
+
+UPDATE plant.orders
+SET receiver_province_code = CASE
+    WHEN receiver_province IN ('北京市', '北京') THEN '110000'
+    WHEN receiver_province IN ('天津市', '天津') THEN '120000'
+    WHEN receiver_province IN ('河北省', '河北') THEN '130000'
+    WHEN receiver_province IN ('山西省', '山西') THEN '140000'
+    WHEN receiver_province IN ('内蒙古自治区', '内蒙古') THEN '150000'
+    WHEN receiver_province IN ('辽宁省', '辽宁') THEN '210000'
+    WHEN receiver_province IN ('吉林省', '吉林') THEN '220000'
+    WHEN receiver_province IN ('黑龙江省', '黑龙江') THEN '230000'
+    WHEN receiver_province IN ('上海市', '上海') THEN '310000'
+    WHEN receiver_province IN ('江苏省', '江苏') THEN '320000'
+    WHEN receiver_province IN ('浙江省', '浙江') THEN '330000'
+    WHEN receiver_province IN ('安徽省', '安徽') THEN '340000'
+    WHEN receiver_province IN ('福建省', '福建') THEN '350000'
+    WHEN receiver_province IN ('江西省', '江西') THEN '360000'
+    WHEN receiver_province IN ('山东省', '山东') THEN '370000'
+    WHEN receiver_province IN ('河南省', '河南') THEN '410000'
+    WHEN receiver_province IN ('湖北省', '湖北') THEN '420000'
+    WHEN receiver_province IN ('湖南省', '湖南') THEN '430000'
+    WHEN receiver_province IN ('广东省', '广东') THEN '440000'
+    WHEN receiver_province IN ('广西壮族自治区', '广西') THEN '450000'
+    WHEN receiver_province IN ('海南省', '海南') THEN '460000'
+    WHEN receiver_province IN ('重庆市', '重庆') THEN '500000'
+    WHEN receiver_province IN ('四川省', '四川') THEN '510000'
+    WHEN receiver_province IN ('贵州省', '贵州') THEN '520000'
+    WHEN receiver_province IN ('云南省', '云南') THEN '530000'
+    WHEN receiver_province IN ('西藏自治区', '西藏') THEN '540000'
+    WHEN receiver_province IN ('陕西省', '陕西') THEN '610000'
+    WHEN receiver_province IN ('甘肃省', '甘肃') THEN '620000'
+    WHEN receiver_province IN ('青海省', '青海') THEN '630000'
+    WHEN receiver_province IN ('宁夏回族自治区', '宁夏') THEN '640000'
+    WHEN receiver_province IN ('新疆维吾尔自治区', '新疆') THEN '650000'
+    WHEN receiver_province IN ('台湾省', '台湾') THEN '710000'
+    WHEN receiver_province IN ('香港特别行政区', '香港') THEN '810000'
+    WHEN receiver_province IN ('澳门特别行政区', '澳门') THEN '820000'
+    ELSE '' END
+WHERE receiver_province_code = '';
@@ -0,0 +1,13 @@
+-- 植物累计销量：支付成功时在同一事务内累加，列表按人气排序时直接使用，避免每次请求汇总订单
+ALTER TABLE plant.plants
+    ADD COLUMN sales_count BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '累计销量（支付成功时累加）',
+    ADD KEY idx_sales_count (sales_count);
+
+-- 按已支付（含已发货、已送达、已完成）订单回填
+UPDATE plant.plants p
+    JOIN (SELECT oi.plant_id, SUM(oi.quantity) sales
+          FROM plant.order_items oi
+                   JOIN plant.orders o ON o.id = oi.order_id
+          WHERE o.order_status IN (1, 2, 3, 4)
+          GROUP BY oi.plant_id) s ON s.plant_id = p.id
+SET p.sales_count = s.sales;
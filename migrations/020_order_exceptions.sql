@@ -0,0 +1,15 @@
+-- 订单异常记录：支付确认时库存不足等需人工处理的情况，处理后标记为已解决
+CREATE TABLE IF NOT EXISTS plant.order_exceptions
+(
+    id           BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT '主键ID',
+    order_sn     VARCHAR(32)     NOT NULL COMMENT '订单号',
+    kind         VARCHAR(32)     NOT NULL COMMENT '异常类型',
+    detail       VARCHAR(500)    NOT NULL COMMENT '异常详情',
+    resolved     TINYINT(1)      NOT NULL DEFAULT 0 COMMENT '是否已处理',
+    resolved_by  BIGINT UNSIGNED NULL COMMENT '处理人ID',
+    resolve_time DATETIME        NULL COMMENT '处理时间',
+    create_time  DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    KEY idx_order_sn (order_sn),
+    KEY idx_resolved (resolved, id)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='订单异常记录';
@@ -1,6 +1,7 @@
 package aliyun
 
 import (
+	"errors"
 	"log"
 	"os"
 )
@@ -14,19 +15,29 @@ type AliConfig struct {
 	OSSBucketName   string
 }
 
-func LoadAliConfig() AliConfig {
-	// 从环境变量读取敏感配置（避免硬编码）
-	accessKeyID := os.Getenv("ALI_OSS_ACCESS_KEY_ID")         // 阿里云访问密钥ID
-	accessKeySecret := os.Getenv("ALI_OSS_ACCESS_KEY_SECRET") // 阿里云访问密钥Secret
-	roleARN := os.Getenv("ALI_OSS_ROLE_ARN")                  // 阿里云角色ARN
+var ErrAliConfigMissing = errors.New("未配置环境变量 ALI_OSS_ACCESS_KEY_ID / ALI_OSS_ACCESS_KEY_SECRET / ALI_OSS_ROLE_ARN")
 
+func LoadAliConfig() AliConfig {
+	cfg, err := ReadAliConfig()
 	// 校验必填配置
-	if accessKeyID == "" || accessKeySecret == "" || roleARN == "" {
+	if err != nil {
 		log.Fatalf("错误：必须配置以下环境变量后运行！\n" +
 			"  ALI_OSS_ACCESS_KEY_ID\n" + // 访问密钥ID
 			"  ALI_OSS_ACCESS_KEY_SECRET\n" + // 访问密钥Secret
 			"  ALI_OSS_ROLE_ARN") // 角色ARN
 	}
+	return cfg
+}
+
+// ReadAliConfig 从环境变量读取配置，缺少必填项时返回 ErrAliConfigMissing（供请求处理中使用，避免进程退出）
+func ReadAliConfig() (AliConfig, error) {
+	// 从环境变量读取敏感配置（避免硬编码）
+	accessKeyID := os.Getenv("ALI_OSS_ACCESS_KEY_ID")         // 阿里云访问密钥ID
+	accessKeySecret := os.Getenv("ALI_OSS_ACCESS_KEY_SECRET") // 阿里云访问密钥Secret
+	roleARN := os.Getenv("ALI_OSS_ROLE_ARN")                  // 阿里云角色ARN
+	if accessKeyID == "" || accessKeySecret == "" || roleARN == "" {
+		return AliConfig{}, ErrAliConfigMissing
+	}
 
 	return AliConfig{
 		AccessKeyID:     accessKeyID,              // 访问密钥ID
@@ -35,5 +46,5 @@ func LoadAliConfig() AliConfig {
 		RoleSessionName: "aliyun-sts-session-123", // STS临时会话名称
 		OSSEndpoint:     "oss-cn-beijing.aliyuncs.com",
 		OSSBucketName:   "public-plant-images", // OSS存储桶名称
-	}
+	}, nil
 }
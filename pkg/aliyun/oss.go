@@ -47,6 +47,36 @@ func DownloadImage(cfg AliConfig, objectKey string) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// UploadObject 通过STS凭证上传文件到OSS
+func UploadObject(cfg AliConfig, objectKey string, reader io.Reader, contentType string) error {
+	stsResp, err := GetSTSCredentials(cfg)
+	if err != nil {
+		return err
+	}
+
+	// 创建OSS客户端
+	client, err := oss.New(
+		cfg.OSSEndpoint,
+		stsResp.Credentials.AccessKeyId,
+		stsResp.Credentials.AccessKeySecret,
+		oss.SecurityToken(stsResp.Credentials.SecurityToken),
+	)
+	if err != nil {
+		return fmt.Errorf("创建OSS客户端失败: %w", err)
+	}
+
+	// 获取Bucket
+	bucket, err := client.Bucket(cfg.OSSBucketName)
+	if err != nil {
+		return fmt.Errorf("获取Bucket失败: %w", err)
+	}
+
+	if err := bucket.PutObject(objectKey, reader, oss.ContentType(contentType)); err != nil {
+		return fmt.Errorf("上传文件失败: %w", err)
+	}
+	return nil
+}
+
 func GetOssUrl(cfg AliConfig, objectKey string, width int, height int) (string, error) {
 	stsResp, err := GetSTSCredentials(cfg)
 	if err != nil {
@@ -0,0 +1,87 @@
+package carrier
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sunzhaoc/plant_be/pkg/utils"
+)
+
+// EventStatus 物流轨迹节点状态
+type EventStatus string
+
+const (
+	StatusAccepted       EventStatus = "accepted"         // 已揽收
+	StatusInTransit      EventStatus = "in_transit"       // 运输中
+	StatusOutForDelivery EventStatus = "out_for_delivery" // 派送中
+	StatusDelivered      EventStatus = "delivered"        // 已签收
+	StatusException      EventStatus = "exception"        // 异常（破损、退回等）
+)
+
+var statusLabels = map[EventStatus]string{
+	StatusAccepted:       "已揽收",
+	StatusInTransit:      "运输中",
+	StatusOutForDelivery: "派送中",
+	StatusDelivered:      "已签收",
+	StatusException:      "异常",
+}
+
+// Label 状态的中文描述
+func (s EventStatus) Label() string {
+	if label, ok := statusLabels[s]; ok {
+		return label
+	}
+	return "未知状态"
+}
+
+// Event 一条物流轨迹
+type Event struct {
+	TrackingNo  string
+	Time        time.Time
+	Status      EventStatus
+	Location    string
+	Description string
+}
+
+// Carrier 物流公司抽象（快递100/菜鸟风格：订阅运单后由物流方推送轨迹，也支持主动查询）
+type Carrier interface {
+	// Name 物流公司编码，对应推送路由 /api/carrier/notify/:carrier
+	Name() string
+	// Subscribe 订阅运单轨迹推送
+	Subscribe(ctx context.Context, trackingNo string) error
+	// Track 主动查询运单的全部轨迹
+	Track(ctx context.Context, trackingNo string) ([]Event, error)
+	// VerifyPush 校验轨迹推送签名并解析推送内容
+	VerifyPush(r *http.Request) ([]Event, error)
+	// PushAck 返回给物流方的应答（状态码、内容）
+	PushAck(success bool) (int, string)
+}
+
+var carriers = make(map[string]Carrier)
+
+// Init 按配置初始化所有启用的物流公司，未启用任何物流公司时发货接口返回不支持
+func Init(cfg CarrierConfig) error {
+	if cfg.Fake.Secret != "" {
+		if utils.IsProduction() {
+			return fmt.Errorf("生产环境不允许启用模拟物流")
+		}
+		Register(NewFakeCarrier(cfg.Fake))
+	}
+	return nil
+}
+
+// Register 注册物流公司，同名会被覆盖
+func Register(c Carrier) {
+	carriers[c.Name()] = c
+}
+
+// Get 按编码获取物流公司
+func Get(name string) (Carrier, error) {
+	c, exists := carriers[name]
+	if !exists {
+		return nil, fmt.Errorf("物流公司[%s]不存在", name)
+	}
+	return c, nil
+}
@@ -0,0 +1,37 @@
+package carrier
+
+import (
+	"log"
+	"os"
+
+	"github.com/spf13/viper"
+)
+
+type FakeConfig struct {
+	Secret string `mapstructure:"-"` // 本地模拟物流的推送签名密钥，取自环境变量 CARRIER_FAKE_SECRET
+}
+
+type CarrierConfig struct {
+	Fake FakeConfig `mapstructure:"fake"`
+}
+
+var CarrierCfg CarrierConfig
+
+func Load() CarrierConfig {
+	// 配置文件路径和名称
+	viper.SetConfigName("config")   // 配置文件名（无后缀）
+	viper.SetConfigType("yaml")     // 配置文件类型
+	viper.AddConfigPath("./config") // 配置文件所在目录
+
+	// 读取配置文件
+	if err := viper.ReadInConfig(); err != nil {
+		log.Fatalf("读取配置文件失败: %v", err)
+	}
+
+	// 解析配置到结构体
+	if err := viper.UnmarshalKey("carrier", &CarrierCfg); err != nil {
+		log.Fatalf("解析配置失败: %v", err)
+	}
+	CarrierCfg.Fake.Secret = os.Getenv("CARRIER_FAKE_SECRET")
+	return CarrierCfg
+}
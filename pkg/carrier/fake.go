@@ -0,0 +1,121 @@
+package carrier
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeCarrier 本地模拟物流，用于开发联调与测试，仅非生产环境启用
+//
+// 轨迹只保存在进程内存中，重启或多实例部署时主动查询可能查不到已推送的轨迹，以数据库中已入库的轨迹为准。
+//
+// 推送为表单提交，参数：tracking_no、status、location、description、event_time（Unix 秒）、sign，
+// 签名算法为 HMAC-SHA256（参数名升序拼接，跳过空值与 sign）
+type FakeCarrier struct {
+	secret []byte
+
+	mu     sync.Mutex
+	events map[string][]Event // trackingNo -> 已产生的轨迹
+}
+
+func NewFakeCarrier(cfg FakeConfig) *FakeCarrier {
+	return &FakeCarrier{
+		secret: []byte(cfg.Secret),
+		events: make(map[string][]Event),
+	}
+}
+
+func (f *FakeCarrier) Name() string {
+	return "fake"
+}
+
+func (f *FakeCarrier) Subscribe(ctx context.Context, trackingNo string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.events[trackingNo]; !ok {
+		f.events[trackingNo] = []Event{}
+	}
+	return nil
+}
+
+func (f *FakeCarrier) Track(ctx context.Context, trackingNo string) ([]Event, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// 内存中没有的运单视为暂无新轨迹，不影响已入库的轨迹
+	return append([]Event(nil), f.events[trackingNo]...), nil
+}
+
+func (f *FakeCarrier) VerifyPush(r *http.Request) ([]Event, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, fmt.Errorf("解析推送参数失败: %w", err)
+	}
+	params := make(map[string]string, len(r.PostForm))
+	for k := range r.PostForm {
+		params[k] = r.PostForm.Get(k)
+	}
+	if !hmac.Equal([]byte(f.sign(params)), []byte(params["sign"])) {
+		return nil, fmt.Errorf("推送签名校验失败")
+	}
+	ts, err := strconv.ParseInt(params["event_time"], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("推送时间格式错误: %w", err)
+	}
+	event := Event{
+		TrackingNo:  params["tracking_no"],
+		Time:        time.Unix(ts, 0),
+		Status:      EventStatus(params["status"]),
+		Location:    params["location"],
+		Description: params["description"],
+	}
+	f.record(event)
+	return []Event{event}, nil
+}
+
+func (f *FakeCarrier) PushAck(success bool) (int, string) {
+	if success {
+		return http.StatusOK, "success"
+	}
+	return http.StatusBadRequest, "fail"
+}
+
+// record 记录轨迹，同一时间同一状态的重复推送只保留一条
+func (f *FakeCarrier) record(event Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, e := range f.events[event.TrackingNo] {
+		if e.Time.Equal(event.Time) && e.Status == event.Status {
+			return
+		}
+	}
+	f.events[event.TrackingNo] = append(f.events[event.TrackingNo], event)
+}
+
+func (f *FakeCarrier) sign(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for k, v := range params {
+		if v == "" || k == "sign" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteByte('&')
+		}
+		sb.WriteString(k + "=" + params[k])
+	}
+	mac := hmac.New(sha256.New, f.secret)
+	mac.Write([]byte(sb.String()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// AdminAuditLog 后台操作审计日志
+type AdminAuditLog struct {
+	Id           uint64    `gorm:"column:id;primaryKey;autoIncrement"`
+	OperatorId   uint64    `gorm:"column:operator_id"`
+	OperatorName string    `gorm:"column:operator_name"`
+	Action       string    `gorm:"column:action"`      // 操作，如 plant.create
+	TargetType   string    `gorm:"column:target_type"` // 操作对象类型，如 plant/sku/image
+	TargetId     uint64    `gorm:"column:target_id"`
+	Before       *string   `gorm:"column:before_data"` // 变更前数据（JSON）
+	After        *string   `gorm:"column:after_data"`  // 变更后数据（JSON）
+	ClientIp     string    `gorm:"column:client_ip"`
+	CreateTime   time.Time `gorm:"column:create_time;autoCreateTime"`
+}
+
+func (a AdminAuditLog) TableName() string {
+	return "admin_audit_logs"
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"time"
+
+	"github.com/sunzhaoc/plant_be/pkg/money"
+)
+
+// AfterSaleType 售后类型
+type AfterSaleType int8
+
+const (
+	AfterSaleRefundOnly AfterSaleType = 1 // 仅退款
+	AfterSaleReturn     AfterSaleType = 2 // 退货退款
+	AfterSaleReplace    AfterSaleType = 3 // 换货
+)
+
+// Refundable 该类型是否需要退款
+func (t AfterSaleType) Refundable() bool {
+	return t == AfterSaleRefundOnly || t == AfterSaleReturn
+}
+
+// AfterSaleStatus 售后状态
+type AfterSaleStatus int8
+
+const (
+	AfterSalePending   AfterSaleStatus = 0 // 待审核
+	AfterSaleApproved  AfterSaleStatus = 1 // 已同意，待退款
+	AfterSaleRejected  AfterSaleStatus = 2 // 已驳回
+	AfterSaleCompleted AfterSaleStatus = 3 // 已完成（已退款或已补发）
+	AfterSaleCancelled AfterSaleStatus = 4 // 用户撤销
+)
+
+var afterSaleStatusLabels = map[AfterSaleStatus]string{
+	AfterSalePending:   "待审核",
+	AfterSaleApproved:  "待退款",
+	AfterSaleRejected:  "已驳回",
+	AfterSaleCompleted: "已完成",
+	AfterSaleCancelled: "已撤销",
+}
+
+// Label 状态的中文描述
+func (s AfterSaleStatus) Label() string {
+	if label, ok := afterSaleStatusLabels[s]; ok {
+		return label
+	}
+	return "未知状态"
+}
+
+// Open 是否仍在处理中
+func (s AfterSaleStatus) Open() bool {
+	return s == AfterSalePending || s == AfterSaleApproved
+}
+
+// AfterSale 售后申请
+type AfterSale struct {
+	Id           uint64          `gorm:"column:id;primaryKey;autoIncrement"`
+	OrderId      uint64          `gorm:"column:order_id"`
+	OrderSn      string          `gorm:"column:order_sn"`
+	OrderItemId  uint64          `gorm:"column:order_item_id"`
+	UserId       uint64          `gorm:"column:user_id"`
+	Type         AfterSaleType   `gorm:"column:type"`
+	Quantity     uint            `gorm:"column:quantity"`
+	Reason       string          `gorm:"column:reason"`
+	Description  string          `gorm:"column:description"`
+	Images       *string         `gorm:"column:images"` // 凭证图片 OSS Key（JSON 数组）
+	RefundAmount money.Money     `gorm:"column:refund_amount"`
+	Restock      bool            `gorm:"column:restock"`
+	Status       AfterSaleStatus `gorm:"column:status;default:0"`
+	AdminRemark  string          `gorm:"column:admin_remark"`
+	HandlerId    uint64          `gorm:"column:handler_id"`
+	HandleTime   *time.Time      `gorm:"column:handle_time"`
+	RefundNo     string          `gorm:"column:refund_no"`
+	RefundError  string          `gorm:"column:refund_error"`
+	CompleteTime *time.Time      `gorm:"column:complete_time"`
+	CreateTime   time.Time       `gorm:"column:create_time;autoCreateTime"`
+	UpdateTime   time.Time       `gorm:"column:update_time;autoUpdateTime"`
+}
+
+func (a AfterSale) TableName() string {
+	return "after_sales"
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/sunzhaoc/plant_be/pkg/money"
+)
+
+// CartItem 购物车项（Redis 购物车的持久化副本）
+type CartItem struct {
+	UserId     uint64      `gorm:"column:user_id;primaryKey"`
+	PlantId    uint64      `gorm:"column:plant_id;primaryKey"`
+	SkuId      uint64      `gorm:"column:sku_id;primaryKey"`
+	Quantity   int         `gorm:"column:quantity"`
+	PriceAtAdd money.Money `gorm:"column:price_at_add"` // 加入购物车时的单价
+	Selected   bool        `gorm:"column:selected"`     // 是否勾选结算
+	AddTime    time.Time   `gorm:"column:add_time"`     // 首次加入时间
+	UpdateTime time.Time   `gorm:"column:update_time;autoUpdateTime"`
+}
+
+func (c CartItem) TableName() string {
+	return "cart_items"
+}
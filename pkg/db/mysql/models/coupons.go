@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"github.com/sunzhaoc/plant_be/pkg/money"
+)
+
+// CouponType 优惠券类型
+type CouponType int8
+
+const (
+	CouponFixed        CouponType = 1 // 立减
+	CouponPercent      CouponType = 2 // 折扣
+	CouponThreshold    CouponType = 3 // 满减
+	CouponFreeShipping CouponType = 4 // 免运费
+)
+
+// UserCouponStatus 用户优惠券状态
+type UserCouponStatus int8
+
+const (
+	UserCouponUnused UserCouponStatus = 0 // 未使用
+	UserCouponUsed   UserCouponStatus = 1 // 已使用
+)
+
+// Coupon 优惠券模板
+type Coupon struct {
+	Id            uint64      `gorm:"column:id;primaryKey;autoIncrement"`
+	Name          string      `gorm:"column:name"`
+	Type          CouponType  `gorm:"column:type"`
+	Amount        money.Money `gorm:"column:amount"`         // 立减/满减金额
+	PercentOff    uint        `gorm:"column:percent_off"`    // 折扣券减免百分比
+	MaxDiscount   money.Money `gorm:"column:max_discount"`   // 折扣券最高优惠，0 表示不限
+	Threshold     money.Money `gorm:"column:threshold"`      // 使用门槛，0 表示无门槛
+	TotalQuantity uint        `gorm:"column:total_quantity"` // 发放总量，0 表示不限
+	ClaimedCount  uint        `gorm:"column:claimed_count"`  // 已领取数量
+	PerUserLimit  uint        `gorm:"column:per_user_limit"` // 每人限领
+	StartTime     time.Time   `gorm:"column:start_time"`
+	EndTime       time.Time   `gorm:"column:end_time"`
+	IsActive      bool        `gorm:"column:is_active"` // 是否可领取
+	CreateTime    time.Time   `gorm:"column:create_time;autoCreateTime"`
+	UpdateTime    time.Time   `gorm:"column:update_time;autoUpdateTime"`
+}
+
+func (c Coupon) TableName() string {
+	return "coupons"
+}
+
+// UserCoupon 用户领取的优惠券
+type UserCoupon struct {
+	Id         uint64           `gorm:"column:id;primaryKey;autoIncrement"`
+	CouponId   uint64           `gorm:"column:coupon_id"`
+	UserId     uint64           `gorm:"column:user_id"`
+	Status     UserCouponStatus `gorm:"column:status;default:0"`
+	OrderSn    *string          `gorm:"column:order_sn"`
+	UseTime    *time.Time       `gorm:"column:use_time"`
+	CreateTime time.Time        `gorm:"column:create_time;autoCreateTime"`
+	UpdateTime time.Time        `gorm:"column:update_time;autoUpdateTime"`
+}
+
+func (u UserCoupon) TableName() string {
+	return "user_coupons"
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/sunzhaoc/plant_be/pkg/money"
+)
+
+// FlashSale 限量秒杀活动
+type FlashSale struct {
+	Id           uint64      `gorm:"column:id;primaryKey;autoIncrement"`
+	SkuId        uint64      `gorm:"column:sku_id"`
+	SalePrice    money.Money `gorm:"column:sale_price"`     // 秒杀价
+	Quantity     uint        `gorm:"column:quantity"`       // 活动限量
+	PerUserLimit uint        `gorm:"column:per_user_limit"` // 每人限购
+	StartTime    time.Time   `gorm:"column:start_time"`
+	EndTime      time.Time   `gorm:"column:end_time"`
+	CreateTime   time.Time   `gorm:"column:create_time;autoCreateTime"`
+	UpdateTime   time.Time   `gorm:"column:update_time;autoUpdateTime"`
+}
+
+func (f FlashSale) TableName() string {
+	return "flash_sales"
+}
+
+// FlashSaleOrder 秒杀活动订单，与订单在同一事务中写入
+type FlashSaleOrder struct {
+	Id         uint64    `gorm:"column:id;primaryKey;autoIncrement"`
+	SaleId     uint64    `gorm:"column:sale_id"`
+	UserId     uint64    `gorm:"column:user_id"`
+	OrderSn    string    `gorm:"column:order_sn"`
+	Quantity   uint      `gorm:"column:quantity"`
+	CreateTime time.Time `gorm:"column:create_time;autoCreateTime"`
+}
+
+func (f FlashSaleOrder) TableName() string {
+	return "flash_sale_orders"
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// OrderExceptionKind 订单异常类型
+type OrderExceptionKind string
+
+const (
+	ExceptionOversold        OrderExceptionKind = "oversold"          // 支付确认时库存不足
+	ExceptionPaidAfterClosed OrderExceptionKind = "paid_after_closed" // 已取消等非待支付订单收到支付成功，需原路退款
+)
+
+// OrderException 订单异常记录，需后台人工处理
+type OrderException struct {
+	Id          uint64             `gorm:"column:id;primaryKey;autoIncrement"`
+	OrderSn     string             `gorm:"column:order_sn"`
+	Kind        OrderExceptionKind `gorm:"column:kind"`
+	Detail      string             `gorm:"column:detail"`
+	Resolved    bool               `gorm:"column:resolved"`
+	ResolvedBy  *uint64            `gorm:"column:resolved_by"`
+	ResolveTime *time.Time         `gorm:"column:resolve_time"`
+	CreateTime  time.Time          `gorm:"column:create_time;autoCreateTime"`
+}
+
+func (e OrderException) TableName() string {
+	return "order_exceptions"
+}
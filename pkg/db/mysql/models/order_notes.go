@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// OrderNote 订单内部备注，仅后台可见
+type OrderNote struct {
+	Id         uint64    `gorm:"column:id;primaryKey;autoIncrement"`
+	OrderId    uint64    `gorm:"column:order_id"`
+	AdminId    uint64    `gorm:"column:admin_id"`
+	Content    string    `gorm:"column:content"`
+	CreateTime time.Time `gorm:"column:create_time;autoCreateTime"`
+}
+
+func (n OrderNote) TableName() string {
+	return "order_notes"
+}
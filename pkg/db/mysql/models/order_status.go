@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+)
+
+// OrderStatus 订单状态
+type OrderStatus int
+
+const (
+	OrderStatusPendingPayment OrderStatus = 0 // 待支付
+	OrderStatusPaid           OrderStatus = 1 // 已支付（待发货）
+	OrderStatusShipped        OrderStatus = 2 // 已发货
+	OrderStatusDelivered      OrderStatus = 3 // 已送达
+	OrderStatusCompleted      OrderStatus = 4 // 已完成
+	OrderStatusCancelled      OrderStatus = 5 // 已取消
+	OrderStatusRefunding      OrderStatus = 6 // 退款中
+	OrderStatusRefunded       OrderStatus = 7 // 已退款
+)
+
+var orderStatusLabels = map[OrderStatus]string{
+	OrderStatusPendingPayment: "待支付",
+	OrderStatusPaid:           "待发货",
+	OrderStatusShipped:        "已发货",
+	OrderStatusDelivered:      "已送达",
+	OrderStatusCompleted:      "已完成",
+	OrderStatusCancelled:      "已取消",
+	OrderStatusRefunding:      "退款中",
+	OrderStatusRefunded:       "已退款",
+}
+
+// orderStatusTransitions 合法的状态流转，key 为当前状态，value 为可流转到的状态
+var orderStatusTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusPendingPayment: {OrderStatusPaid, OrderStatusCancelled},
+	OrderStatusPaid:           {OrderStatusShipped, OrderStatusRefunding},
+	// 用户确认收货时可跳过已送达直接完成
+	OrderStatusShipped:   {OrderStatusDelivered, OrderStatusCompleted, OrderStatusRefunding},
+	OrderStatusDelivered: {OrderStatusCompleted, OrderStatusRefunding},
+	OrderStatusCompleted: {OrderStatusRefunding},
+	// 退款被驳回时回到发起退款前的状态，具体为哪一个由 orders.Transition 按状态记录校验
+	OrderStatusRefunding: {OrderStatusRefunded, OrderStatusPaid, OrderStatusShipped, OrderStatusDelivered, OrderStatusCompleted},
+}
+
+// Label 状态的中文描述
+func (s OrderStatus) Label() string {
+	if label, ok := orderStatusLabels[s]; ok {
+		return label
+	}
+	return "未知状态"
+}
+
+// Valid 是否为已定义的状态
+func (s OrderStatus) Valid() bool {
+	_, ok := orderStatusLabels[s]
+	return ok
+}
+
+// CanTransitionTo 是否允许从当前状态流转到 to
+func (s OrderStatus) CanTransitionTo(to OrderStatus) bool {
+	for _, next := range orderStatusTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderStatusHistory 订单状态变更记录
+type OrderStatusHistory struct {
+	Id           uint64       `gorm:"column:id;primaryKey;autoIncrement"`
+	OrderId      uint64       `gorm:"column:order_id"`
+	OrderSn      string       `gorm:"column:order_sn"`
+	FromStatus   *OrderStatus `gorm:"column:from_status"` // 为空表示订单创建
+	ToStatus     OrderStatus  `gorm:"column:to_status"`
+	OperatorType string       `gorm:"column:operator_type"` // user/admin/system
+	OperatorId   uint64       `gorm:"column:operator_id"`
+	Remark       string       `gorm:"column:remark"`
+	CreateTime   time.Time    `gorm:"column:create_time;autoCreateTime"`
+}
+
+func (h OrderStatusHistory) TableName() string {
+	return "order_status_history"
+}
@@ -2,20 +2,33 @@ package models
 
 import (
 	"time"
+
+	"github.com/sunzhaoc/plant_be/pkg/money"
 )
 
 type Orders struct {
-	Id              uint64    `gorm:"column:id;primaryKey;autoIncrement"`
-	OrderSn         string    `gorm:"column:order_sn;unique"`
-	UserId          uint64    `gorm:"column:user_id"`
-	TotalAmount     float64   `gorm:"column:total_amount"`
-	PayAmount       float64   `gorm:"column:pay_amount"`
-	OrderStatus     int       `gorm:"column:order_status;default:0"`
-	ReceiverName    string    `gorm:"column:receiver_name"`
-	ReceiverPhone   string    `gorm:"column:receiver_phone"`
-	ReceiverAddress string    `gorm:"column:receiver_address"`
-	CreateTime      time.Time `gorm:"column:create_time;autoCreateTime"`
-	UpdateTime      time.Time `gorm:"column:update_time;autoUpdateTime"`
+	Id                   uint64      `gorm:"column:id;primaryKey;autoIncrement"`
+	OrderSn              string      `gorm:"column:order_sn;unique"`
+	UserId               uint64      `gorm:"column:user_id"`
+	TotalAmount          money.Money `gorm:"column:total_amount"`
+	ShippingFee          money.Money `gorm:"column:shipping_fee"`    // 运费
+	DiscountAmount       money.Money `gorm:"column:discount_amount"` // 优惠金额
+	PayAmount            money.Money `gorm:"column:pay_amount"`
+	RefundAmount         money.Money `gorm:"column:refund_amount"` // 累计退款金额
+	OrderStatus          OrderStatus `gorm:"column:order_status;default:0"`
+	ReceiverName         string      `gorm:"column:receiver_name"`
+	ReceiverPhone        string      `gorm:"column:receiver_phone"`
+	ReceiverAddress      string      `gorm:"column:receiver_address"`
+	ReceiverProvince     string      `gorm:"column:receiver_province"`      // 收货地址快照：省
+	ReceiverProvinceCode string      `gorm:"column:receiver_province_code"` // 收货地址快照：省级行政区划代码
+	ReceiverCity         string      `gorm:"column:receiver_city"`          // 收货地址快照：市
+	ReceiverArea         string      `gorm:"column:receiver_area"`          // 收货地址快照：区/县
+	ReceiverDetail       string      `gorm:"column:receiver_detail"`        // 收货地址快照：详细地址
+	PayChannel           string      `gorm:"column:pay_channel"`            // 支付渠道
+	TradeNo              string      `gorm:"column:trade_no"`               // 渠道交易号
+	PayTime              *time.Time  `gorm:"column:pay_time"`               // 支付时间
+	CreateTime           time.Time   `gorm:"column:create_time;autoCreateTime"`
+	UpdateTime           time.Time   `gorm:"column:update_time;autoUpdateTime"`
 }
 
 func (o Orders) TableName() string {
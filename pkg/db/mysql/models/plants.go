@@ -0,0 +1,44 @@
+package models
+
+import "github.com/sunzhaoc/plant_be/pkg/money"
+
+type Plant struct {
+	Id                 uint64      `gorm:"column:id;primaryKey;autoIncrement"`
+	Name               string      `gorm:"column:name"`                 // 中文名
+	LatinName          string      `gorm:"column:latin_name"`           // 拉丁学名
+	MainImgUrl         string      `gorm:"column:main_img_url"`         // 主图地址
+	MinPrice           money.Money `gorm:"column:min_price"`            // 起始价格（各SKU最低价）
+	CategoryId         uint64      `gorm:"column:category_id"`          // 分类ID
+	IsOnSale           bool        `gorm:"column:is_on_sale"`           // 是否上架
+	ShippingTemplateId *uint64     `gorm:"column:shipping_template_id"` // 运费模板，为空使用默认模板
+	SalesCount         uint64      `gorm:"column:sales_count"`          // 累计销量（支付成功时累加）
+}
+
+func (p Plant) TableName() string {
+	return "plants"
+}
+
+type PlantSku struct {
+	Id          uint64      `gorm:"column:id;primaryKey;autoIncrement"`
+	PlantId     uint64      `gorm:"column:plant_id"`
+	Size        string      `gorm:"column:size"`         // 规格名称
+	Price       money.Money `gorm:"column:price"`        // 单价
+	Stock       uint        `gorm:"column:stock"`        // 库存
+	WeightGrams uint        `gorm:"column:weight_grams"` // 计费重量（克，含包装）
+	Sort        int         `gorm:"column:sort"`         // 排序（升序）
+}
+
+func (s PlantSku) TableName() string {
+	return "plant_sku"
+}
+
+type PlantImage struct {
+	Id      uint64 `gorm:"column:id;primaryKey;autoIncrement"`
+	PlantId uint64 `gorm:"column:plant_id"`
+	ImgUrl  string `gorm:"column:img_url"`
+	Sort    int    `gorm:"column:sort"` // 排序（升序）
+}
+
+func (i PlantImage) TableName() string {
+	return "plant_image"
+}
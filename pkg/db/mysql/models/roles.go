@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// Role 角色
+type Role struct {
+	Id         uint64    `gorm:"column:id;primaryKey;autoIncrement"`
+	Code       string    `gorm:"column:code"` // 角色编码，如 admin/warehouse
+	Name       string    `gorm:"column:name"`
+	CreateTime time.Time `gorm:"column:create_time;autoCreateTime"`
+}
+
+func (r Role) TableName() string {
+	return "roles"
+}
+
+// Permission 权限点
+type Permission struct {
+	Id         uint64    `gorm:"column:id;primaryKey;autoIncrement"`
+	Code       string    `gorm:"column:code"` // 权限编码，如 catalog:write
+	Name       string    `gorm:"column:name"`
+	CreateTime time.Time `gorm:"column:create_time;autoCreateTime"`
+}
+
+func (p Permission) TableName() string {
+	return "permissions"
+}
+
+// UserRole 用户-角色关联
+type UserRole struct {
+	UserId     uint64    `gorm:"column:user_id;primaryKey"`
+	RoleId     uint64    `gorm:"column:role_id;primaryKey"`
+	CreateTime time.Time `gorm:"column:create_time;autoCreateTime"`
+}
+
+func (u UserRole) TableName() string {
+	return "user_roles"
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+)
+
+// Shipment 发货包裹
+type Shipment struct {
+	Id          uint64     `gorm:"column:id;primaryKey;autoIncrement"`
+	OrderId     uint64     `gorm:"column:order_id"`
+	OrderSn     string     `gorm:"column:order_sn"`
+	Carrier     string     `gorm:"column:carrier"`
+	TrackingNo  string     `gorm:"column:tracking_no"`
+	Status      string     `gorm:"column:status"` // 最新轨迹状态，取值见 carrier.EventStatus
+	ShippedAt   time.Time  `gorm:"column:shipped_at"`
+	DeliveredAt *time.Time `gorm:"column:delivered_at"`
+	OperatorId  uint64     `gorm:"column:operator_id"`
+	Remark      string     `gorm:"column:remark"`
+	CreateTime  time.Time  `gorm:"column:create_time;autoCreateTime"`
+	UpdateTime  time.Time  `gorm:"column:update_time;autoUpdateTime"`
+}
+
+func (s Shipment) TableName() string {
+	return "shipments"
+}
+
+// ShipmentItem 包裹内容
+type ShipmentItem struct {
+	Id          uint64 `gorm:"column:id;primaryKey;autoIncrement"`
+	ShipmentId  uint64 `gorm:"column:shipment_id"`
+	OrderItemId uint64 `gorm:"column:order_item_id"`
+	Quantity    uint   `gorm:"column:quantity"`
+}
+
+func (s ShipmentItem) TableName() string {
+	return "shipment_items"
+}
+
+// ShipmentEvent 物流轨迹
+type ShipmentEvent struct {
+	Id          uint64    `gorm:"column:id;primaryKey;autoIncrement"`
+	ShipmentId  uint64    `gorm:"column:shipment_id"`
+	EventTime   time.Time `gorm:"column:event_time"`
+	Status      string    `gorm:"column:status"`
+	Location    string    `gorm:"column:location"`
+	Description string    `gorm:"column:description"`
+	CreateTime  time.Time `gorm:"column:create_time;autoCreateTime"`
+}
+
+func (e ShipmentEvent) TableName() string {
+	return "shipment_events"
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"github.com/sunzhaoc/plant_be/pkg/money"
+)
+
+// ChargeMode 运费计费方式
+type ChargeMode int8
+
+const (
+	ChargeByWeight ChargeMode = 1 // 按重量：首重/续重单位为克
+	ChargeByItem   ChargeMode = 2 // 按件：首件/续件单位为件
+)
+
+// ShippingTemplate 运费模板
+type ShippingTemplate struct {
+	Id              uint64      `gorm:"column:id;primaryKey;autoIncrement"`
+	Name            string      `gorm:"column:name"`
+	ChargeMode      ChargeMode  `gorm:"column:charge_mode"`
+	FreeThreshold   money.Money `gorm:"column:free_threshold"`   // 包邮门槛，0 表示不包邮
+	ExcludedRegions string      `gorm:"column:excluded_regions"` // 不配送的省份，逗号分隔
+	IsDefault       bool        `gorm:"column:is_default"`
+	CreateTime      time.Time   `gorm:"column:create_time;autoCreateTime"`
+	UpdateTime      time.Time   `gorm:"column:update_time;autoUpdateTime"`
+}
+
+func (t ShippingTemplate) TableName() string {
+	return "shipping_templates"
+}
+
+// ShippingTemplateRule 运费模板按地区的计费规则
+type ShippingTemplateRule struct {
+	Id             uint64      `gorm:"column:id;primaryKey;autoIncrement"`
+	TemplateId     uint64      `gorm:"column:template_id"`
+	Regions        string      `gorm:"column:regions"` // 适用省份，逗号分隔，为空表示其他地区
+	FirstUnit      uint        `gorm:"column:first_unit"`
+	FirstFee       money.Money `gorm:"column:first_fee"`
+	AdditionalUnit uint        `gorm:"column:additional_unit"`
+	AdditionalFee  money.Money `gorm:"column:additional_fee"`
+}
+
+func (r ShippingTemplateRule) TableName() string {
+	return "shipping_template_rules"
+}
+
+// PlantShippingRestriction 植物季节性配送限制
+type PlantShippingRestriction struct {
+	Id         uint64    `gorm:"column:id;primaryKey;autoIncrement"`
+	PlantId    uint64    `gorm:"column:plant_id"`
+	Regions    string    `gorm:"column:regions"`   // 限制的省份，逗号分隔
+	StartDay   string    `gorm:"column:start_day"` // MM-DD
+	EndDay     string    `gorm:"column:end_day"`   // MM-DD（含）
+	Reason     string    `gorm:"column:reason"`
+	CreateTime time.Time `gorm:"column:create_time;autoCreateTime"`
+}
+
+func (r PlantShippingRestriction) TableName() string {
+	return "plant_shipping_restrictions"
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+// ReservationStatus 库存预占状态
+type ReservationStatus int8
+
+const (
+	ReservationReserved  ReservationStatus = 0 // 预占中
+	ReservationConfirmed ReservationStatus = 1 // 已确认（支付成功，已扣减库存）
+	ReservationReleased  ReservationStatus = 2 // 已释放（订单取消或超时）
+)
+
+// StockReservation 库存预占记录
+type StockReservation struct {
+	Id         uint64            `gorm:"column:id;primaryKey;autoIncrement"`
+	OrderSn    string            `gorm:"column:order_sn"`
+	SkuId      uint64            `gorm:"column:sku_id"`
+	Quantity   uint              `gorm:"column:quantity"`
+	Status     ReservationStatus `gorm:"column:status;default:0"`
+	ExpireTime time.Time         `gorm:"column:expire_time"`
+	CreateTime time.Time         `gorm:"column:create_time;autoCreateTime"`
+	UpdateTime time.Time         `gorm:"column:update_time;autoUpdateTime"`
+}
+
+func (s StockReservation) TableName() string {
+	return "stock_reservations"
+}
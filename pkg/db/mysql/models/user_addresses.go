@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+type UserAddress struct {
+	Id            uint64    `gorm:"column:id;primaryKey;autoIncrement"`
+	UserId        uint64    `gorm:"column:user_id"`
+	Receiver      string    `gorm:"column:receiver"`       // 收货人姓名
+	Phone         string    `gorm:"column:phone"`          // 联系电话
+	Province      string    `gorm:"column:province"`       // 省
+	ProvinceCode  string    `gorm:"column:province_code"`  // 省级行政区划代码
+	City          string    `gorm:"column:city"`           // 市
+	Area          string    `gorm:"column:area"`           // 区/县
+	DetailAddress string    `gorm:"column:detail_address"` // 详细地址
+	IsDefault     bool      `gorm:"column:is_default"`     // 是否默认地址
+	CreateTime    time.Time `gorm:"column:create_time;autoCreateTime"`
+	UpdateTime    time.Time `gorm:"column:update_time;autoUpdateTime"`
+}
+
+func (a UserAddress) TableName() string {
+	return "user_addresses"
+}
+
+// FullAddress 拼接完整地址
+func (a UserAddress) FullAddress() string {
+	return a.Province + a.City + a.Area + a.DetailAddress
+}
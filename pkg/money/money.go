@@ -0,0 +1,229 @@
+// Package money 以整数分表示金额，避免 float64 运算的舍入误差
+//
+// 数据库列保持 DECIMAL(12,2)：读取时按十进制字符串精确解析，写入时输出两位小数字符串；
+// JSON 输出为保留两位小数的数字（如 12.50），与原 float64 字段兼容
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money 金额，单位：分
+type Money int64
+
+// Fen 一分；Yuan 一元
+const (
+	Fen  Money = 1
+	Yuan Money = 100
+)
+
+var (
+	ErrInvalid  = errors.New("金额格式错误")
+	ErrNegative = errors.New("金额不能为负数")
+)
+
+// FromFen 按分构造金额
+func FromFen(fen int64) Money {
+	return Money(fen)
+}
+
+// FromYuan 将浮点元转换为金额（四舍五入到分），仅用于兼容历史的 float64 数据
+func FromYuan(yuan float64) Money {
+	return Money(math.Round(yuan * 100))
+}
+
+// Parse 解析十进制金额字符串（如 "12"、"12.3"、"-0.05"），超过两位的非零小数视为错误
+func Parse(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, ErrInvalid
+	}
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	if intPart == "" && fracPart == "" {
+		return 0, ErrInvalid
+	}
+	if len(strings.TrimRight(fracPart, "0")) > 2 {
+		return 0, fmt.Errorf("%w: 最多两位小数", ErrInvalid)
+	}
+	fracPart = (fracPart + "00")[:2]
+	if intPart == "" {
+		intPart = "0"
+	}
+	for _, part := range []string{intPart, fracPart} {
+		for _, ch := range part {
+			if ch < '0' || ch > '9' {
+				return 0, ErrInvalid
+			}
+		}
+	}
+	yuan, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil || yuan > math.MaxInt64/100-1 {
+		return 0, fmt.Errorf("%w: 超出范围", ErrInvalid)
+	}
+	fen, _ := strconv.ParseInt(fracPart, 10, 64)
+	m := Money(yuan*100 + fen)
+	if negative {
+		m = -m
+	}
+	return m, nil
+}
+
+// Fen 金额的分值，用于对接支付渠道
+func (m Money) Fen() int64 {
+	return int64(m)
+}
+
+// String 两位小数的元，如 "12.50"、"-0.05"
+func (m Money) String() string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/100, v%100)
+}
+
+// Mul 单价乘以数量
+func (m Money) Mul(n int64) Money {
+	return m * Money(n)
+}
+
+// MulRatio 按比例 num/den 计算金额（如折扣、分摊），四舍五入到分（0.5 分远离零进位）
+func (m Money) MulRatio(num int64, den int64) Money {
+	if den == 0 {
+		return 0
+	}
+	p := int64(m) * num
+	q := p / den
+	r := p % den
+	if r < 0 {
+		r = -r
+	}
+	if r*2 >= abs(den) {
+		if (p < 0) != (den < 0) {
+			q--
+		} else {
+			q++
+		}
+	}
+	return Money(q)
+}
+
+// Allocate 将 total 按 weights 的比例拆分，结果之和严格等于 total（最大余数法，余下的分给余数最大的项）
+//
+// 用于把订单级优惠分摊到各商品行；weights 之和为 0 时全部分给第一项
+func Allocate(total Money, weights []Money) []Money {
+	parts := make([]Money, len(weights))
+	if len(weights) == 0 {
+		return parts
+	}
+	var sum int64
+	for _, w := range weights {
+		sum += int64(w)
+	}
+	if sum == 0 {
+		parts[0] = total
+		return parts
+	}
+	remainders := make([]int64, len(weights))
+	var allocated Money
+	for i, w := range weights {
+		p := int64(total) * int64(w)
+		parts[i] = Money(p / sum)
+		remainders[i] = p % sum
+		allocated += parts[i]
+	}
+	// 剩余的分逐个补给余数最大的项（余数相同时靠前的优先）
+	for left := total - allocated; left != 0; {
+		best := 0
+		for i := range remainders {
+			if remainders[i] > remainders[best] {
+				best = i
+			}
+		}
+		step := Money(1)
+		if left < 0 {
+			step = -1
+		}
+		parts[best] += step
+		remainders[best] = math.MinInt64
+		left -= step
+	}
+	return parts
+}
+
+// Scan 实现 sql.Scanner，DECIMAL 列按字符串精确解析
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	case int64:
+		*m = Money(v) * Yuan
+	case float64:
+		*m = FromYuan(v)
+	default:
+		return fmt.Errorf("无法将 %T 转换为金额", src)
+	}
+	return nil
+}
+
+func (m *Money) scanString(s string) error {
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Value 实现 driver.Valuer，写入两位小数字符串
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// MarshalJSON 输出两位小数的数字
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON 接受数字或字符串形式的元；JSON 只用于请求与报价等外部输入，负数一律拒绝
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" {
+		return nil
+	}
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	if v < 0 {
+		return ErrNegative
+	}
+	*m = v
+	return nil
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
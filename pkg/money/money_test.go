@@ -0,0 +1,220 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{"12", 1200, false},
+		{"12.3", 1230, false},
+		{"12.50", 1250, false},
+		{"1.230", 123, false},
+		{".5", 50, false},
+		{"+1.5", 150, false},
+		{"-0.05", -5, false},
+		{"-12.34", -1234, false},
+		{" 7.01 ", 701, false},
+		{"0", 0, false},
+		{"1.234", 0, true},
+		{"1.2.3", 0, true},
+		{"", 0, true},
+		{"-", 0, true},
+		{"abc", 0, true},
+		{"1e3", 0, true},
+		{"1,000", 0, true},
+		{"92233720368547758", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("Parse(%q) error = %v, want ErrInvalid", tt.in, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Parse(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		in   Money
+		want string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{-5, "-0.05"},
+		{1250, "12.50"},
+		{-123456, "-1234.56"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Money(%d).String() = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMulRatio(t *testing.T) {
+	tests := []struct {
+		m        Money
+		num, den int64
+		want     Money
+	}{
+		{1000, 85, 100, 850},
+		{100, 1, 3, 33}, // 33.33 舍
+		{200, 1, 3, 67}, // 66.67 入
+		{5, 1, 2, 3},    // 2.5 远离零进位
+		{-5, 1, 2, -3},  // -2.5 远离零进位
+		{5, 1, -2, -3},  // 分母为负
+		{-5, -1, 2, 3},  // 分子为负
+		{-100, 1, 3, -33},
+		{1999, 0, 7, 0},
+		{1999, 3, 0, 0}, // 分母为 0
+	}
+	for _, tt := range tests {
+		if got := tt.m.MulRatio(tt.num, tt.den); got != tt.want {
+			t.Errorf("Money(%d).MulRatio(%d, %d) = %d, want %d", tt.m, tt.num, tt.den, got, tt.want)
+		}
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		total   Money
+		weights []Money
+		want    []Money
+	}{
+		{"even", 90, []Money{1, 1, 1}, []Money{30, 30, 30}},
+		{"equal remainders go first", 100, []Money{1, 1, 1}, []Money{34, 33, 33}},
+		{"largest remainder", 100, []Money{1000, 2000, 3999}, []Money{14, 29, 57}},
+		{"single cent", 1, []Money{3, 1}, []Money{1, 0}},
+		{"negative total", -100, []Money{1, 1, 1}, []Money{-34, -33, -33}},
+		{"zero weights", 10, []Money{0, 0}, []Money{10, 0}},
+		{"zero weight item", 50, []Money{0, 300, 100}, []Money{0, 38, 12}},
+		{"empty", 10, nil, []Money{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Allocate(tt.total, tt.weights)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Allocate() = %v, want %v", got, tt.want)
+			}
+			var sum Money
+			for i := range got {
+				sum += got[i]
+				if got[i] != tt.want[i] {
+					t.Errorf("Allocate() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+			if len(got) > 0 && sum != tt.total {
+				t.Errorf("sum of %v = %d, want %d", got, sum, tt.total)
+			}
+		})
+	}
+}
+
+func TestAllocateSum(t *testing.T) {
+	weights := []Money{1999, 1, 35000, 777, 3, 12345}
+	for total := Money(-1000); total <= 5000; total += 37 {
+		var sum Money
+		for _, p := range Allocate(total, weights) {
+			sum += p
+		}
+		if sum != total {
+			t.Fatalf("Allocate(%d) sums to %d", total, sum)
+		}
+	}
+}
+
+func TestScanValueRoundTrip(t *testing.T) {
+	for _, m := range []Money{0, 1, 5, -5, 1250, -123456, 99999999999} {
+		v, err := m.Value()
+		if err != nil {
+			t.Fatalf("Money(%d).Value() error = %v", m, err)
+		}
+		s, ok := v.(string)
+		if !ok {
+			t.Fatalf("Money(%d).Value() = %T, want string", m, v)
+		}
+		var fromBytes, fromString Money
+		if err := fromBytes.Scan([]byte(s)); err != nil || fromBytes != m {
+			t.Errorf("Scan([]byte(%q)) = %d, %v, want %d", s, fromBytes, err, m)
+		}
+		if err := fromString.Scan(s); err != nil || fromString != m {
+			t.Errorf("Scan(%q) = %d, %v, want %d", s, fromString, err, m)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		src     any
+		want    Money
+		wantErr bool
+	}{
+		{nil, 0, false},
+		{[]byte("12.34"), 1234, false},
+		{"0.10", 10, false},
+		{int64(3), 300, false},
+		{float64(19.99), 1999, false},
+		{[]byte("12.345"), 0, true},
+		{true, 0, true},
+	}
+	for _, tt := range tests {
+		m := Money(42)
+		err := m.Scan(tt.src)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Scan(%v) error = %v, wantErr %v", tt.src, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && m != tt.want {
+			t.Errorf("Scan(%v) = %d, want %d", tt.src, m, tt.want)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	data, err := json.Marshal(struct {
+		A Money `json:"a"`
+	}{1250})
+	if err != nil || string(data) != `{"a":12.50}` {
+		t.Fatalf("Marshal = %s, %v", data, err)
+	}
+
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr error
+	}{
+		{`12.5`, 1250, nil},
+		{`"12.50"`, 1250, nil},
+		{`0`, 0, nil},
+		{`null`, 42, nil}, // null 保持原值
+		{`-1`, 42, ErrNegative},
+		{`"-0.01"`, 42, ErrNegative},
+		{`"abc"`, 42, ErrInvalid},
+		{`1.001`, 42, ErrInvalid},
+	}
+	for _, tt := range tests {
+		m := Money(42)
+		err := json.Unmarshal([]byte(tt.in), &m)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("Unmarshal(%s) error = %v, want %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if m != tt.want {
+			t.Errorf("Unmarshal(%s) = %d, want %d", tt.in, m, tt.want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Package ordersn 生成与解析订单号
+//
+// 订单号共 27 位数字：
+//
+//	yyyyMMddHHmmss  14位  创建时间（取 Redis 服务器时间，本地时区）
+//	seq             6位   该秒内的全局序号（Redis HINCRBY，跨实例唯一）
+//	uid             6位   用户ID后6位，便于按用户排查
+//	check           1位   Luhn 校验位，用于识别手工录入错误
+//
+// 时间与序号由同一个 Lua 脚本在 Redis 中取得，实例间时钟偏差或回拨不会产生重复订单号
+package ordersn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/sunzhaoc/plant_be/pkg/db/redis"
+)
+
+const (
+	seqKey       = "ordersn:seq" // 当前秒与秒内序号（HASH：sec、seq）
+	seqKeyTTL    = 3600          // 序号键保留时间（秒）
+	maxSeq       = 999999
+	timeLayout   = "20060102150405"
+	snLength     = 27
+	legacyLength = 26 // 旧版订单号：时间戳 + 6位随机数 + 用户ID后6位，无校验位
+)
+
+var (
+	ErrSeqExhausted = errors.New("订单号序号已用尽")
+	ErrInvalid      = errors.New("订单号格式错误")
+	ErrCheckDigit   = errors.New("订单号校验位错误")
+)
+
+// Info 订单号解析结果
+type Info struct {
+	CreatedAt time.Time // 创建时间（秒级）
+	Seq       uint64    // 秒内序号；旧版订单号为随机数
+	UidSuffix uint64    // 用户ID后6位
+	Legacy    bool      // 是否为旧版订单号
+}
+
+// nextScript 读取 Redis 服务器时间并自增该秒的序号
+//
+// 只保存最近使用的秒与序号，进入新的一秒时序号从 1 开始；服务器时间回拨时沿用已记录的秒继续计数，保证不重复
+// KEYS: seqKey；ARGV: ttl；返回 {unix秒, 序号}
+var nextScript = goredis.NewScript(`
+local now = tonumber(redis.call('TIME')[1])
+local sec = tonumber(redis.call('HGET', KEYS[1], 'sec') or '0')
+if now > sec then
+	redis.call('HSET', KEYS[1], 'sec', now, 'seq', 1)
+	redis.call('EXPIRE', KEYS[1], ARGV[1])
+	return {now, 1}
+end
+return {sec, redis.call('HINCRBY', KEYS[1], 'seq', 1)}
+`)
+
+// New 生成订单号，单秒序号用尽时等待下一秒
+func New(ctx context.Context, uid uint64) (string, error) {
+	rdb, err := redis.GetDb("ali")
+	if err != nil {
+		return "", err
+	}
+	for attempt := 0; attempt < 3; attempt++ {
+		res, err := nextScript.Run(ctx, rdb, []string{seqKey}, seqKeyTTL).Int64Slice()
+		if err != nil {
+			return "", fmt.Errorf("生成订单号失败: %w", err)
+		}
+		sec, seq := res[0], res[1]
+		if seq <= maxSeq {
+			return format(time.Unix(sec, 0), uint64(seq), uid), nil
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(time.Until(time.Unix(sec+1, 0))):
+		}
+	}
+	return "", ErrSeqExhausted
+}
+
+// format 拼接订单号并追加校验位
+func format(createdAt time.Time, seq uint64, uid uint64) string {
+	body := fmt.Sprintf("%s%06d%06d", createdAt.Format(timeLayout), seq, uid%1000000)
+	return body + strconv.Itoa(checkDigit(body))
+}
+
+// Parse 解析订单号，兼容旧版 26 位订单号（无校验位）
+func Parse(sn string) (Info, error) {
+	if len(sn) != snLength && len(sn) != legacyLength {
+		return Info{}, ErrInvalid
+	}
+	for _, ch := range sn {
+		if ch < '0' || ch > '9' {
+			return Info{}, ErrInvalid
+		}
+	}
+	legacy := len(sn) == legacyLength
+	if !legacy && checkDigit(sn[:snLength-1]) != int(sn[snLength-1]-'0') {
+		return Info{}, ErrCheckDigit
+	}
+	createdAt, err := time.ParseInLocation(timeLayout, sn[:14], time.Local)
+	if err != nil {
+		return Info{}, ErrInvalid
+	}
+	seq, _ := strconv.ParseUint(sn[14:20], 10, 64)
+	uid, _ := strconv.ParseUint(sn[20:26], 10, 64)
+	return Info{CreatedAt: createdAt, Seq: seq, UidSuffix: uid, Legacy: legacy}, nil
+}
+
+// checkDigit 计算 Luhn 校验位
+func checkDigit(digits string) int {
+	sum := 0
+	double := true // 从右往左，校验位左侧第一位起隔位加倍
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return (10 - sum%10) % 10
+}
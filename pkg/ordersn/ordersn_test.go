@@ -0,0 +1,92 @@
+package ordersn
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckDigit(t *testing.T) {
+	tests := []struct {
+		digits string
+		want   int
+	}{
+		{"7992739871", 3},
+		{"0", 0},
+		{"1", 8},
+		{"9", 1},
+		{"12345678", 2},
+		{"20250101120000000001000042", 6},
+	}
+	for _, tt := range tests {
+		if got := checkDigit(tt.digits); got != tt.want {
+			t.Errorf("checkDigit(%q) = %d, want %d", tt.digits, got, tt.want)
+		}
+	}
+}
+
+func TestFormatWidth(t *testing.T) {
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.Local)
+	tests := []struct {
+		name string
+		seq  uint64
+		uid  uint64
+		body string
+	}{
+		{"padding", 1, 42, "20250102030405000001000042"},
+		{"max seq", maxSeq, 999999, "20250102030405999999999999"},
+		{"uid keeps last 6 digits", 12, 1234567890, "20250102030405000012567890"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sn := format(createdAt, tt.seq, tt.uid)
+			if len(sn) != snLength {
+				t.Fatalf("len(%q) = %d, want %d", sn, len(sn), snLength)
+			}
+			if sn[:snLength-1] != tt.body {
+				t.Errorf("body = %q, want %q", sn[:snLength-1], tt.body)
+			}
+			if int(sn[snLength-1]-'0') != checkDigit(tt.body) {
+				t.Errorf("check digit of %q mismatch", sn)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.Local)
+	valid := format(createdAt, 123, 456789)
+
+	// 改动任意一位数字都应被校验位识别
+	corrupted := []byte(valid)
+	corrupted[15] = '0' + (corrupted[15]-'0'+1)%10
+
+	tests := []struct {
+		name    string
+		sn      string
+		want    Info
+		wantErr error
+	}{
+		{"current", valid, Info{CreatedAt: createdAt, Seq: 123, UidSuffix: 456789}, nil},
+		{"legacy 26 digits", "20250102030405987654000042", Info{CreatedAt: createdAt, Seq: 987654, UidSuffix: 42, Legacy: true}, nil},
+		{"bad check digit", string(corrupted), Info{}, ErrCheckDigit},
+		{"too short", "2025010203040512345", Info{}, ErrInvalid},
+		{"too long", valid + "0", Info{}, ErrInvalid},
+		{"non digit", "2025010203040500012345678X", Info{}, ErrInvalid},
+		{"bad time", "20251302030405000001000042", Info{}, ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.sn)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Parse(%q) error = %v, want %v", tt.sn, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !got.CreatedAt.Equal(tt.want.CreatedAt) || got.Seq != tt.want.Seq || got.UidSuffix != tt.want.UidSuffix || got.Legacy != tt.want.Legacy {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.sn, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,362 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sunzhaoc/plant_be/pkg/money"
+)
+
+const alipayTimeLayout = "2006-01-02 15:04:05"
+
+// AlipayProvider 支付宝电脑网站支付（RSA2 签名）
+type AlipayProvider struct {
+	appId      string
+	gateway    string
+	returnURL  string
+	privateKey *rsa.PrivateKey
+	publicKey  *rsa.PublicKey
+	client     *http.Client
+}
+
+func NewAlipayProvider(cfg AlipayConfig) (*AlipayProvider, error) {
+	privateKey, err := loadRSAPrivateKey(cfg.PrivateKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := loadRSAPublicKey(cfg.AlipayPublicKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	gateway := cfg.Gateway
+	if gateway == "" {
+		gateway = "https://openapi.alipay.com/gateway.do"
+	}
+	return &AlipayProvider{
+		appId:      cfg.AppId,
+		gateway:    gateway,
+		returnURL:  cfg.ReturnURL,
+		privateKey: privateKey,
+		publicKey:  publicKey,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (p *AlipayProvider) Name() string {
+	return "alipay"
+}
+
+// commonParams 构造公共请求参数
+func (p *AlipayProvider) commonParams(method string, bizContent any) (map[string]string, error) {
+	biz, err := json.Marshal(bizContent)
+	if err != nil {
+		return nil, fmt.Errorf("序列化业务参数失败: %w", err)
+	}
+	return map[string]string{
+		"app_id":      p.appId,
+		"method":      method,
+		"format":      "JSON",
+		"charset":     "utf-8",
+		"sign_type":   "RSA2",
+		"timestamp":   time.Now().Format(alipayTimeLayout),
+		"version":     "1.0",
+		"biz_content": string(biz),
+	}, nil
+}
+
+func (p *AlipayProvider) CreatePrepay(ctx context.Context, req PrepayRequest) (*PrepayResult, error) {
+	biz := map[string]string{
+		"out_trade_no": req.OrderSn,
+		"total_amount": req.Amount.String(),
+		"subject":      req.Subject,
+		"product_code": "FAST_INSTANT_TRADE_PAY",
+	}
+	if !req.ExpireAt.IsZero() {
+		biz["time_expire"] = req.ExpireAt.Format(alipayTimeLayout)
+	}
+	params, err := p.commonParams("alipay.trade.page.pay", biz)
+	if err != nil {
+		return nil, err
+	}
+	params["notify_url"] = req.NotifyURL
+	params["return_url"] = p.returnURL
+
+	sign, err := p.Sign(params)
+	if err != nil {
+		return nil, err
+	}
+	values := url.Values{}
+	for k, v := range params {
+		if v != "" {
+			values.Set(k, v)
+		}
+	}
+	values.Set("sign", sign)
+
+	return &PrepayResult{
+		Channel: p.Name(),
+		PayURL:  p.gateway + "?" + values.Encode(),
+	}, nil
+}
+
+// Sign 请求参数中除 sign 外均参与签名（含 sign_type）
+func (p *AlipayProvider) Sign(params map[string]string) (string, error) {
+	digest := sha256.Sum256([]byte(buildSignContent(params, "sign")))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("RSA2签名失败: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// verify 使用支付宝公钥校验签名
+func (p *AlipayProvider) verify(content string, sign string) error {
+	sig, err := base64.StdEncoding.DecodeString(sign)
+	if err != nil {
+		return fmt.Errorf("签名解码失败: %w", err)
+	}
+	digest := sha256.Sum256([]byte(content))
+	if err := rsa.VerifyPKCS1v15(p.publicKey, crypto.SHA256, digest[:], sig); err != nil {
+		return fmt.Errorf("签名校验失败: %w", err)
+	}
+	return nil
+}
+
+func (p *AlipayProvider) VerifyNotify(r *http.Request) (*NotifyResult, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, fmt.Errorf("解析通知参数失败: %w", err)
+	}
+	params := make(map[string]string, len(r.PostForm))
+	for k := range r.PostForm {
+		params[k] = r.PostForm.Get(k)
+	}
+
+	// 异步通知验签时 sign 与 sign_type 均不参与
+	if err := p.verify(buildSignContent(params, "sign", "sign_type"), params["sign"]); err != nil {
+		return nil, err
+	}
+	if params["app_id"] != p.appId {
+		return nil, fmt.Errorf("通知app_id不匹配: %s", params["app_id"])
+	}
+
+	amount, err := money.Parse(params["total_amount"])
+	if err != nil {
+		return nil, fmt.Errorf("通知金额格式错误: %w", err)
+	}
+	paidAt, err := time.ParseInLocation(alipayTimeLayout, params["gmt_payment"], time.Local)
+	if err != nil {
+		paidAt = time.Now()
+	}
+	status := params["trade_status"]
+	return &NotifyResult{
+		OrderSn: params["out_trade_no"],
+		TradeNo: params["trade_no"],
+		Amount:  amount,
+		Paid:    status == "TRADE_SUCCESS" || status == "TRADE_FINISHED",
+		PaidAt:  paidAt,
+	}, nil
+}
+
+func (p *AlipayProvider) NotifyAck(success bool) (int, string) {
+	// 支付宝以响应内容判断是否成功，非 success 会按策略重试
+	if success {
+		return http.StatusOK, "success"
+	}
+	return http.StatusOK, "fail"
+}
+
+// call 调用支付宝开放接口并校验响应签名，返回 xxx_response 节点内容
+func (p *AlipayProvider) call(ctx context.Context, method string, bizContent any) (json.RawMessage, error) {
+	params, err := p.commonParams(method, bizContent)
+	if err != nil {
+		return nil, err
+	}
+	sign, err := p.Sign(params)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{}
+	for k, v := range params {
+		form.Set(k, v)
+	}
+	form.Set("sign", sign)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.gateway, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded;charset=utf-8")
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("请求支付宝网关失败: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取支付宝响应失败: %w", err)
+	}
+
+	nodeName := strings.ReplaceAll(method, ".", "_") + "_response"
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("解析支付宝响应失败: %w", err)
+	}
+	node, ok := envelope[nodeName]
+	if !ok {
+		return nil, fmt.Errorf("支付宝响应缺少节点: %s", nodeName)
+	}
+	var respSign string
+	_ = json.Unmarshal(envelope["sign"], &respSign)
+	// 响应签名针对原始 JSON 节点字符串（去掉首尾空白）
+	if err := p.verify(string(bytes.TrimSpace(node)), respSign); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+type alipayResponse struct {
+	Code        string `json:"code"`
+	Msg         string `json:"msg"`
+	SubCode     string `json:"sub_code"`
+	SubMsg      string `json:"sub_msg"`
+	TradeNo     string `json:"trade_no"`
+	OutTradeNo  string `json:"out_trade_no"`
+	TradeStatus string `json:"trade_status"`
+	TotalAmount string `json:"total_amount"`
+	SendPayDate string `json:"send_pay_date"`
+	RefundFee   string `json:"refund_fee"` // 累计退款金额
+}
+
+func (r alipayResponse) err() error {
+	if r.Code == "10000" {
+		return nil
+	}
+	return fmt.Errorf("支付宝返回错误: %s %s (%s %s)", r.Code, r.Msg, r.SubCode, r.SubMsg)
+}
+
+func (p *AlipayProvider) Query(ctx context.Context, orderSn string) (*QueryResult, error) {
+	node, err := p.call(ctx, "alipay.trade.query", map[string]string{"out_trade_no": orderSn})
+	if err != nil {
+		return nil, err
+	}
+	var resp alipayResponse
+	if err := json.Unmarshal(node, &resp); err != nil {
+		return nil, fmt.Errorf("解析查询结果失败: %w", err)
+	}
+	if err := resp.err(); err != nil {
+		return nil, err
+	}
+	amount, err := money.Parse(resp.TotalAmount)
+	if err != nil {
+		return nil, fmt.Errorf("查询金额格式错误: %w", err)
+	}
+	paidAt, _ := time.ParseInLocation(alipayTimeLayout, resp.SendPayDate, time.Local)
+	return &QueryResult{
+		OrderSn: resp.OutTradeNo,
+		TradeNo: resp.TradeNo,
+		Amount:  amount,
+		Paid:    resp.TradeStatus == "TRADE_SUCCESS" || resp.TradeStatus == "TRADE_FINISHED",
+		Closed:  resp.TradeStatus == "TRADE_CLOSED",
+		PaidAt:  paidAt,
+	}, nil
+}
+
+func (p *AlipayProvider) Close(ctx context.Context, orderSn string) error {
+	node, err := p.call(ctx, "alipay.trade.close", map[string]string{"out_trade_no": orderSn})
+	if err != nil {
+		return err
+	}
+	var resp alipayResponse
+	if err := json.Unmarshal(node, &resp); err != nil {
+		return fmt.Errorf("解析关闭结果失败: %w", err)
+	}
+	// 用户未扫码时支付宝侧尚无交易，视为关闭成功
+	if resp.SubCode == "ACQ.TRADE_NOT_EXIST" {
+		return nil
+	}
+	return resp.err()
+}
+
+// Refund out_request_no 为退款单号，支付宝以此保证同一笔退款重复请求不会重复退款
+func (p *AlipayProvider) Refund(ctx context.Context, req RefundRequest) (*RefundResult, error) {
+	biz := map[string]string{
+		"out_trade_no":   req.OrderSn,
+		"refund_amount":  req.Amount.String(),
+		"out_request_no": req.RefundNo,
+		"refund_reason":  req.Reason,
+	}
+	node, err := p.call(ctx, "alipay.trade.refund", biz)
+	if err != nil {
+		return nil, err
+	}
+	var resp alipayResponse
+	if err := json.Unmarshal(node, &resp); err != nil {
+		return nil, fmt.Errorf("解析退款结果失败: %w", err)
+	}
+	if err := resp.err(); err != nil {
+		return nil, err
+	}
+	refunded, err := money.Parse(resp.RefundFee)
+	if err != nil {
+		return nil, fmt.Errorf("退款金额格式错误: %w", err)
+	}
+	return &RefundResult{RefundNo: req.RefundNo, Amount: req.Amount, RefundedTotal: refunded}, nil
+}
+
+func loadRSAPrivateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取应用私钥失败: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("应用私钥不是有效的PEM格式")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("解析应用私钥失败: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("应用私钥不是RSA密钥")
+	}
+	return rsaKey, nil
+}
+
+func loadRSAPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取支付宝公钥失败: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("支付宝公钥不是有效的PEM格式")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("解析支付宝公钥失败: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("支付宝公钥不是RSA密钥")
+	}
+	return rsaKey, nil
+}
@@ -0,0 +1,49 @@
+package payment
+
+import (
+	"log"
+	"os"
+
+	"github.com/spf13/viper"
+)
+
+type FakeConfig struct {
+	Secret string `mapstructure:"-"`       // 本地模拟渠道的签名密钥，取自环境变量 PAYMENT_FAKE_SECRET
+	PayURL string `mapstructure:"pay_url"` // 模拟收银台地址
+}
+
+type AlipayConfig struct {
+	AppId               string `mapstructure:"app_id"`
+	Gateway             string `mapstructure:"gateway"`                // 网关地址（沙箱/正式）
+	PrivateKeyPath      string `mapstructure:"private_key_path"`       // 应用私钥（PEM）
+	AlipayPublicKeyPath string `mapstructure:"alipay_public_key_path"` // 支付宝公钥（PEM）
+	ReturnURL           string `mapstructure:"return_url"`             // 支付完成后的前端跳转地址
+}
+
+type PaymentConfig struct {
+	Default       string       `mapstructure:"default"`         // 默认支付渠道
+	NotifyBaseURL string       `mapstructure:"notify_base_url"` // 异步通知地址前缀，渠道名拼在末尾
+	Fake          FakeConfig   `mapstructure:"fake"`
+	Alipay        AlipayConfig `mapstructure:"alipay"`
+}
+
+var PaymentCfg PaymentConfig
+
+func Load() PaymentConfig {
+	// 配置文件路径和名称
+	viper.SetConfigName("config")   // 配置文件名（无后缀）
+	viper.SetConfigType("yaml")     // 配置文件类型
+	viper.AddConfigPath("./config") // 配置文件所在目录
+
+	// 读取配置文件
+	if err := viper.ReadInConfig(); err != nil {
+		log.Fatalf("读取配置文件失败: %v", err)
+	}
+
+	// 解析配置到结构体
+	if err := viper.UnmarshalKey("payment", &PaymentCfg); err != nil {
+		log.Fatalf("解析配置失败: %v", err)
+	}
+	PaymentCfg.Fake.Secret = os.Getenv("PAYMENT_FAKE_SECRET")
+	return PaymentCfg
+}
@@ -0,0 +1,201 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sunzhaoc/plant_be/pkg/money"
+)
+
+// FakeProvider 本地模拟支付渠道，用于开发联调与测试
+//
+// 签名算法为 HMAC-SHA256，通知参数与支付宝风格一致（表单提交）：
+// out_trade_no、trade_no、total_amount（分）、trade_status、gmt_payment、sign
+type FakeProvider struct {
+	secret []byte
+	payURL string
+
+	mu      sync.Mutex
+	trades  map[string]*QueryResult
+	refunds map[string]map[string]money.Money // orderSn -> refundNo -> 退款金额
+}
+
+func NewFakeProvider(cfg FakeConfig) *FakeProvider {
+	return &FakeProvider{
+		secret:  []byte(cfg.Secret),
+		payURL:  cfg.PayURL,
+		trades:  make(map[string]*QueryResult),
+		refunds: make(map[string]map[string]money.Money),
+	}
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) CreatePrepay(ctx context.Context, req PrepayRequest) (*PrepayResult, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("支付金额必须大于0")
+	}
+
+	p.mu.Lock()
+	p.trades[req.OrderSn] = &QueryResult{OrderSn: req.OrderSn, Amount: req.Amount}
+	p.mu.Unlock()
+
+	params := map[string]string{
+		"out_trade_no": req.OrderSn,
+		"total_amount": strconv.FormatInt(req.Amount.Fen(), 10),
+	}
+	sign, err := p.Sign(params)
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{}
+	for k, v := range params {
+		query.Set(k, v)
+	}
+	query.Set("sign", sign)
+
+	payURL := fmt.Sprintf("%s?%s", p.payURL, query.Encode())
+	return &PrepayResult{
+		Channel:  p.Name(),
+		PayURL:   payURL,
+		QrCode:   payURL,
+		PrepayId: "fake_" + req.OrderSn,
+	}, nil
+}
+
+func (p *FakeProvider) Sign(params map[string]string) (string, error) {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte(buildSignContent(params, "sign")))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func (p *FakeProvider) VerifyNotify(r *http.Request) (*NotifyResult, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, fmt.Errorf("解析通知参数失败: %w", err)
+	}
+	params := make(map[string]string, len(r.PostForm))
+	for k := range r.PostForm {
+		params[k] = r.PostForm.Get(k)
+	}
+
+	expected, _ := p.Sign(params)
+	if !hmac.Equal([]byte(expected), []byte(params["sign"])) {
+		return nil, fmt.Errorf("通知签名校验失败")
+	}
+
+	fen, err := strconv.ParseInt(params["total_amount"], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("通知金额格式错误: %w", err)
+	}
+	paidAt := time.Now()
+	if ts, err := strconv.ParseInt(params["gmt_payment"], 10, 64); err == nil {
+		paidAt = time.Unix(ts, 0)
+	}
+	result := &NotifyResult{
+		OrderSn: params["out_trade_no"],
+		TradeNo: params["trade_no"],
+		Amount:  money.FromFen(fen),
+		Paid:    params["trade_status"] == "SUCCESS",
+		PaidAt:  paidAt,
+	}
+
+	if result.Paid {
+		p.mu.Lock()
+		p.trades[result.OrderSn] = &QueryResult{
+			OrderSn: result.OrderSn,
+			TradeNo: result.TradeNo,
+			Amount:  result.Amount,
+			Paid:    true,
+			PaidAt:  result.PaidAt,
+		}
+		p.mu.Unlock()
+	}
+	return result, nil
+}
+
+func (p *FakeProvider) NotifyAck(success bool) (int, string) {
+	if success {
+		return http.StatusOK, "success"
+	}
+	return http.StatusBadRequest, "fail"
+}
+
+func (p *FakeProvider) Query(ctx context.Context, orderSn string) (*QueryResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	trade, ok := p.trades[orderSn]
+	if !ok {
+		return nil, fmt.Errorf("交易[%s]不存在", orderSn)
+	}
+	result := *trade
+	return &result, nil
+}
+
+func (p *FakeProvider) Close(ctx context.Context, orderSn string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	trade, ok := p.trades[orderSn]
+	if !ok {
+		return nil
+	}
+	if trade.Paid {
+		return fmt.Errorf("交易[%s]已支付，无法关闭", orderSn)
+	}
+	trade.Closed = true
+	return nil
+}
+
+func (p *FakeProvider) Refund(ctx context.Context, req RefundRequest) (*RefundResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	trade, ok := p.trades[req.OrderSn]
+	if !ok || !trade.Paid {
+		return nil, fmt.Errorf("交易[%s]未支付，无法退款", req.OrderSn)
+	}
+	refunds := p.refunds[req.OrderSn]
+	if refunds == nil {
+		refunds = make(map[string]money.Money)
+		p.refunds[req.OrderSn] = refunds
+	}
+	var total money.Money
+	for _, amount := range refunds {
+		total += amount
+	}
+	// 重复的退款单号直接返回原结果
+	if amount, ok := refunds[req.RefundNo]; ok {
+		return &RefundResult{RefundNo: req.RefundNo, Amount: amount, RefundedTotal: total}, nil
+	}
+	if req.Amount <= 0 || total+req.Amount > trade.Amount {
+		return nil, fmt.Errorf("退款金额超过可退金额")
+	}
+	refunds[req.RefundNo] = req.Amount
+	return &RefundResult{RefundNo: req.RefundNo, Amount: req.Amount, RefundedTotal: total + req.Amount}, nil
+}
+
+// BuildNotify 生成一份已签名的支付成功通知表单，供联调时模拟渠道回调
+func (p *FakeProvider) BuildNotify(orderSn string, amount money.Money) url.Values {
+	params := map[string]string{
+		"out_trade_no": orderSn,
+		"trade_no":     fmt.Sprintf("FAKE%d", time.Now().UnixNano()),
+		"total_amount": strconv.FormatInt(amount.Fen(), 10),
+		"trade_status": "SUCCESS",
+		"gmt_payment":  strconv.FormatInt(time.Now().Unix(), 10),
+	}
+	sign, _ := p.Sign(params)
+	form := url.Values{}
+	for k, v := range params {
+		form.Set(k, v)
+	}
+	form.Set("sign", sign)
+	return form
+}
@@ -0,0 +1,167 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sunzhaoc/plant_be/pkg/money"
+	"github.com/sunzhaoc/plant_be/pkg/utils"
+)
+
+// PrepayRequest 发起支付所需的订单信息
+type PrepayRequest struct {
+	OrderSn   string      // 商户订单号
+	Amount    money.Money // 支付金额
+	Subject   string      // 订单标题
+	ClientIP  string      // 用户IP
+	ExpireAt  time.Time   // 支付截止时间（零值表示不限制）
+	NotifyURL string      // 异步通知地址
+}
+
+// PrepayResult 支付渠道返回的预支付信息
+type PrepayResult struct {
+	Channel  string `json:"channel"`  // 支付渠道
+	PayURL   string `json:"payUrl"`   // 跳转支付链接（PC/H5）
+	QrCode   string `json:"qrCode"`   // 二维码内容（扫码支付）
+	PrepayId string `json:"prepayId"` // 渠道预支付ID
+}
+
+// NotifyResult 验签通过后的异步通知内容
+type NotifyResult struct {
+	OrderSn string      // 商户订单号
+	TradeNo string      // 渠道交易号
+	Amount  money.Money // 实付金额
+	Paid    bool        // 是否支付成功
+	PaidAt  time.Time   // 支付时间
+}
+
+// QueryResult 主动查询的交易状态
+type QueryResult struct {
+	OrderSn string
+	TradeNo string
+	Amount  money.Money
+	Paid    bool
+	Closed  bool
+	PaidAt  time.Time
+}
+
+// RefundRequest 退款请求，同一 RefundNo 重复提交只退款一次
+type RefundRequest struct {
+	OrderSn  string      // 商户订单号
+	RefundNo string      // 商户退款单号，部分退款时区分多笔退款
+	Amount   money.Money // 本次退款金额
+	Reason   string      // 退款原因
+}
+
+// RefundResult 退款结果
+type RefundResult struct {
+	RefundNo      string
+	Amount        money.Money // 本次退款金额
+	RefundedTotal money.Money // 该订单累计已退款金额
+}
+
+// Provider 支付渠道抽象（支付宝/微信支付风格）
+type Provider interface {
+	// Name 渠道名称，对应回调路由 /api/payment/notify/:provider
+	Name() string
+	// CreatePrepay 创建预支付单，返回支付链接或二维码
+	CreatePrepay(ctx context.Context, req PrepayRequest) (*PrepayResult, error)
+	// Sign 对参数签名
+	Sign(params map[string]string) (string, error)
+	// VerifyNotify 校验异步通知签名并解析通知内容
+	VerifyNotify(r *http.Request) (*NotifyResult, error)
+	// NotifyAck 返回给渠道的应答（状态码、内容）
+	NotifyAck(success bool) (int, string)
+	// Query 主动查询交易状态
+	Query(ctx context.Context, orderSn string) (*QueryResult, error)
+	// Close 关闭未支付的交易
+	Close(ctx context.Context, orderSn string) error
+	// Refund 对已支付的交易发起（部分）退款
+	Refund(ctx context.Context, req RefundRequest) (*RefundResult, error)
+}
+
+var providers = make(map[string]Provider)
+var defaultChannel string
+var notifyBaseURL string
+
+// Init 按配置初始化所有启用的支付渠道
+//
+// 模拟渠道仅在非生产环境且设置了 PAYMENT_FAKE_SECRET 时注册，生产环境下启用模拟渠道直接报错；
+// 默认渠道未启用时生产环境报错，其他环境仅告警，下单返回不支持的支付渠道
+func Init(cfg PaymentConfig) error {
+	if cfg.Fake.Secret != "" || cfg.Default == "fake" {
+		if utils.IsProduction() {
+			return fmt.Errorf("生产环境不允许启用模拟支付渠道")
+		}
+		if cfg.Fake.Secret != "" {
+			Register(NewFakeProvider(cfg.Fake))
+		}
+	}
+	if cfg.Alipay.AppId != "" {
+		p, err := NewAlipayProvider(cfg.Alipay)
+		if err != nil {
+			return fmt.Errorf("初始化支付宝渠道失败: %w", err)
+		}
+		Register(p)
+	}
+	if _, ok := providers[cfg.Default]; !ok {
+		if utils.IsProduction() {
+			return fmt.Errorf("默认支付渠道[%s]未启用", cfg.Default)
+		}
+		slog.Warn("默认支付渠道未启用，下单将无法发起支付", "channel", cfg.Default)
+	}
+	defaultChannel = cfg.Default
+	notifyBaseURL = strings.TrimSuffix(cfg.NotifyBaseURL, "/")
+	return nil
+}
+
+// Register 注册支付渠道，同名渠道会被覆盖
+func Register(p Provider) {
+	providers[p.Name()] = p
+}
+
+// Get 按名称获取支付渠道，name 为空时返回默认渠道
+func Get(name string) (Provider, error) {
+	if name == "" {
+		name = defaultChannel
+	}
+	p, exists := providers[name]
+	if !exists {
+		return nil, fmt.Errorf("支付渠道[%s]不存在", name)
+	}
+	return p, nil
+}
+
+// NotifyURL 渠道的异步通知地址
+func NotifyURL(name string) string {
+	return fmt.Sprintf("%s/%s", notifyBaseURL, name)
+}
+
+// buildSignContent 按参数名 ASCII 升序拼接待签名字符串，跳过空值与 excludes 中的参数
+func buildSignContent(params map[string]string, excludes ...string) string {
+	keys := make([]string, 0, len(params))
+	for k, v := range params {
+		if v == "" || slices.Contains(excludes, k) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteByte('&')
+		}
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(params[k])
+	}
+	return sb.String()
+}
@@ -0,0 +1,87 @@
+// Package region 省级行政区划（GB/T 2260），收货地址与运费模板统一按省级代码匹配
+package region
+
+import "strings"
+
+// Province 省级行政区
+type Province struct {
+	Code  string // 行政区划代码，如 "230000"
+	Name  string // 全称，如 "黑龙江省"
+	Short string // 简称，如 "黑龙江"
+}
+
+var provinces = []Province{
+	{"110000", "北京市", "北京"},
+	{"120000", "天津市", "天津"},
+	{"130000", "河北省", "河北"},
+	{"140000", "山西省", "山西"},
+	{"150000", "内蒙古自治区", "内蒙古"},
+	{"210000", "辽宁省", "辽宁"},
+	{"220000", "吉林省", "吉林"},
+	{"230000", "黑龙江省", "黑龙江"},
+	{"310000", "上海市", "上海"},
+	{"320000", "江苏省", "江苏"},
+	{"330000", "浙江省", "浙江"},
+	{"340000", "安徽省", "安徽"},
+	{"350000", "福建省", "福建"},
+	{"360000", "江西省", "江西"},
+	{"370000", "山东省", "山东"},
+	{"410000", "河南省", "河南"},
+	{"420000", "湖北省", "湖北"},
+	{"430000", "湖南省", "湖南"},
+	{"440000", "广东省", "广东"},
+	{"450000", "广西壮族自治区", "广西"},
+	{"460000", "海南省", "海南"},
+	{"500000", "重庆市", "重庆"},
+	{"510000", "四川省", "四川"},
+	{"520000", "贵州省", "贵州"},
+	{"530000", "云南省", "云南"},
+	{"540000", "西藏自治区", "西藏"},
+	{"610000", "陕西省", "陕西"},
+	{"620000", "甘肃省", "甘肃"},
+	{"630000", "青海省", "青海"},
+	{"640000", "宁夏回族自治区", "宁夏"},
+	{"650000", "新疆维吾尔自治区", "新疆"},
+	{"710000", "台湾省", "台湾"},
+	{"810000", "香港特别行政区", "香港"},
+	{"820000", "澳门特别行政区", "澳门"},
+}
+
+// index 代码、全称、简称 -> 省份
+var index = func() map[string]Province {
+	m := make(map[string]Province, len(provinces)*3)
+	for _, p := range provinces {
+		m[p.Code] = p
+		m[p.Name] = p
+		m[p.Short] = p
+	}
+	return m
+}()
+
+// Lookup 按代码、全称或简称精确查找省份
+func Lookup(s string) (Province, bool) {
+	p, ok := index[strings.TrimSpace(s)]
+	return p, ok
+}
+
+// Resolve 优先按代码查找，代码为空时按名称查找（兼容未记录代码的旧地址）
+func Resolve(code string, name string) (Province, bool) {
+	if code != "" {
+		p, ok := index[code]
+		return p, ok && p.Code == code
+	}
+	return Lookup(name)
+}
+
+// Name 代码对应的省份全称，未知代码原样返回
+func Name(code string) string {
+	if p, ok := index[code]; ok && p.Code == code {
+		return p.Name
+	}
+	return code
+}
+
+// All 全部省份（按代码升序）
+func All() []Province {
+	return append([]Province(nil), provinces...)
+}
@@ -1,41 +1,92 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-const TokenExpire = 24 * time.Hour // Token 过期时间
+const TokenExpire = 15 * time.Minute // Access Token 过期时间
 //const TokenExpire = 1 * time.Minute // Token 过期时间
 
+const RefreshTokenExpire = 7 * 24 * time.Hour // Refresh Token 过期时间
+
 // Claims 自定义JWT声明，包含用户非敏感身份信息
 type Claims struct {
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
+	UserID      uint     `json:"user_id"`
+	Username    string   `json:"username"`
+	Roles       []string `json:"roles,omitempty"`       // 角色编码
+	Permissions []string `json:"permissions,omitempty"` // 权限编码（角色展开后）
+	IssuedAtMs  int64    `json:"iat_ms,omitempty"`      // 毫秒精度的签发时间，用于与吊销时间点比较
 	jwt.RegisteredClaims
 }
 
-func GetJWTSecretKey() []byte {
+// IssuedAtMilli 签发时间（毫秒），旧版 Token 没有 iat_ms 时退回秒级 iat，均缺失时返回 0
+func (c *Claims) IssuedAtMilli() int64 {
+	if c.IssuedAtMs > 0 {
+		return c.IssuedAtMs
+	}
+	if c.IssuedAt != nil {
+		return c.IssuedAt.UnixMilli()
+	}
+	return 0
+}
+
+// GetJWTSecretKey HS256 共享密钥（环境变量 JWT_SECRET_KEY），仅在未配置非对称密钥的非生产环境使用，未设置时返回错误
+func GetJWTSecretKey() ([]byte, error) {
 	secret := os.Getenv("JWT_SECRET_KEY")
 	if secret == "" {
-		return []byte("default-secret_key-antplant-store-forever")
+		return nil, errors.New("未设置环境变量 JWT_SECRET_KEY")
 	}
-	return []byte(secret)
+	return []byte(secret), nil
+}
+
+// RandomToken 生成 n 字节的随机串（十六进制编码）
+func RandomToken(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
 }
 
-// GenerateToken 生成JWT Token
-func GenerateToken(userID uint, username string) (string, error) {
+// GenerateToken 生成JWT Token，jti 用于服务端吊销
+func GenerateToken(userID uint, username string, roles []string, permissions []string) (string, error) {
 	// 1. 构建自定义声明
+	now := time.Now()
 	claims := &Claims{
-		UserID:   userID,
-		Username: username,
+		UserID:      userID,
+		Username:    username,
+		Roles:       roles,
+		Permissions: permissions,
+		IssuedAtMs:  now.UnixMilli(),
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(TokenExpire)), // 过期时间（24小时）
-			IssuedAt:  jwt.NewNumericDate(time.Now()),                  // 签发时间
+			ID:        RandomToken(16),                          // jti
+			ExpiresAt: jwt.NewNumericDate(now.Add(TokenExpire)), // 过期时间
+			IssuedAt:  jwt.NewNumericDate(now),                  // 签发时间
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(GetJWTSecretKey())
+	return signToken(claims)
+}
+
+// ParseToken 解析并校验JWT Token
+//
+// skipExpiry 为 true 时不校验过期时间（仅用于登出等需要识别已过期Token的场景），签名仍会校验
+func ParseToken(tokenStr string, skipExpiry bool) (*Claims, error) {
+	var opts []jwt.ParserOption
+	if skipExpiry {
+		opts = append(opts, jwt.WithoutClaimsValidation())
+	}
+	claims := &Claims{}
+	validMethods := []string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, verifyKeyFunc, append(opts, jwt.WithValidMethods(validMethods))...)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	return claims, nil
 }
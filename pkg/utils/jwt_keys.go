@@ -0,0 +1,225 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/spf13/viper"
+)
+
+// JWTConfig JWT 签名密钥配置
+//
+// 密钥目录下每个文件对应一个 kid（文件名去掉 .pem 后缀）：
+//
+//	<kid>.pem  私钥（RSA 或 Ed25519，PKCS#1/PKCS#8），可签名也可验签
+//	<kid>.pub  公钥（PKIX），仅用于验签，私钥下线后保留至旧 Token 全部过期
+//
+// 轮换流程：生成新密钥放入目录 -> 将 active_kid 切换为新 kid 并发布 -> 旧密钥保留至少
+// RefreshTokenExpire 之后再删除（或仅保留 .pub），期间旧 Token 仍可验签
+type JWTConfig struct {
+	KeyDir    string `mapstructure:"key_dir"`    // 密钥目录
+	ActiveKid string `mapstructure:"active_kid"` // 当前用于签名的 kid
+}
+
+// jwtKey 密钥环中的一把密钥
+type jwtKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer // 仅验签的密钥为 nil
+	public  crypto.PublicKey
+}
+
+var (
+	JWTCfg       JWTConfig
+	jwtKeys      = make(map[string]*jwtKey)
+	jwtActiveKey *jwtKey
+)
+
+func LoadJWTConfig() JWTConfig {
+	// 配置文件路径和名称
+	viper.SetConfigName("config")   // 配置文件名（无后缀）
+	viper.SetConfigType("yaml")     // 配置文件类型
+	viper.AddConfigPath("./config") // 配置文件所在目录
+
+	// 读取配置文件
+	if err := viper.ReadInConfig(); err != nil {
+		log.Fatalf("读取配置文件失败: %v", err)
+	}
+
+	// 解析配置到结构体
+	if err := viper.UnmarshalKey("jwt", &JWTCfg); err != nil {
+		log.Fatalf("解析配置失败: %v", err)
+	}
+	return JWTCfg
+}
+
+// IsProduction 是否运行在生产环境（环境变量 APP_ENV=production）
+func IsProduction() bool {
+	return os.Getenv("APP_ENV") == "production"
+}
+
+// InitJWTKeys 加载签名密钥环
+//
+// 未配置密钥目录时退回 HS256 共享密钥，生产环境下不允许退回，非生产环境须设置 JWT_SECRET_KEY
+func InitJWTKeys(cfg JWTConfig) error {
+	if cfg.KeyDir == "" {
+		if IsProduction() {
+			return fmt.Errorf("生产环境必须配置 jwt.key_dir 使用非对称签名")
+		}
+		if _, err := GetJWTSecretKey(); err != nil {
+			return fmt.Errorf("未配置 jwt.key_dir 时使用 HS256: %w", err)
+		}
+		slog.Warn("未配置JWT密钥目录，使用 JWT_SECRET_KEY 签名（HS256）")
+		return nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(cfg.KeyDir, "*"))
+	if err != nil {
+		return fmt.Errorf("读取JWT密钥目录失败: %w", err)
+	}
+	keys := make(map[string]*jwtKey)
+	for _, file := range files {
+		ext := filepath.Ext(file)
+		if ext != ".pem" && ext != ".pub" {
+			continue
+		}
+		kid := strings.TrimSuffix(filepath.Base(file), ext)
+		key, err := loadJWTKey(file, kid)
+		if err != nil {
+			return err
+		}
+		// 同一 kid 同时存在 .pem 与 .pub 时以私钥为准
+		if existing, ok := keys[kid]; ok && existing.private != nil {
+			continue
+		}
+		keys[kid] = key
+	}
+
+	active, ok := keys[cfg.ActiveKid]
+	if !ok || active.private == nil {
+		return fmt.Errorf("JWT签名密钥[%s]不存在或缺少私钥", cfg.ActiveKid)
+	}
+	jwtKeys = keys
+	jwtActiveKey = active
+	slog.Info("JWT密钥环加载成功", "activeKid", active.kid, "alg", active.method.Alg(), "keyCount", len(keys))
+	return nil
+}
+
+// loadJWTKey 解析 PEM 文件为密钥，根据密钥类型确定签名算法（RSA -> RS256，Ed25519 -> EdDSA）
+func loadJWTKey(file string, kid string) (*jwtKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("读取JWT密钥[%s]失败: %w", file, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("JWT密钥[%s]不是有效的PEM格式", file)
+	}
+
+	var parsed any
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("JWT密钥[%s]类型不支持: %s", file, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析JWT密钥[%s]失败: %w", file, err)
+	}
+
+	key := &jwtKey{kid: kid}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.method, key.public = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.method, key.public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("JWT密钥[%s]算法不支持，仅支持RSA与Ed25519", file)
+	}
+	return key, nil
+}
+
+// signToken 使用当前密钥签名，未加载密钥环时退回 HS256
+func signToken(claims jwt.Claims) (string, error) {
+	if jwtActiveKey == nil {
+		secret, err := GetJWTSecretKey()
+		if err != nil {
+			return "", err
+		}
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	}
+	token := jwt.NewWithClaims(jwtActiveKey.method, claims)
+	token.Header["kid"] = jwtActiveKey.kid
+	return token.SignedString(jwtActiveKey.private)
+}
+
+// verifyKeyFunc 根据 Token 头部的 kid 选择验签公钥，并校验算法与密钥匹配
+func verifyKeyFunc(token *jwt.Token) (interface{}, error) {
+	if jwtActiveKey == nil {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("不支持的签名算法: %v", token.Header["alg"])
+		}
+		return GetJWTSecretKey()
+	}
+	kid, _ := token.Header["kid"].(string)
+	key, ok := jwtKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("未知的kid: %s", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("kid[%s]签名算法不匹配: %s", kid, token.Method.Alg())
+	}
+	return key.public, nil
+}
+
+// JWKS 当前全部验签公钥（RFC 7517），供其他服务校验 Token
+func JWKS() map[string]any {
+	kids := make([]string, 0, len(jwtKeys))
+	for kid := range jwtKeys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	keys := make([]map[string]string, 0, len(kids))
+	for _, kid := range kids {
+		key := jwtKeys[kid]
+		jwk := map[string]string{
+			"kid": kid,
+			"use": "sig",
+			"alg": key.method.Alg(),
+		}
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk["kty"] = "RSA"
+			jwk["n"] = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk["e"] = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk["kty"] = "OKP"
+			jwk["crv"] = "Ed25519"
+			jwk["x"] = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		keys = append(keys, jwk)
+	}
+	return map[string]any{"keys": keys}
+}
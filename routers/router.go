@@ -11,6 +11,7 @@ import (
 	"github.com/natefinch/lumberjack"
 	"github.com/sunzhaoc/plant_be/internal/api"
 	"github.com/sunzhaoc/plant_be/internal/middleware"
+	"github.com/sunzhaoc/plant_be/internal/rbac"
 )
 
 // setupLogger 配置Gin日志输出到文件并实现拆分
@@ -46,7 +47,7 @@ func InitRouter() {
 		AllowOrigins:     []string{"https://antplant.store/", "http://antplant.store/", "http://localhost:5174", "http://localhost:5173"}, // 允许的前端域名
 		AllowCredentials: true,                                                                                                            // 开启允许携带凭证（Cookie）
 		AllowMethods:     []string{"GET", "POST", "OPTIONS", "PUT", "DELETE"},                                                             // 允许的请求方法
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept"},                                                                    // 允许的请求头
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", middleware.IdempotencyHeader},                                      // 允许的请求头
 		MaxAge:           12 * time.Hour,                                                                                                  // 预检请求的有效期（可选，默认8小时）
 	}))
 
@@ -54,23 +55,143 @@ func InitRouter() {
 		c.JSON(http.StatusOK, gin.H{"message": "test"})
 	})
 
+	r.GET("/.well-known/jwks.json", api.GetJWKS)
+
 	//r.GET("/api/plant-image", api.GetPlantImageHandler)
 
 	r.GET("/api/plants", api.GetPlants)
 
+	r.GET("/api/plant-filters", api.GetPlantFilters)
+
 	r.GET("/api/plant-detail/:plantId", middleware.JWTAuthMiddleware(), api.GetPlantDetail)
 
-	r.POST("/api/cart/sync-stock", middleware.JWTAuthMiddleware(), api.SyncCartStock)
+	r.POST("/api/cart/sync-stock", middleware.JWTAuthMiddleware(), middleware.IdempotencyMiddleware(), api.SyncCartStock)
 
 	r.GET("/api/order/get-orders", middleware.JWTAuthMiddleware(), api.GetOrders)
 
-	r.POST("/api/order/create-payment", middleware.JWTAuthMiddleware(), api.CreatePayment)
+	r.GET("/api/order/:orderSn", middleware.JWTAuthMiddleware(), api.GetOrderDetail)
+
+	r.POST("/api/order/:orderSn/cancel", middleware.JWTAuthMiddleware(), api.CancelOrder)
+
+	r.POST("/api/order/:orderSn/pay", middleware.JWTAuthMiddleware(), api.PayOrder)
+
+	r.POST("/api/order/:orderSn/confirm", middleware.JWTAuthMiddleware(), api.ConfirmOrder)
+
+	r.POST("/api/order/preview", middleware.JWTAuthMiddleware(), api.PreviewOrder)
+
+	r.POST("/api/order/create-payment", middleware.JWTAuthMiddleware(), middleware.IdempotencyMiddleware(), api.CreatePayment)
+
+	r.POST("/api/payment/notify/:provider", api.PaymentNotify)
+
+	r.POST("/api/carrier/notify/:carrier", api.CarrierNotify)
+
+	r.GET("/api/regions", api.GetRegions)
 
-	r.POST("/api/register", api.PostRegister)
+	r.GET("/api/address/list", middleware.JWTAuthMiddleware(), api.GetAddresses)
+
+	r.POST("/api/address/create", middleware.JWTAuthMiddleware(), api.CreateAddress)
+
+	r.PUT("/api/address/update/:addressId", middleware.JWTAuthMiddleware(), api.UpdateAddress)
+
+	r.DELETE("/api/address/delete/:addressId", middleware.JWTAuthMiddleware(), api.DeleteAddress)
+
+	r.POST("/api/address/set-default/:addressId", middleware.JWTAuthMiddleware(), api.SetDefaultAddress)
+
+	r.POST("/api/register", middleware.IdempotencyMiddleware(), api.PostRegister)
 
 	r.POST("/api/login", api.PostLogin)
 
-	r.POST("/api/cart/sync-redis", middleware.JWTAuthMiddleware(), api.SyncCartToRedis)
+	r.POST("/api/token/refresh", api.PostRefreshToken)
+
+	r.POST("/api/logout", api.PostLogout)
+
+	r.POST("/api/logout-all", middleware.JWTAuthMiddleware(), api.PostLogoutAll)
+
+	r.POST("/api/cart/sync-redis", middleware.JWTAuthMiddleware(), middleware.IdempotencyMiddleware(), api.SyncCartToRedis)
+
+	r.GET("/api/cart", middleware.JWTAuthMiddleware(), api.GetCart)
+
+	r.POST("/api/cart/merge", middleware.JWTAuthMiddleware(), middleware.IdempotencyMiddleware(), api.MergeCart)
+
+	r.GET("/api/flash-sales/:saleId", api.GetFlashSale)
+
+	r.POST("/api/flash-sales/:saleId/purchase", middleware.JWTAuthMiddleware(), middleware.IdempotencyMiddleware(), api.PurchaseFlashSale)
+
+	r.GET("/api/flash-sales/tickets/:ticketId", middleware.JWTAuthMiddleware(), api.GetFlashSaleTicket)
+
+	r.GET("/api/coupons", api.GetCoupons)
+
+	r.POST("/api/coupons/:couponId/claim", middleware.JWTAuthMiddleware(), middleware.IdempotencyMiddleware(), api.ClaimCoupon)
+
+	r.GET("/api/coupons/mine", middleware.JWTAuthMiddleware(), api.GetMyCoupons)
+
+	r.POST("/api/after-sales/images", middleware.JWTAuthMiddleware(), api.UploadAfterSaleImage)
+
+	r.POST("/api/after-sales", middleware.JWTAuthMiddleware(), middleware.IdempotencyMiddleware(), api.CreateAfterSale)
+
+	r.GET("/api/after-sales", middleware.JWTAuthMiddleware(), api.GetMyAfterSales)
+
+	r.GET("/api/after-sales/:afterSaleId", middleware.JWTAuthMiddleware(), api.GetAfterSale)
+
+	r.POST("/api/after-sales/:afterSaleId/withdraw", middleware.JWTAuthMiddleware(), api.WithdrawAfterSale)
+
+	// 后台管理接口，按权限点授权
+	admin := r.Group("/api/admin", middleware.JWTAuthMiddleware())
+	{
+		catalog := admin.Group("", middleware.RequirePermission(rbac.PermCatalogWrite))
+		catalog.GET("/plants/:plantId", api.AdminGetPlant)
+		catalog.POST("/plants", api.AdminCreatePlant)
+		catalog.PUT("/plants/:plantId", api.AdminUpdatePlant)
+		catalog.PUT("/plants/:plantId/on-sale", api.AdminSetPlantOnSale)
+		catalog.POST("/plants/:plantId/skus", api.AdminCreateSku)
+		catalog.PUT("/plants/:plantId/skus/sort", api.AdminSortSkus)
+		catalog.PUT("/skus/:skuId", api.AdminUpdateSku)
+		catalog.POST("/skus/:skuId/stock", api.AdminAdjustSkuStock)
+		catalog.POST("/plants/:plantId/images", api.AdminCreateImage)
+		catalog.PUT("/plants/:plantId/images/sort", api.AdminSortImages)
+		catalog.DELETE("/images/:imageId", api.AdminDeleteImage)
+		catalog.GET("/flash-sales", api.AdminGetFlashSales)
+		catalog.POST("/flash-sales", api.AdminCreateFlashSale)
+		catalog.GET("/shipping-templates", api.AdminGetShippingTemplates)
+		catalog.POST("/shipping-templates", api.AdminCreateShippingTemplate)
+		catalog.PUT("/shipping-templates/:templateId", api.AdminUpdateShippingTemplate)
+		catalog.GET("/plants/:plantId/shipping-restrictions", api.AdminGetShippingRestrictions)
+		catalog.POST("/plants/:plantId/shipping-restrictions", api.AdminCreateShippingRestriction)
+		catalog.DELETE("/shipping-restrictions/:restrictionId", api.AdminDeleteShippingRestriction)
+
+		promotion := admin.Group("", middleware.RequirePermission(rbac.PermPromotionManage))
+		promotion.GET("/coupons", api.AdminGetCoupons)
+		promotion.POST("/coupons", api.AdminCreateCoupon)
+		promotion.PUT("/coupons/:couponId/active", api.AdminSetCouponActive)
+
+		orderRead := admin.Group("", middleware.RequirePermission(rbac.PermOrderRead))
+		orderRead.GET("/orders", api.AdminGetOrders)
+		orderRead.GET("/orders/export", api.AdminExportOrders)
+		orderRead.GET("/orders/:orderId", api.AdminGetOrder)
+		orderRead.GET("/order-exceptions", api.AdminGetOrderExceptions)
+
+		orderManage := admin.Group("", middleware.RequirePermission(rbac.PermOrderManage))
+		orderManage.PUT("/orders/:orderId/address", api.AdminUpdateOrderAddress)
+		orderManage.POST("/orders/:orderId/notes", api.AdminAddOrderNote)
+		orderManage.POST("/orders/:orderId/cancel", api.AdminCancelOrder)
+		orderManage.POST("/order-exceptions/:exceptionId/resolve", api.AdminResolveOrderException)
+		orderManage.GET("/after-sales", api.AdminGetAfterSales)
+		orderManage.POST("/after-sales/:afterSaleId/approve", api.AdminApproveAfterSale)
+		orderManage.POST("/after-sales/:afterSaleId/reject", api.AdminRejectAfterSale)
+		orderManage.POST("/after-sales/:afterSaleId/refund", api.AdminRetryRefund)
+
+		ship := admin.Group("", middleware.RequirePermission(rbac.PermOrderShip))
+		ship.GET("/orders/:orderId/shipments", api.AdminGetOrderShipments)
+		ship.POST("/orders/:orderId/shipments", api.AdminCreateShipment)
+		ship.POST("/orders/:orderId/mark-shipped", api.AdminMarkOrderShipped)
+		ship.POST("/shipments/:shipmentId/sync", api.AdminSyncShipment)
+
+		roles := admin.Group("", middleware.RequirePermission(rbac.PermRBACManage))
+		roles.GET("/roles", api.AdminGetRoles)
+		roles.GET("/users/:userId/roles", api.AdminGetUserRoles)
+		roles.POST("/users/:userId/roles", api.AdminGrantRole)
+		roles.DELETE("/users/:userId/roles/:role", api.AdminRevokeRole)
+	}
 
 	err := r.Run(":8080")
 	if err != nil {